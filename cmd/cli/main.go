@@ -12,10 +12,8 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/mitchellh/cli"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
-	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
 
 	"github.com/yusufsyaifudin/go-project-structure/assets"
 	pingcli "github.com/yusufsyaifudin/go-project-structure/cmd/cli/ping"
@@ -25,9 +23,25 @@ import (
 
 type Config struct {
 	LogLevel        string `env:"LOG_LEVEL" envDefault:"DEBUG"`
-	OtelExporter    string `env:"OTEL_EXPORTER" envDefault:"NOOP"` // NOOP, STDOUT, JAEGER, OTLP, OTLP_GRPC
-	OtelOtlpURL     string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"localhost:4318" validate:"required_if=OtelExporter OTLP"`
-	OtelOtlpGrpcURL string `env:"OTEL_EXPORTER_OTLP_GRPC_ENDPOINT" envDefault:"localhost:4317" validate:"required_if=OtelExporter OTLP_GRPC"`
+	OtelExporter    string `env:"OTEL_EXPORTER" envDefault:"NOOP"` // comma-separated of NOOP, STDOUT, JAEGER, OTLP, OTLP_GRPC, FILE
+	OtelOtlpURL     string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"localhost:4318"`
+	OtelOtlpGrpcURL string `env:"OTEL_EXPORTER_OTLP_GRPC_ENDPOINT" envDefault:"localhost:4317"`
+	OtelJaegerURL   string `env:"OTEL_EXPORTER_JAEGER_ENDPOINT" envDefault:"http://localhost:4318"`
+
+	// DeploymentEnvironment is the deployment.environment.name of OpenTelemetry resource, i.e. staging or production.
+	// The other resource attributes can be added using OTEL_RESOURCE_ATTRIBUTES.
+	DeploymentEnvironment string `env:"DEPLOYMENT_ENVIRONMENT" envDefault:"demo"`
+
+	// OtelFilePath is where the FILE exporter writes the spans as OTLP-JSON lines,
+	// rotated when the size exceeds OtelFileMaxSize bytes and keeping OtelFileMaxBackups old files.
+	OtelFilePath       string `env:"OTEL_EXPORTER_FILE_PATH" envDefault:"traces.jsonl"`
+	OtelFileMaxSize    int64  `env:"OTEL_EXPORTER_FILE_MAX_SIZE" envDefault:"104857600" validate:"gt=0"`
+	OtelFileMaxBackups int    `env:"OTEL_EXPORTER_FILE_MAX_BACKUPS" envDefault:"5" validate:"gte=0"`
+
+	// OtelTracesSampler is one of always_on, always_off, traceidratio, parentbased_always_on, parentbased_always_off,
+	// parentbased_traceidratio. OtelTracesSamplerArg is the ratio (0 to 1) for the traceidratio samplers.
+	OtelTracesSampler    string `env:"OTEL_TRACES_SAMPLER" envDefault:"parentbased_always_on"`
+	OtelTracesSamplerArg string `env:"OTEL_TRACES_SAMPLER_ARG"`
 }
 
 func main() {
@@ -47,12 +61,21 @@ func main() {
 	defer cancel()
 
 	// ** Prepare logger using slog
+	logLevel, err := ylog.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+
+	// logLevelController decides the minimum level of the log, including the level per logger group.
+	logLevelController := ylog.NewLevelController(logLevel)
+
 	loggerOpt := &slog.HandlerOptions{
 		AddSource:   true,
-		Level:       slog.LevelDebug,
+		Level:       logLevelController,
 		ReplaceAttr: nil,
 	}
-	var loggerHandler slog.Handler = slog.NewJSONHandler(os.Stdout, loggerOpt)
+	jsonHandler := slog.NewJSONHandler(os.Stdout, loggerOpt)
 
 	// ** Prepare logger using ylog
 	yloggerOpt := &ylog.OpenTelemetryOption{
@@ -61,11 +84,29 @@ func main() {
 		},
 	}
 
-	loggerHandler = ylog.NewOTEL(loggerHandler, yloggerOpt)
+	// The level handler must be the outermost, because ylog.OpenTelemetry doesn't pass the group into the next handler.
+	loggerHandler := ylog.Chain(jsonHandler,
+		ylog.LevelMiddleware(logLevelController),
+		ylog.OTELMiddleware(yloggerOpt),
+	)
 	logger := slog.New(loggerHandler)
 	slog.SetDefault(logger)
 
-	otelResources := newResource(systemCtx, serviceName)
+	otelResources, err := oteltracer.NewResource(systemCtx,
+		oteltracer.ResourceWithServiceName(serviceName),
+		oteltracer.ResourceWithServiceVersion(assets.BuildCommitID()),
+		oteltracer.ResourceWithBuildTime(assets.BuildTime()),
+		oteltracer.ResourceWithEnvironment(cfg.DeploymentEnvironment),
+	)
+	if err != nil {
+		if otelResources == nil {
+			log.Fatalln(fmt.Errorf("cannot prepare OpenTelemetry resource: %w", err))
+			return
+		}
+
+		// some detectors failed, continue with the partially detected resource
+		slog.WarnContext(systemCtx, "OpenTelemetry resource is partially detected", slog.Any("error", err))
+	}
 
 	// ** Prepare tracer for CLI (act as front-end).
 	// This never block the CLI operation since it send through UDP.
@@ -76,9 +117,14 @@ func main() {
 
 		// prepare tracer exporter, whether using stdout or jaeger
 		tracerExporter, tracerErr = oteltracer.NewTracerExporter(cfg.OtelExporter,
+			oteltracer.WithOTLPFromEnv(),
 			oteltracer.WithLogger(slog.Default()),
 			oteltracer.WithOTLPEndpoint(cfg.OtelOtlpURL),
 			oteltracer.WithOTLPGrpcEndpoint(cfg.OtelOtlpGrpcURL),
+			oteltracer.WithJaegerEndpoint(cfg.OtelJaegerURL),
+			oteltracer.WithFilePath(cfg.OtelFilePath),
+			oteltracer.WithFileMaxSize(cfg.OtelFileMaxSize),
+			oteltracer.WithFileMaxBackups(cfg.OtelFileMaxBackups),
 		)
 		if tracerErr != nil {
 			tracerExporter = tracetest.NewNoopExporter()
@@ -88,11 +134,18 @@ func main() {
 			slog.InfoContext(systemCtx, fmt.Sprintf("using %s exporter", cfg.OtelExporter))
 		}
 
+		tracerSampler, samplerErr := oteltracer.NewSampler(cfg.OtelTracesSampler, cfg.OtelTracesSamplerArg)
+		if samplerErr != nil {
+			tracerSampler = trace.ParentBased(trace.AlwaysSample())
+
+			slog.ErrorContext(systemCtx, "failed configure tracer sampler", slog.Any("error", samplerErr))
+		}
+
 		tracerProviderImplemented := trace.NewTracerProvider(
 			// use sync operation to make sure every span persisted before CLI done
 			trace.WithSyncer(tracerExporter),
 			trace.WithResource(otelResources),
-			trace.WithSampler(trace.AlwaysSample()),
+			trace.WithSampler(tracerSampler),
 		)
 		defer func() {
 			if _err := tracerProviderImplemented.Shutdown(systemCtx); _err != nil {
@@ -120,20 +173,3 @@ func main() {
 
 	os.Exit(exitStatus)
 }
-
-// newResource returns a resource describing this application.
-func newResource(ctx context.Context, serviceName string) *resource.Resource {
-	r := resource.NewWithAttributes(
-		semconv.SchemaURL,
-		semconv.ServiceName(serviceName),
-		semconv.ServiceVersion("v0.1.0"),
-		semconv.DeploymentEnvironmentName("demo"),
-	)
-
-	if r == nil {
-		slog.ErrorContext(ctx, "cannot use OpenTelemetry resource because of nil, fallback to default resource")
-		return resource.Default()
-	}
-
-	return r
-}
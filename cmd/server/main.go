@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,31 +22,194 @@ import (
 	"github.com/yusufsyaifudin/go-project-structure/assets"
 	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpclientmw"
 	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/health"
 	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
 	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/profiler"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/redact"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/requestid"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/tlsreload"
 	"github.com/yusufsyaifudin/go-project-structure/pkg/validator"
 	"github.com/yusufsyaifudin/go-project-structure/pkg/ylog"
 	"github.com/yusufsyaifudin/go-project-structure/transport/restapi"
+	"github.com/yusufsyaifudin/go-project-structure/transport/restapi/handleradmin"
 	"github.com/yusufsyaifudin/go-project-structure/transport/restapi/handlersystem"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
 )
 
 type Config struct {
 	HTTPPort        int    `env:"PORT" envDefault:"3000" validate:"required"`
 	LogLevel        string `env:"LOG_LEVEL" envDefault:"DEBUG" validate:"required"`
-	OtelExporter    string `env:"OTEL_EXPORTER" envDefault:"NOOP"` // NOOP, STDOUT, JAEGER, OTLP, OTLP_GRPC
-	OtelOtlpURL     string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"localhost:4318" validate:"required_if=OtelExporter OTLP"`
-	OtelOtlpGrpcURL string `env:"OTEL_EXPORTER_OTLP_GRPC_ENDPOINT" envDefault:"localhost:4317" validate:"required_if=OtelExporter OTLP_GRPC"`
+	OtelExporter    string `env:"OTEL_EXPORTER" envDefault:"NOOP"` // comma-separated of NOOP, STDOUT, JAEGER, OTLP, OTLP_GRPC, FILE, MEMORY
+	OtelOtlpURL     string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"localhost:4318"`
+	OtelOtlpGrpcURL string `env:"OTEL_EXPORTER_OTLP_GRPC_ENDPOINT" envDefault:"localhost:4317"`
+	OtelJaegerURL   string `env:"OTEL_EXPORTER_JAEGER_ENDPOINT" envDefault:"http://localhost:4318"`
+
+	// DeploymentEnvironment is the deployment.environment.name of OpenTelemetry resource, i.e. staging or production.
+	// The other resource attributes can be added using OTEL_RESOURCE_ATTRIBUTES.
+	DeploymentEnvironment string `env:"DEPLOYMENT_ENVIRONMENT" envDefault:"demo"`
+
+	// OtelFilePath is where the FILE exporter writes the spans as OTLP-JSON lines,
+	// rotated when the size exceeds OtelFileMaxSize bytes and keeping OtelFileMaxBackups old files.
+	OtelFilePath       string `env:"OTEL_EXPORTER_FILE_PATH" envDefault:"traces.jsonl"`
+	OtelFileMaxSize    int64  `env:"OTEL_EXPORTER_FILE_MAX_SIZE" envDefault:"104857600" validate:"gt=0"`
+	OtelFileMaxBackups int    `env:"OTEL_EXPORTER_FILE_MAX_BACKUPS" envDefault:"5" validate:"gte=0"`
+
+	// OtelMemoryMaxTraces is the number of recent traces kept by the MEMORY exporter, queryable from /admin/traces.
+	OtelMemoryMaxTraces int `env:"OTEL_EXPORTER_MEMORY_MAX_TRACES" envDefault:"100" validate:"gt=0"`
+
+	// OtelTracesSampler is one of always_on, always_off, traceidratio, parentbased_always_on, parentbased_always_off,
+	// parentbased_traceidratio. OtelTracesSamplerArg is the ratio (0 to 1) for the traceidratio samplers.
+	OtelTracesSampler    string `env:"OTEL_TRACES_SAMPLER" envDefault:"parentbased_always_on"`
+	OtelTracesSamplerArg string `env:"OTEL_TRACES_SAMPLER_ARG"`
+
+	// OtelTracesSamplerRoutes overrides the sampling ratio of the request per route template, i.e: "/ping=0,/users/:id=0.1".
+	OtelTracesSamplerRoutes []string `env:"OTEL_TRACES_SAMPLER_ROUTES" envSeparator:","`
+
+	// OtelTracesKeepErrors also exports the span which is not sampled but ends with error status (i.e. 5xx response).
+	OtelTracesKeepErrors bool `env:"OTEL_TRACES_KEEP_ERRORS" envDefault:"false"`
+
+	// OtelMetricExporter pushes metrics to OpenTelemetry collector using the same OTLP endpoints as the span exporter.
+	// NOOP, STDOUT, OTLP, OTLP_GRPC. Prometheus /metrics endpoint keeps working regardless of this value.
+	OtelMetricExporter string `env:"OTEL_METRICS_EXPORTER" envDefault:"NOOP"`
+
+	// OtelMetricExportInterval is the interval of pushing metrics to OpenTelemetry metric exporter.
+	OtelMetricExportInterval time.Duration `env:"OTEL_METRIC_EXPORT_INTERVAL" envDefault:"60s" validate:"gt=0"`
+
+	// OtelLogExporter also sends each log record to OpenTelemetry collector using the same OTLP endpoints as the span exporter.
+	// NOOP, OTLP, OTLP_GRPC. The logs are always written into stdout regardless of this value.
+	OtelLogExporter string `env:"OTEL_LOGS_EXPORTER" envDefault:"NOOP"`
+
+	// HTTPReadHeaderTimeout, HTTPReadTimeout, HTTPWriteTimeout and HTTPIdleTimeout are the http.Server timeouts,
+	// zero means no timeout. HTTPWriteTimeout must be longer than the slowest response (i.e. streaming, file download).
+	HTTPReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"10s" validate:"gte=0"`
+	HTTPReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" envDefault:"30s" validate:"gte=0"`
+	HTTPWriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" envDefault:"60s" validate:"gte=0"`
+	HTTPIdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" envDefault:"120s" validate:"gte=0"`
+
+	// HTTPMaxHeaderBytes is the maximum bytes of request headers, the larger request is responded 431 by net/http.
+	HTTPMaxHeaderBytes int `env:"HTTP_MAX_HEADER_BYTES" envDefault:"1048576" validate:"gt=0"`
+
+	// HTTPMaxBodyBytes is the maximum bytes of request body, the larger request is responded 413. Zero means unlimited.
+	HTTPMaxBodyBytes int64 `env:"HTTP_MAX_BODY_BYTES" envDefault:"10485760" validate:"gte=0"`
+
+	// HTTPMaxInFlight is the maximum number of concurrent requests, the rest is responded 503 with Retry-After
+	// of HTTPRetryAfter. Zero means unlimited. The health probes and /metrics are not limited.
+	HTTPMaxInFlight int           `env:"HTTP_MAX_IN_FLIGHT" envDefault:"1000" validate:"gte=0"`
+	HTTPRetryAfter  time.Duration `env:"HTTP_RETRY_AFTER" envDefault:"1s" validate:"gt=0"`
+
+	// RateLimit is the default limit of each client in format requests/window[,burst], i.e: "100/1m" or "10/1s,50".
+	// Rate limiting is disabled when empty. RateLimitRoutes overrides it per route template separated by semicolon,
+	// i.e: "/login=5/1m;/users/:id=10/1s".
+	RateLimit       string   `env:"RATE_LIMIT"`
+	RateLimitRoutes []string `env:"RATE_LIMIT_ROUTES" envSeparator:";"`
+
+	// RateLimitAlgorithm is either token_bucket or sliding_window.
+	RateLimitAlgorithm string `env:"RATE_LIMIT_ALGORITHM" envDefault:"token_bucket" validate:"oneof=token_bucket sliding_window"`
+
+	// RateLimitKeys is how the client is identified, the first found is used: header (RateLimitKeyHeader, i.e. API key),
+	// peer (verified mTLS client certificate) or ip (RateLimitIPHeader when behind proxy, i.e. X-Forwarded-For).
+	RateLimitKeys      []string `env:"RATE_LIMIT_KEYS" envDefault:"ip" envSeparator:","`
+	RateLimitKeyHeader string   `env:"RATE_LIMIT_KEY_HEADER" envDefault:"X-API-Key"`
+	RateLimitIPHeader  string   `env:"RATE_LIMIT_IP_HEADER"`
+
+	// RateLimitMaxKeys is the maximum number of clients tracked in memory, the least recently used is evicted.
+	RateLimitMaxKeys int `env:"RATE_LIMIT_MAX_KEYS" envDefault:"100000" validate:"gt=0"`
+
+	// ShutdownDelay is the time to wait after readiness is flipped to failing and before the server stops accepting
+	// new connections. It gives the load balancer (i.e. Kubernetes Endpoints) the time to remove this pod.
+	ShutdownDelay time.Duration `env:"SHUTDOWN_DELAY" envDefault:"5s" validate:"gte=0"`
+
+	// ShutdownTimeout is the single grace period to drain in-flight requests, then flush logs, tracer and metrics.
+	// Keep it lower than Kubernetes terminationGracePeriodSeconds minus ShutdownDelay.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"20s" validate:"gt=0"`
+
+	// MetricsHistogramBuckets is the upper bounds in seconds of the timer histogram, i.e: "0.005,0.01,0.05,0.1,0.5,1,5".
+	// Default to Prometheus default buckets when empty.
+	MetricsHistogramBuckets []float64 `env:"METRICS_HISTOGRAM_BUCKETS" envSeparator:","`
+
+	// MetricsNativeHistogramFactor enables Prometheus native histogram when greater than 1, i.e: 1.1.
+	MetricsNativeHistogramFactor float64 `env:"METRICS_NATIVE_HISTOGRAM_FACTOR" envDefault:"0"`
+
+	// StatsdAddress is the StatsD (or DogStatsD agent) UDP address, i.e: localhost:8125. StatsD is disabled when empty.
+	StatsdAddress string `env:"STATSD_ADDRESS"`
+
+	// StatsdTagFormat is either DOGSTATSD (labels as tags) or NONE (label values appended into metric name).
+	StatsdTagFormat string `env:"STATSD_TAG_FORMAT" envDefault:"DOGSTATSD" validate:"oneof=DOGSTATSD NONE"`
+
+	// StatsdFlushInterval is the interval to send the buffered metrics, zero sends each metric immediately.
+	StatsdFlushInterval time.Duration `env:"STATSD_FLUSH_INTERVAL" envDefault:"1s" validate:"gte=0"`
+
+	// LogRedactHeaders, LogRedactBodyKeys and LogRedactQueryParams are masked in the request/response log,
+	// in addition to the defaults (i.e. Authorization, Cookie, password, card_number, access_token, etc.).
+	LogRedactHeaders     []string `env:"LOG_REDACT_HEADERS" envSeparator:","`
+	LogRedactBodyKeys    []string `env:"LOG_REDACT_BODY_KEYS" envSeparator:","`
+	LogRedactQueryParams []string `env:"LOG_REDACT_QUERY_PARAMS" envSeparator:","`
+
+	// LogRedactBodyPaths is the JSON paths to be masked in the request/response log, i.e: "user.pin,items.*.cvv".
+	LogRedactBodyPaths []string `env:"LOG_REDACT_BODY_PATHS" envSeparator:","`
+
+	// LogRedactPatterns is the regular expressions separated by semicolon, the matched string is masked in the log.
+	LogRedactPatterns []string `env:"LOG_REDACT_PATTERNS" envSeparator:";"`
+
+	// LogMaxBodyBytes is the maximum bytes of request/response body in the log, the rest is truncated.
+	LogMaxBodyBytes int `env:"LOG_MAX_BODY_BYTES" envDefault:"16384" validate:"gte=0"`
+
+	// LogPolicy is the default request/response log policy: full, headers, metadata or off.
+	LogPolicy string `env:"LOG_POLICY" envDefault:"full" validate:"oneof=full headers metadata off"`
+
+	// LogRoutePolicies overrides the LogPolicy per route template, i.e: "/users/:id=headers,/upload=metadata".
+	LogRoutePolicies []string `env:"LOG_ROUTE_POLICIES" envSeparator:","`
+
+	// LogSampleRate is the probability (0 to 1) of request to be logged, 5xx and slow requests are always logged.
+	// Set to 0 to only log 5xx and slow requests.
+	LogSampleRate float64 `env:"LOG_SAMPLE_RATE" envDefault:"1" validate:"gte=0,lte=1"`
+
+	// LogRateLimit is the maximum number of sampled requests logged per second, 0 means unlimited.
+	LogRateLimit int `env:"LOG_RATE_LIMIT" envDefault:"0" validate:"gte=0"`
+
+	// LogSlowThreshold is the latency of request which is always logged regardless of sampling, 0 means disabled.
+	LogSlowThreshold time.Duration `env:"LOG_SLOW_THRESHOLD" envDefault:"0s" validate:"gte=0"`
+
+	// AdminToken is the Bearer token for /admin/* endpoints, the endpoints are disabled when empty.
+	AdminToken string `env:"ADMIN_TOKEN"`
+
+	// AdminPort serves /metrics, /system-info, health probes, /admin/* and /debug/* on a separate internal listener,
+	// so the public port only serves the application routes. Zero serves everything on the public port.
+	AdminPort int `env:"ADMIN_PORT" envDefault:"0" validate:"gte=0,nefield=HTTPPort"`
+
+	// ProfileMaxDuration is the longest duration of /debug/profile/capture, the endpoint requires AdminToken.
+	// ProfileMutexFraction and ProfileBlockRate enable the mutex and block profiles, zero disables them,
+	// see runtime.SetMutexProfileFraction and runtime.SetBlockProfileRate.
+	ProfileMaxDuration   time.Duration `env:"PROFILE_MAX_DURATION" envDefault:"60s" validate:"gt=0"`
+	ProfileMutexFraction int           `env:"PROFILE_MUTEX_FRACTION" envDefault:"0" validate:"gte=0"`
+	ProfileBlockRate     int           `env:"PROFILE_BLOCK_RATE" envDefault:"0" validate:"gte=0"`
+
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set, the files are reloaded every TLSReloadInterval
+	// when changed (i.e. renewed by cert-manager), so the rotation doesn't need restart.
+	TLSCertFile       string        `env:"TLS_CERT_FILE" validate:"required_with=TLSKeyFile"`
+	TLSKeyFile        string        `env:"TLS_KEY_FILE" validate:"required_with=TLSCertFile"`
+	TLSReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" envDefault:"10s" validate:"gte=0"`
+
+	// TLSClientCAFile enables mTLS, the client certificate is verified against this CA bundle.
+	// TLSClientAuth is either require (reject the client without certificate) or optional (verify if given).
+	TLSClientCAFile string `env:"TLS_CLIENT_CA_FILE" validate:"excluded_without=TLSCertFile"`
+	TLSClientAuth   string `env:"TLS_CLIENT_AUTH" envDefault:"require" validate:"oneof=require optional"`
+
+	// TLSMinVersion is 1.2 or 1.3. TLSCipherSuites is the allowed TLS 1.2 cipher suites, i.e:
+	// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256". Default to Go secure cipher suites.
+	TLSMinVersion   string   `env:"TLS_MIN_VERSION" envDefault:"1.2" validate:"oneof=1.2 1.3"`
+	TLSCipherSuites []string `env:"TLS_CIPHER_SUITES" envSeparator:","`
 }
 
 func main() {
 	// systemCtx is context for system-wide process, it should not pass into HTTP or any Client process.
-	systemCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// It must not be used to bound the shutdown process, use shutdown.Context instead.
+	systemCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	serviceName := assets.AppName + "_server"
@@ -65,22 +232,108 @@ func main() {
 		return
 	}
 
+	// shutdown is the single ShutdownTimeout deadline shared by the http drain and the deferred telemetry flushes,
+	// so the whole shutdown fits in ShutdownDelay + ShutdownTimeout.
+	shutdown := &shutdownBudget{timeout: cfg.ShutdownTimeout}
+
 	// ** Prepare logger using slog
+	logLevel, err := ylog.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Fatalln(err)
+		return
+	}
+
+	// logLevelController can be changed at runtime via admin endpoint.
+	logLevelController := ylog.NewLevelController(logLevel)
+
 	loggerOpt := &slog.HandlerOptions{
 		AddSource:   true,
-		Level:       slog.LevelDebug,
+		Level:       logLevelController,
 		ReplaceAttr: nil,
 	}
-	var loggerHandler slog.Handler = slog.NewJSONHandler(os.Stdout, loggerOpt)
+	jsonHandler := slog.NewJSONHandler(os.Stdout, loggerOpt)
+
+	otelResource, err := oteltracer.NewResource(systemCtx,
+		oteltracer.ResourceWithServiceName(serviceName),
+		oteltracer.ResourceWithServiceVersion(buildCommitID),
+		oteltracer.ResourceWithBuildTime(buildTime),
+		oteltracer.ResourceWithEnvironment(cfg.DeploymentEnvironment),
+	)
+	if err != nil {
+		if otelResource == nil {
+			log.Fatalln(fmt.Errorf("cannot prepare OpenTelemetry resource: %w", err))
+			return
+		}
+
+		// some detectors failed, continue with the partially detected resource
+		slog.WarnContext(systemCtx, "OpenTelemetry resource is partially detected", slog.Any("error", err))
+	}
+
+	// redactPolicy masks the sensitive data in the incoming HTTP request/response log.
+	redactPolicy, err := redact.Default(
+		redact.WithHeaders(cfg.LogRedactHeaders...),
+		redact.WithBodyKeys(cfg.LogRedactBodyKeys...),
+		redact.WithQueryParams(cfg.LogRedactQueryParams...),
+		redact.WithBodyPaths(cfg.LogRedactBodyPaths...),
+		redact.WithPatterns(cfg.LogRedactPatterns...),
+	)
+	if err != nil {
+		log.Fatalln(fmt.Errorf("invalid log redaction config: %w", err))
+		return
+	}
 
 	// ** Prepare logger using ylog
 	yloggerOpt := &ylog.OpenTelemetryOption{
 		ContextExtractor: func(ctx context.Context) []slog.Attr {
-			return nil
+			var attrs []slog.Attr
+			if id := requestid.FromContext(ctx); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+
+			if peer, ok := httpservermw.PeerIdentityFromContext(ctx); ok {
+				attrs = append(attrs, slog.String("peer_common_name", peer.CommonName))
+			}
+
+			return attrs
 		},
 	}
 
-	loggerHandler = ylog.NewOTEL(loggerHandler, yloggerOpt)
+	if otelLogExporter := strings.ToUpper(strings.TrimSpace(cfg.OtelLogExporter)); otelLogExporter != "" && otelLogExporter != "NOOP" {
+		logExporter, logExporterErr := oteltracer.NewLogExporter(cfg.OtelLogExporter,
+			oteltracer.WithOTLPFromEnv(),
+			oteltracer.WithOTLPEndpoint(cfg.OtelOtlpURL),
+			oteltracer.WithOTLPGrpcEndpoint(cfg.OtelOtlpGrpcURL),
+		)
+		if logExporterErr != nil {
+			log.Fatalln(fmt.Errorf("prepare log exporter error: %w", logExporterErr))
+			return
+		}
+
+		loggerProvider := sdklog.NewLoggerProvider(
+			sdklog.WithProcessor(sdklog.NewBatchProcessor(logExporter)),
+			sdklog.WithResource(otelResource),
+		)
+
+		// Registered before the tracer and metrics, so it is called last and the logs during shutdown are exported.
+		// Shutting down the logger provider also flush and shutdown the log exporter.
+		defer func() {
+			ctx, cancelShutdown := shutdown.Context()
+			defer cancelShutdown()
+
+			slog.InfoContext(ctx, "flushing logger provider...")
+			if _err := loggerProvider.Shutdown(ctx); _err != nil {
+				slog.ErrorContext(ctx, "shutdown logger provider error", slog.Any("error", _err))
+			}
+		}()
+
+		yloggerOpt.LoggerProvider = loggerProvider
+	}
+
+	// The level handler must be the outermost, because ylog.OpenTelemetry doesn't pass the group into the next handler.
+	loggerHandler := ylog.Chain(jsonHandler,
+		ylog.LevelMiddleware(logLevelController),
+		ylog.OTELMiddleware(yloggerOpt),
+	)
 	logger := slog.New(loggerHandler)
 	slog.SetDefault(logger)
 
@@ -88,14 +341,26 @@ func main() {
 	// So, any error from OpenTelemetry will also comply with the standard slog.
 	otel.SetErrorHandler(&otelErrHandler{})
 
-	otelResource := newResource(systemCtx, serviceName)
+	// The in-memory trace buffer is used when the MEMORY exporter is enabled, so the recent traces can be inspected
+	// from the admin endpoint without any collector.
+	traceBuffer, err := oteltracer.NewMemoryExporter(oteltracer.MemoryWithMaxTraces(cfg.OtelMemoryMaxTraces))
+	if err != nil {
+		slog.ErrorContext(systemCtx, "prepare trace buffer error", slog.Any("error", err))
+		return
+	}
 
 	// prepare tracer exporter, whether using stdout or jaeger
 	{
 		tracerExporter, tracerExporterErr := oteltracer.NewTracerExporter(cfg.OtelExporter,
+			oteltracer.WithOTLPFromEnv(),
 			oteltracer.WithLogger(slog.Default()),
 			oteltracer.WithOTLPEndpoint(cfg.OtelOtlpURL),
 			oteltracer.WithOTLPGrpcEndpoint(cfg.OtelOtlpGrpcURL),
+			oteltracer.WithJaegerEndpoint(cfg.OtelJaegerURL),
+			oteltracer.WithFilePath(cfg.OtelFilePath),
+			oteltracer.WithFileMaxSize(cfg.OtelFileMaxSize),
+			oteltracer.WithFileMaxBackups(cfg.OtelFileMaxBackups),
+			oteltracer.WithMemoryExporter(traceBuffer),
 			oteltracer.WithHttpRoundTripper(
 				httpclientmw.NewHttpRoundTripper(
 					httpclientmw.WithBaseRoundTripper(&http.Transport{}),
@@ -103,16 +368,6 @@ func main() {
 			),
 		)
 
-		defer func() {
-			if tracerExporter == nil {
-				return
-			}
-
-			if _err := tracerExporter.Shutdown(systemCtx); _err != nil {
-				slog.ErrorContext(systemCtx, "prepare exporter on shutdown error", slog.Any("error", _err))
-			}
-		}()
-
 		if tracerExporterErr != nil {
 			slog.ErrorContext(systemCtx, "prepare exporter error", slog.Any("error", tracerExporterErr))
 			return
@@ -120,14 +375,31 @@ func main() {
 
 		slog.ErrorContext(systemCtx, fmt.Sprintf("using %s as OpenTelemetry span exporter", cfg.OtelExporter))
 
+		tracerSampler, tracerSamplerErr := newSampler(cfg)
+		if tracerSamplerErr != nil {
+			slog.ErrorContext(systemCtx, "prepare sampler error", slog.Any("error", tracerSamplerErr))
+			return
+		}
+
+		// The error span processor only changes the behavior when the sampler records the not sampled span.
+		tracerProcessor := trace.NewBatchSpanProcessor(tracerExporter)
+		if cfg.OtelTracesKeepErrors {
+			tracerProcessor = oteltracer.NewErrorSpanProcessor(tracerProcessor)
+		}
+
 		tracerProvider := trace.NewTracerProvider(
-			trace.WithBatcher(tracerExporter),
+			trace.WithSpanProcessor(tracerProcessor),
 			trace.WithResource(otelResource),
-			trace.WithSampler(trace.AlwaysSample()),
+			trace.WithSampler(tracerSampler),
 		)
+		// Shutting down the tracer provider also flush and shutdown the span exporter.
 		defer func() {
-			if _err := tracerProvider.Shutdown(systemCtx); _err != nil {
-				slog.ErrorContext(systemCtx, "shutdown tracer error", slog.Any("error", _err))
+			ctx, cancelShutdown := shutdown.Context()
+			defer cancelShutdown()
+
+			slog.InfoContext(ctx, "flushing tracer provider...")
+			if _err := tracerProvider.Shutdown(ctx); _err != nil {
+				slog.ErrorContext(ctx, "shutdown tracer error", slog.Any("error", _err))
 			}
 		}()
 
@@ -135,56 +407,170 @@ func main() {
 		otel.SetTracerProvider(tracerProvider)
 	}
 
-	var combinedMetrics metrics.Metric
+	var combinedMetrics *metrics.Combine
 	{
 		var prometheusMetric metrics.Metric
 		prometheusMetric, err = metrics.NewPrometheus(
-			metrics.PrometheusWithPrefix(serviceName + "_"),
+			metrics.PrometheusWithPrefix(serviceName+"_"),
+			metrics.PrometheusWithHistogramOpts(metrics.HistogramOpts{
+				Buckets:                     cfg.MetricsHistogramBuckets,
+				NativeHistogramBucketFactor: cfg.MetricsNativeHistogramFactor,
+			}),
 		)
 		if err != nil {
 			slog.ErrorContext(systemCtx, "cannot prepare prometheus metric", slog.Any("error", err))
 			return
 		}
 
-		// combine metrics to various type of outputs (prometheus, statsd, etc)
-		combinedMetrics, err = metrics.NewCombinedMetrics(
+		combineOpts := []metrics.CombineOpt{
 			metrics.CombineMetricAdd(prometheusMetric),
-		)
+		}
+
+		if otelMetricExporter := strings.ToUpper(strings.TrimSpace(cfg.OtelMetricExporter)); otelMetricExporter != "" && otelMetricExporter != "NOOP" {
+			metricExporter, metricExporterErr := oteltracer.NewMetricExporter(cfg.OtelMetricExporter,
+				oteltracer.WithOTLPFromEnv(),
+				oteltracer.WithLogger(slog.Default()),
+				oteltracer.WithOTLPEndpoint(cfg.OtelOtlpURL),
+				oteltracer.WithOTLPGrpcEndpoint(cfg.OtelOtlpGrpcURL),
+			)
+			if metricExporterErr != nil {
+				slog.ErrorContext(systemCtx, "prepare metric exporter error", slog.Any("error", metricExporterErr))
+				return
+			}
+
+			// Closing the OpenTelemetry metric also flush and shutdown the metric exporter.
+			var otelMetric metrics.Metric
+			otelMetric, err = metrics.NewOpenTelemetry(
+				metrics.OpenTelemetryWithResource(otelResource),
+				metrics.OpenTelemetryWithExporter(metricExporter),
+				metrics.OpenTelemetryWithExportInterval(cfg.OtelMetricExportInterval),
+				metrics.OpenTelemetryWithHistogramOpts(metrics.HistogramOpts{
+					Buckets: cfg.MetricsHistogramBuckets,
+				}),
+			)
+			if err != nil {
+				slog.ErrorContext(systemCtx, "cannot prepare OpenTelemetry metric", slog.Any("error", err))
+				return
+			}
+
+			slog.InfoContext(systemCtx, fmt.Sprintf("using %s as OpenTelemetry metric exporter", cfg.OtelMetricExporter))
+			combineOpts = append(combineOpts, metrics.CombineMetricAdd(otelMetric))
+		}
+
+		if cfg.StatsdAddress != "" {
+			var statsdMetric metrics.Metric
+			statsdMetric, err = metrics.NewStatsd(
+				metrics.StatsdWithAddress(cfg.StatsdAddress),
+				metrics.StatsdWithPrefix(serviceName+"."),
+				metrics.StatsdWithTagFormat(metrics.StatsdTagFormat(cfg.StatsdTagFormat)),
+				metrics.StatsdWithFlushInterval(cfg.StatsdFlushInterval),
+			)
+			if err != nil {
+				slog.ErrorContext(systemCtx, "cannot prepare statsd metric", slog.Any("error", err))
+				return
+			}
+
+			combineOpts = append(combineOpts, metrics.CombineMetricAdd(statsdMetric))
+		}
+
+		// combine metrics to various type of outputs (prometheus, statsd, etc)
+		combinedMetrics, err = metrics.NewCombinedMetrics(combineOpts...)
 		if err != nil {
 			slog.ErrorContext(systemCtx, "cannot combine metrics collector", slog.Any("error", err))
 			return
 		}
+
+		// Closing within the shutdown context, so the final flush of the OpenTelemetry exporter cannot block forever.
+		defer func() {
+			ctx, cancelShutdown := shutdown.Context()
+			defer cancelShutdown()
+
+			slog.InfoContext(ctx, "closing metrics...")
+			if _err := combinedMetrics.CloseContext(ctx); _err != nil {
+				slog.ErrorContext(ctx, "close metrics error", slog.Any("error", _err))
+			}
+		}()
 	}
 
 	startupTime := time.Now()
 
-	// prepare handler system for ping and system info routes.
+	// healthChecker holds the health checks of the components, exposed as /healthz/live, /healthz/ready and /healthz/startup.
+	// Register the check of each dependency here, for example the database connection:
+	//
+	//	healthChecker.Register("postgres", db.PingContext, health.CheckWithTimeout(time.Second), health.CheckWithCacheTTL(5*time.Second))
+	//
+	// Use health.CheckWithCritical(false) for the dependency that the application can still serve without it.
+	healthChecker := health.New()
+
+	// prepare handler system for ping, health and system info routes.
 	handlerSystem, err := handlersystem.New(
 		handlersystem.WithBuildCommitID(buildCommitID),
 		handlersystem.WithBuildTime(buildTime),
 		handlersystem.WithStartupTime(startupTime),
+		handlersystem.WithHealth(healthChecker),
 	)
 	if err != nil {
 		slog.ErrorContext(systemCtx, "cannot prepare http handler for system router", slog.Any("error", err))
 		return
 	}
 
-	// ** setup server with graceful shutdown
-	slog.InfoContext(systemCtx, "preparing server http...")
-	var serverMux http.Handler
-	serverMux, err = restapi.NewHTTP(
+	// The mutex and block profiles are only recorded when the rate is set, and they have small overhead.
+	profiler.SetRates(cfg.ProfileMutexFraction, cfg.ProfileBlockRate)
+
+	// prepare handler admin for operational routes, i.e: changing log level at runtime and profiling.
+	adminOpts := []handleradmin.Opt{
+		handleradmin.WithToken(cfg.AdminToken),
+		handleradmin.WithLevelController(logLevelController),
+		handleradmin.WithProfiling(cfg.ProfileMaxDuration),
+	}
+
+	// The trace endpoints are only registered when the MEMORY exporter is enabled, otherwise the buffer is always empty.
+	if hasTracerExporter(cfg.OtelExporter, "MEMORY") {
+		adminOpts = append(adminOpts, handleradmin.WithTraceBuffer(traceBuffer))
+	}
+
+	handlerAdmin, err := handleradmin.New(adminOpts...)
+	if err != nil {
+		slog.ErrorContext(systemCtx, "cannot prepare http handler for admin router", slog.Any("error", err))
+		return
+	}
+
+	// The system and admin routes are served on the admin port when it is set, otherwise on the public port.
+	adminEnabled := cfg.AdminPort > 0
+	restAPIOpts := []restapi.HTTPConfig{
 		restapi.WithBuildCommitID(buildCommitID),
 		restapi.WithBuildTime(buildTime),
 		restapi.WithStartupTime(startupTime),
+	}
 
-		// register all handler here
+	opsHandlers := []restapi.HTTPConfig{
 		restapi.AddHandler(handlerSystem),
-	)
+		restapi.AddHandler(handlerAdmin),
+	}
+
+	// ** setup server with graceful shutdown
+	slog.InfoContext(systemCtx, "preparing server http...")
+
+	// register all application handler here
+	publicOpts := append([]restapi.HTTPConfig{}, restAPIOpts...)
+	if !adminEnabled {
+		publicOpts = append(publicOpts, opsHandlers...)
+	}
+
+	restAPI, err := restapi.NewHTTP(publicOpts...)
 	if err != nil {
 		slog.ErrorContext(systemCtx, "error prepare rest api server", slog.Any("error", err))
 		return
 	}
 
+	var serverMux http.Handler = restAPI
+
+	// Recover the panic of the handler inside the span and access log, so both record the 500 response.
+	serverMux = httpservermw.RecoveryMiddleware(serverMux,
+		httpservermw.RecoveryWithLogger(logger),
+		httpservermw.RecoveryWithMetric(combinedMetrics),
+	)
+
 	// Register all endpoint that you won't need to be logged and traced.
 	// For example, /ping can be skipped (return false) because it will be exhaust your Kubernetes log
 	// if you set it as Readiness Probe.
@@ -199,8 +585,19 @@ func main() {
 			return true
 		}
 
-		switch strings.TrimRight(req.URL.Path, "/") {
-		case "/favicon.ico", "/ping":
+		path := strings.TrimRight(req.URL.Path, "/")
+		switch path {
+		case "/favicon.ico", "/ping", "/ready":
+			return false
+		}
+
+		// Health probes are called every few seconds by Kubernetes.
+		if strings.HasPrefix(path, "/healthz/") {
+			return false
+		}
+
+		// Admin and profiling endpoints carry the admin token in the request header, don't print it in the log.
+		if strings.HasPrefix(path, "/admin/") || strings.HasPrefix(path, "/debug/") {
 			return false
 		}
 
@@ -212,17 +609,37 @@ func main() {
 	// If we think that Logger middleware run before otelhttp middleware, you wrong!
 	// The order of these middleware are:
 	// 1. Remove trailing slash, then
-	// 2. Add Prometheus middleware metrics, then
-	// 3. Continue from request tracer span (if exist in request header) or create new tracer span, then
-	// 4. Inject a non-exported span for filtered routes (so handler logs always carry trace_id), then
-	// 5. Add middleware log!
+	// 2. Resolve route template (i.e. /users/:id) from the router, then
+	// 3. Add Prometheus middleware metrics, then
+	// 4. Reject the request when too many requests are in-flight, the client exceeds the rate limit or the body is too large, then
+	// 5. Continue from request tracer span (if exist in request header) or create new tracer span, then
+	// 6. Inject a non-exported span for filtered routes (so handler logs always carry trace_id), then
+	// 7. Add middleware log!
 
 	// Add logger middleware
-	serverMux = httpservermw.LoggingMiddleware(serverMux,
+	logMwOpts := []httpservermw.LoggerOpt{
 		httpservermw.LogMwWithLogger(logger),
 		httpservermw.LogMwWithTracer(otel.GetTracerProvider()),
 		httpservermw.LogMwWithFilter(filterLogEndpoint),
-	)
+		httpservermw.LogMwWithRedactPolicy(redactPolicy),
+		httpservermw.LogMwWithMaxBodyBytes(cfg.LogMaxBodyBytes),
+		httpservermw.LogMwWithPolicy(httpservermw.LogPolicy(cfg.LogPolicy)),
+		httpservermw.LogMwWithSampleRate(cfg.LogSampleRate),
+		httpservermw.LogMwWithRateLimit(cfg.LogRateLimit),
+		httpservermw.LogMwWithSlowThreshold(cfg.LogSlowThreshold),
+	}
+
+	for _, routePolicy := range cfg.LogRoutePolicies {
+		route, policy, ok := strings.Cut(routePolicy, "=")
+		if !ok {
+			log.Fatalln(fmt.Errorf("invalid LOG_ROUTE_POLICIES '%s', must be in format route=policy", routePolicy))
+			return
+		}
+
+		logMwOpts = append(logMwOpts, httpservermw.LogMwWithRoutePolicy(route, httpservermw.LogPolicy(policy)))
+	}
+
+	serverMux = httpservermw.LoggingMiddleware(serverMux, logMwOpts...)
 
 	// For routes filtered from otelhttp (e.g. /ping used as k8s readiness probe), otelhttp skips
 	// span creation entirely, leaving a zero trace_id in any handler logs. SpanInjectorMiddleware
@@ -247,23 +664,77 @@ func main() {
 				return fmt.Sprintf("%s %s [on nil url]", operation, r.Method)
 			}
 
-			return fmt.Sprintf("[%s] %s %s", operation, r.Method, r.URL.Path)
+			return fmt.Sprintf("[%s] %s %s", operation, r.Method, httpservermw.RouteFromRequest(r))
 		}),
 	)
 
+	// Reject the oversized request body before it is read by the logger and handler.
+	serverMux = httpservermw.BodyLimitMiddleware(serverMux,
+		httpservermw.BodyLimitWithMaxBytes(cfg.HTTPMaxBodyBytes),
+		httpservermw.BodyLimitWithMetric(combinedMetrics),
+	)
+
+	// Health probes are not limited, so the overloaded pod is not restarted by the liveness probe.
+	limitFilter := func(req *http.Request) bool {
+		if req.URL == nil {
+			return true
+		}
+
+		path := strings.TrimRight(req.URL.Path, "/")
+		return path != "/ping" && path != "/ready" && !strings.HasPrefix(path, "/healthz/")
+	}
+
+	// Reject the noisy client, before spending any work for tracing and logging.
+	if cfg.RateLimit != "" {
+		serverMux, err = newRateLimitMiddleware(cfg, serverMux, combinedMetrics, limitFilter)
+		if err != nil {
+			log.Fatalln(fmt.Errorf("invalid rate limit config: %w", err))
+			return
+		}
+	}
+
+	// Shed the load when too many requests are in-flight.
+	serverMux = httpservermw.MaxInFlightMiddleware(serverMux,
+		httpservermw.MaxInFlightWithLimit(cfg.HTTPMaxInFlight),
+		httpservermw.MaxInFlightWithRetryAfter(cfg.HTTPRetryAfter),
+		httpservermw.MaxInFlightWithMetric(combinedMetrics),
+		httpservermw.MaxInFlightWithFilter(limitFilter),
+	)
+
 	// Add Prometheus middleware metrics
 	// prepare middleware and handler for Prometheus at the same time
+	metricsPath := "/metrics"
+	if adminEnabled {
+		metricsPath = "" // served on the admin port
+	}
+
 	serverMux, err = httpservermw.PrometheusMiddleware(serverMux,
 		httpservermw.PrometheusWithMetric(combinedMetrics),
+		httpservermw.PrometheusWithMetricsPath(metricsPath),
 	)
 	if err != nil {
 		slog.ErrorContext(systemCtx, "cannot prepare prometheus middleware", slog.Any("error", err))
 		return
 	}
 
+	// Recover the panic of the middlewares (i.e. metric label mismatch), the handler panic is already recovered above.
+	serverMux = httpservermw.RecoveryMiddleware(serverMux,
+		httpservermw.RecoveryWithLogger(logger),
+		httpservermw.RecoveryWithMetric(combinedMetrics),
+	)
+
+	// Resolve the route template once, so metrics, span names and access log use the same low-cardinality value.
+	serverMux = httpservermw.RouteMiddleware(serverMux, restAPI)
+
 	// Remove trailing slashes.
 	serverMux = httpservermw.RemoveTrailingSlash(serverMux)
 
+	// Put the client certificate identity (mTLS) into the request context for the handlers and logs.
+	serverMux = httpservermw.PeerIdentityMiddleware(serverMux)
+
+	// Accept or generate X-Request-ID as the outermost middleware, so every log, span and error response has it.
+	serverMux = httpservermw.RequestIDMiddleware(serverMux)
+
 	httpPortStr := fmt.Sprintf(":%d", cfg.HTTPPort)
 
 	// Enable HTTP/1.1, TLS HTTP/2, and cleartext HTTP/2 (h2c) using the Go 1.24+ Protocols field,
@@ -274,46 +745,318 @@ func main() {
 	protocols.SetUnencryptedHTTP2(true)
 
 	httpServer := &http.Server{
-		Addr:      httpPortStr,
-		Handler:   serverMux,
-		Protocols: &protocols,
+		Addr:              httpPortStr,
+		Handler:           serverMux,
+		Protocols:         &protocols,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		MaxHeaderBytes:    cfg.HTTPMaxHeaderBytes,
+	}
+
+	// Serve HTTPS when the certificate is set, the certificate is reloaded from the files without restart.
+	tlsEnabled := cfg.TLSCertFile != ""
+	if tlsEnabled {
+		tlsReloader, tlsErr := newTLSReloader(cfg, logger)
+		if tlsErr != nil {
+			slog.ErrorContext(systemCtx, "cannot prepare tls", slog.Any("error", tlsErr))
+			return
+		}
+
+		defer func() {
+			_ = tlsReloader.Close()
+		}()
+
+		httpServer.TLSConfig = tlsReloader.TLSConfig()
+	}
+
+	listener, err := net.Listen("tcp", httpPortStr)
+	if err != nil {
+		slog.ErrorContext(systemCtx, fmt.Sprintf("cannot listen on port %s", httpPortStr), slog.Any("error", err))
+		return
+	}
+
+	// The admin listener is plain HTTP for the internal network only (kubelet probes and Prometheus scraper),
+	// it must not be exposed by the Kubernetes Service or Ingress.
+	var adminServer *http.Server
+	var adminListener net.Listener
+	if adminEnabled {
+		adminHandler, adminErr := newAdminHandler(append(restAPIOpts, opsHandlers...), combinedMetrics, logger)
+		if adminErr != nil {
+			slog.ErrorContext(systemCtx, "error prepare admin server", slog.Any("error", adminErr))
+			return
+		}
+
+		adminPortStr := fmt.Sprintf(":%d", cfg.AdminPort)
+
+		// WriteTimeout is not set, because the profile capture may take longer than the public write timeout.
+		adminServer = &http.Server{
+			Addr:              adminPortStr,
+			Handler:           adminHandler,
+			ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+			IdleTimeout:       cfg.HTTPIdleTimeout,
+			MaxHeaderBytes:    cfg.HTTPMaxHeaderBytes,
+		}
+
+		adminListener, err = net.Listen("tcp", adminPortStr)
+		if err != nil {
+			_ = listener.Close()
+			slog.ErrorContext(systemCtx, fmt.Sprintf("cannot listen on admin port %s", adminPortStr), slog.Any("error", err))
+			return
+		}
+	}
+
+	var errChan = make(chan error, 2)
+	if adminServer != nil {
+		go func() {
+			slog.InfoContext(systemCtx, fmt.Sprintf("starting admin http on port %s", adminServer.Addr))
+			errChan <- adminServer.Serve(adminListener)
+		}()
 	}
 
-	var errChan = make(chan error, 1)
 	go func() {
+		if tlsEnabled {
+			slog.InfoContext(systemCtx, fmt.Sprintf("starting https on port %s", httpPortStr))
+			errChan <- httpServer.ServeTLS(listener, "", "") // the certificate is from TLSConfig
+			return
+		}
+
 		slog.InfoContext(systemCtx, fmt.Sprintf("starting http on port %s", httpPortStr))
-		errChan <- httpServer.ListenAndServe()
+		errChan <- httpServer.Serve(listener)
 	}()
 
+	// The startup probe passes and the readiness probe begins to run the checks once the server is listening.
+	healthChecker.MarkStarted()
+
 	var signalChan = make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
 	select {
 	case s := <-signalChan:
 		msg := fmt.Sprintf("got an interrupt: %+v", s)
-		slog.ErrorContext(systemCtx, msg)
+		slog.InfoContext(systemCtx, msg)
 	case _err := <-errChan:
-		if _err != nil {
+		if _err != nil && !errors.Is(_err, http.ErrServerClosed) {
 			msg := fmt.Sprintf("error while running server: %s", _err)
 			slog.ErrorContext(systemCtx, msg)
 		}
+
+		// The server is already stopped, there is no connection to drain.
+		_ = httpServer.Close()
+		if adminServer != nil {
+			_ = adminServer.Close()
+		}
+
+		return
+	}
+
+	// ** Graceful shutdown sequence:
+	// 1. Flip readiness to failing, so the load balancer stops routing new requests to this instance.
+	// 2. Wait for ShutdownDelay, because the load balancer need time to observe the failing readiness.
+	// 3. Stop accepting new connections and drain the in-flight requests, the ShutdownTimeout deadline starts here.
+	// 4. Flush tracer provider and close metrics (done by the deferred functions above) within the same deadline.
+	handlerSystem.SetReady(false)
+
+	slog.InfoContext(systemCtx, fmt.Sprintf("readiness set to failing, waiting %s before stop accepting connections", cfg.ShutdownDelay))
+	time.Sleep(cfg.ShutdownDelay)
+
+	shutdownCtx, cancelShutdown := shutdown.Context()
+	defer cancelShutdown()
+
+	slog.InfoContext(shutdownCtx, fmt.Sprintf("draining in-flight requests and flushing telemetry within %s", cfg.ShutdownTimeout))
+	httpServer.SetKeepAlivesEnabled(false)
+	if _err := httpServer.Shutdown(shutdownCtx); _err != nil {
+		slog.ErrorContext(shutdownCtx, "cannot gracefully shutdown http server, force closing connections", slog.Any("error", _err))
+
+		if _err = httpServer.Close(); _err != nil {
+			slog.ErrorContext(shutdownCtx, "force close http server error", slog.Any("error", _err))
+		}
+	}
+
+	slog.InfoContext(shutdownCtx, "http server stopped")
+
+	// The admin server is stopped last, so the probes and metrics are available while draining the public requests.
+	if adminServer != nil {
+		if _err := adminServer.Shutdown(shutdownCtx); _err != nil {
+			slog.ErrorContext(shutdownCtx, "cannot gracefully shutdown admin server", slog.Any("error", _err))
+			_ = adminServer.Close()
+		}
+
+		slog.InfoContext(shutdownCtx, "admin server stopped")
+	}
+}
+
+// newAdminHandler returns the handler of the admin port, serving the metrics and the given system and admin routes.
+// The admin requests are not logged, traced nor counted in the http_requests_total metric.
+func newAdminHandler(opts []restapi.HTTPConfig, metric metrics.Metric, logger *slog.Logger) (http.Handler, error) {
+	adminAPI, err := restapi.NewHTTP(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	if metricsHandler := metric.HandlerFunc(); metricsHandler != nil {
+		mux.Handle("GET /metrics", metricsHandler)
+	}
+
+	mux.Handle("/", adminAPI)
+
+	var handler http.Handler = mux
+	handler = httpservermw.RecoveryMiddleware(handler,
+		httpservermw.RecoveryWithLogger(logger),
+		httpservermw.RecoveryWithMetric(metric),
+	)
+	handler = httpservermw.RemoveTrailingSlash(handler)
+	handler = httpservermw.RequestIDMiddleware(handler)
+	return handler, nil
+}
+
+// hasTracerExporter reports whether the comma-separated OTEL_EXPORTER contains the exporter name.
+func hasTracerExporter(exporters, name string) bool {
+	for _, n := range strings.Split(exporters, ",") {
+		if strings.EqualFold(strings.TrimSpace(n), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shutdownBudget is the deadline of the graceful shutdown, shared by every step of the shutdown.
+// The deadline starts on the first call of Context, so the grace period starts when the shutdown begins, not at boot.
+type shutdownBudget struct {
+	timeout  time.Duration
+	once     sync.Once
+	deadline time.Time
+}
+
+// Context returns new context bounded by the shared deadline.
+// It intentionally not derived from systemCtx, so it is only bounded by the shutdown deadline.
+func (s *shutdownBudget) Context() (context.Context, context.CancelFunc) {
+	s.once.Do(func() {
+		s.deadline = time.Now().Add(s.timeout)
+	})
+
+	return context.WithDeadline(context.Background(), s.deadline)
+}
+
+// newTLSReloader returns the TLS certificate reloader from the TLS_* config.
+func newTLSReloader(cfg Config, logger *slog.Logger) (*tlsreload.Reloader, error) {
+	minVersion, err := tlsreload.ParseVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := tlsreload.ParseCipherSuites(cfg.TLSCipherSuites)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS_CIPHER_SUITES: %w", err)
+	}
+
+	opts := []tlsreload.Opt{
+		tlsreload.WithCertificate(cfg.TLSCertFile, cfg.TLSKeyFile),
+		tlsreload.WithMinVersion(minVersion),
+		tlsreload.WithCipherSuites(cipherSuites),
+		tlsreload.WithReloadInterval(cfg.TLSReloadInterval),
+		tlsreload.WithLogger(logger),
+	}
+
+	if cfg.TLSClientCAFile != "" {
+		clientAuth, err := tlsreload.ParseClientAuth(cfg.TLSClientAuth)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, tlsreload.WithClientCA(cfg.TLSClientCAFile), tlsreload.WithClientAuth(clientAuth))
 	}
+
+	return tlsreload.New(opts...)
 }
 
-// newResource returns a resource describing this application.
-func newResource(ctx context.Context, serviceName string) *resource.Resource {
-	r := resource.NewWithAttributes(
-		semconv.SchemaURL,
-		semconv.ServiceName(serviceName),
-		semconv.ServiceVersion("v0.1.0"),
-		semconv.DeploymentEnvironmentName("demo"),
+// newRateLimitMiddleware returns the rate limit middleware from the RATE_LIMIT_* config.
+func newRateLimitMiddleware(cfg Config, next http.Handler, metric metrics.Metric, filter httpservermw.Filter) (http.Handler, error) {
+	limit, err := httpservermw.ParseRateLimit(cfg.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	keyFuncs := make([]httpservermw.RateLimitKeyFunc, 0, len(cfg.RateLimitKeys))
+	for _, key := range cfg.RateLimitKeys {
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "ip":
+			keyFuncs = append(keyFuncs, httpservermw.RateLimitKeyByIP(cfg.RateLimitIPHeader))
+		case "header":
+			keyFuncs = append(keyFuncs, httpservermw.RateLimitKeyByHeader(cfg.RateLimitKeyHeader))
+		case "peer":
+			keyFuncs = append(keyFuncs, httpservermw.RateLimitKeyByPeerIdentity())
+		default:
+			return nil, fmt.Errorf("unknown RATE_LIMIT_KEYS '%s', must be ip, header or peer", key)
+		}
+	}
+
+	store, err := httpservermw.NewRateLimitMemoryStore(
+		httpservermw.RateLimitMemoryWithAlgorithm(httpservermw.RateLimitAlgorithm(cfg.RateLimitAlgorithm)),
+		httpservermw.RateLimitMemoryWithMaxKeys(cfg.RateLimitMaxKeys),
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []httpservermw.RateLimitOpt{
+		httpservermw.RateLimitWithLimit(limit),
+		httpservermw.RateLimitWithKeyFunc(httpservermw.RateLimitKeyFirst(keyFuncs...)),
+		httpservermw.RateLimitWithStore(store),
+		httpservermw.RateLimitWithFilter(filter),
+		httpservermw.RateLimitWithMetric(metric),
+	}
+
+	for _, routeLimit := range cfg.RateLimitRoutes {
+		route, limitStr, ok := strings.Cut(routeLimit, "=")
+		if !ok || strings.TrimSpace(route) == "" {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_ROUTES '%s', must be in format route=requests/window", routeLimit)
+		}
+
+		limit, err := httpservermw.ParseRateLimit(limitStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_ROUTES '%s': %w", routeLimit, err)
+		}
+
+		opts = append(opts, httpservermw.RateLimitWithRouteLimit(route, limit))
+	}
+
+	return httpservermw.RateLimitMiddleware(next, opts...), nil
+}
+
+// newSampler returns the sampler from OTEL_TRACES_SAMPLER, with the sampling ratio per route on top of it.
+// The route is resolved by httpservermw.RouteMiddleware which is placed before otelhttp starts the request span.
+func newSampler(cfg Config) (trace.Sampler, error) {
+	defaultSampler, err := oteltracer.NewSampler(cfg.OtelTracesSampler, cfg.OtelTracesSamplerArg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []oteltracer.RuleSamplerOpt{
+		oteltracer.RuleSamplerWithKeepErrors(cfg.OtelTracesKeepErrors),
+		oteltracer.RuleSamplerWithRouteResolver(func(p trace.SamplingParameters) string {
+			route, _ := httpservermw.RouteFromContext(p.ParentContext)
+			return route
+		}),
+	}
+
+	for _, routeRatio := range cfg.OtelTracesSamplerRoutes {
+		route, ratioStr, ok := strings.Cut(routeRatio, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ROUTES '%s', must be in format route=ratio", routeRatio)
+		}
+
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(ratioStr), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ROUTES '%s': %w", routeRatio, err)
+		}
 
-	if r == nil {
-		slog.ErrorContext(ctx, "cannot use OpenTelemetry resource because of nil, fallback to default resource")
-		return resource.Default()
+		opts = append(opts, oteltracer.RuleSamplerWithRoute(route, ratio))
 	}
 
-	return r
+	return oteltracer.NewRuleSampler(defaultSampler, opts...)
 }
 
 type otelErrHandler struct{}
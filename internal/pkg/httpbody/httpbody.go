@@ -0,0 +1,316 @@
+// Package httpbody captures HTTP request and response body for logging, with bounded memory.
+// Only the first max bytes are kept, while the total length is counted separately,
+// and the body which is not meant to be read as text (binary, multipart, compressed) is summarized instead.
+package httpbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMaxBytes is the default maximum number of body bytes kept for logging.
+const DefaultMaxBytes = 16 * 1024
+
+// Kind is how the body is captured, decided by Content-Type and Content-Encoding header.
+type Kind int
+
+const (
+	// KindText is captured as string, or as JSON when it is a valid JSON.
+	KindText Kind = iota
+
+	// KindJSON is captured as decoded JSON.
+	KindJSON
+
+	// KindForm is application/x-www-form-urlencoded body, captured as url.Values.
+	KindForm
+
+	// KindMultipart is summarized, since it usually contains file upload.
+	KindMultipart
+
+	// KindEncoded is the compressed body (i.e. Content-Encoding gzip), summarized.
+	KindEncoded
+
+	// KindBinary is summarized, i.e. image, pdf or application/octet-stream.
+	KindBinary
+)
+
+// KindOf returns the Kind of body from the header.
+func KindOf(header http.Header) Kind {
+	if encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding"))); encoding != "" && encoding != "identity" {
+		return KindEncoded
+	}
+
+	contentType := header.Get("Content-Type")
+	if strings.TrimSpace(contentType) == "" {
+		// unknown content type is treated as text, the captured bytes is checked whether it is a valid UTF-8.
+		return KindText
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return KindBinary
+	}
+
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return KindJSON
+	case mediaType == "application/x-www-form-urlencoded":
+		return KindForm
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return KindMultipart
+	case mediaType == "application/gzip", mediaType == "application/zip", mediaType == "application/x-gzip":
+		return KindEncoded
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/javascript", mediaType == "application/x-ndjson",
+		mediaType == "application/graphql":
+		return KindText
+	default:
+		return KindBinary
+	}
+}
+
+// Readable returns true if the body of this Kind is captured, otherwise it is only counted and summarized.
+func (k Kind) Readable() bool {
+	switch k {
+	case KindText, KindJSON, KindForm:
+		return true
+	default:
+		return false
+	}
+}
+
+// Buffer is io.Writer that keeps the first max bytes written and counts the total bytes written.
+// Write never fails, so it is safe to be used in io.TeeReader or io.MultiWriter.
+type Buffer struct {
+	max   int
+	buf   bytes.Buffer
+	total int64
+}
+
+var _ io.Writer = (*Buffer)(nil)
+
+// NewBuffer creates Buffer that keeps at most max bytes. Zero or negative max means only counting the bytes.
+func NewBuffer(max int) *Buffer {
+	return &Buffer{max: max}
+}
+
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.total += int64(len(p))
+
+	if remaining := b.max - b.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buf.Write(p[:remaining])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+
+	return len(p), nil
+}
+
+// Bytes returns the kept bytes.
+func (b *Buffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Len returns the total bytes written.
+func (b *Buffer) Len() int64 {
+	return b.total
+}
+
+// Truncated returns true if some bytes are not kept.
+func (b *Buffer) Truncated() bool {
+	return b.total > int64(b.buf.Len())
+}
+
+// Body is the captured body to be logged.
+type Body struct {
+	// Value is the decoded JSON, url.Values, string, or summary of the body.
+	Value any
+
+	// Len is the true total length of the body, -1 if unknown.
+	Len int64
+
+	// Truncated is true when only the first part of body is captured.
+	Truncated bool
+
+	// Err is the error when decoding the body, the Value fallbacks to string.
+	Err error
+}
+
+// Capture converts the captured bytes in Buffer into Body, using the header to decide how it is captured.
+// The total is the true length of body (i.e. b.Len(), or Content-Length), or -1 if unknown.
+func Capture(header http.Header, b *Buffer, total int64) Body {
+	body := Body{
+		Len:       total,
+		Truncated: b.Truncated() || total > int64(len(b.Bytes())),
+	}
+
+	if total == 0 && len(b.Bytes()) <= 0 {
+		return body
+	}
+
+	kind := KindOf(header)
+	if !kind.Readable() {
+		body.Value = summary(kind, header, total)
+		return body
+	}
+
+	raw := b.Bytes()
+	if body.Truncated {
+		raw = trimIncompleteRune(raw)
+	}
+
+	if kind == KindText && !utf8.Valid(raw) {
+		body.Value = summary(KindBinary, header, total)
+		return body
+	}
+
+	if body.Truncated {
+		body.Value = truncatedString(raw, total)
+		return body
+	}
+
+	switch kind {
+	case KindForm:
+		values, err := url.ParseQuery(string(raw))
+		if err != nil {
+			body.Value = string(raw)
+			body.Err = fmt.Errorf("error parse form body: %w", err)
+			return body
+		}
+
+		body.Value = values
+		return body
+
+	default:
+		var decoded any
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			body.Value = string(raw) // Fallback with the real body string
+			if kind == KindJSON || header.Get("Content-Type") == "" {
+				body.Err = fmt.Errorf("error unmarshal body: %w", err)
+			}
+
+			return body
+		}
+
+		body.Value = decoded
+		return body
+	}
+}
+
+func summary(kind Kind, header http.Header, total int64) string {
+	size := "unknown size"
+	if total >= 0 {
+		size = fmt.Sprintf("%d bytes", total)
+	}
+
+	switch kind {
+	case KindMultipart:
+		return fmt.Sprintf("[multipart body: %s, %s]", header.Get("Content-Type"), size)
+	case KindEncoded:
+		encoding := header.Get("Content-Encoding")
+		if encoding == "" {
+			encoding = header.Get("Content-Type")
+		}
+
+		return fmt.Sprintf("[encoded body: %s, %s]", encoding, size)
+	default:
+		contentType := header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "unknown content type"
+		}
+
+		return fmt.Sprintf("[binary body: %s, %s]", contentType, size)
+	}
+}
+
+// truncatedString returns the captured bytes with the truncation marker.
+func truncatedString(raw []byte, total int64) string {
+	if total > int64(len(raw)) {
+		return fmt.Sprintf("%s...[truncated, %d bytes total]", raw, total)
+	}
+
+	return string(raw) + "...[truncated]"
+}
+
+// trimIncompleteRune removes the last incomplete UTF-8 character cut by the max bytes.
+func trimIncompleteRune(b []byte) []byte {
+	for i := 0; i < utf8.UTFMax && len(b) > 0; i++ {
+		r, size := utf8.DecodeLastRune(b)
+		if r != utf8.RuneError || size != 1 {
+			return b
+		}
+
+		b = b[:len(b)-1]
+	}
+
+	return b
+}
+
+// Peek reads at most max bytes from body for logging, then returns the io.ReadCloser that replays the whole body,
+// so the next reader (handler or caller) still reads the complete body.
+// The returned total is the body length when the whole body is read within max bytes, otherwise -1.
+func Peek(body io.ReadCloser, max int) (buf *Buffer, replay io.ReadCloser, total int64, err error) {
+	buf = NewBuffer(max)
+	if body == nil || body == http.NoBody {
+		return buf, body, 0, nil
+	}
+
+	// Read 1 byte more than max, so we know whether the body is longer than max.
+	prefix := &bytes.Buffer{}
+	n, err := io.CopyN(prefix, body, int64(max)+1)
+	_, _ = buf.Write(prefix.Bytes())
+
+	replay = &readCloser{
+		Reader: io.MultiReader(bytes.NewReader(prefix.Bytes()), body),
+		Closer: body,
+	}
+
+	switch {
+	case err == io.EOF:
+		return buf, replay, n, nil
+	case err != nil:
+		return buf, replay, -1, err
+	default:
+		return buf, replay, -1, nil
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// CaptureReader captures the request or response body for logging, then returns the io.ReadCloser
+// which must replace the original body, so the next reader still reads the complete body.
+// The body which is not Readable is not read at all, only summarized using the contentLength (-1 if unknown).
+func CaptureReader(header http.Header, body io.ReadCloser, contentLength int64, max int) (Body, io.ReadCloser, error) {
+	if body == nil || body == http.NoBody {
+		return Body{}, body, nil
+	}
+
+	// Non-nil body with zero Content-Length means unknown length in outgoing request.
+	if contentLength <= 0 {
+		contentLength = -1
+	}
+
+	if !KindOf(header).Readable() {
+		return Capture(header, NewBuffer(0), contentLength), body, nil
+	}
+
+	buf, replay, total, err := Peek(body, max)
+	if total < 0 {
+		total = contentLength
+	}
+
+	return Capture(header, buf, total), replay, err
+}
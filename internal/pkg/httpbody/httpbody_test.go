@@ -0,0 +1,183 @@
+package httpbody_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpbody"
+)
+
+func header(kv ...string) http.Header {
+	h := http.Header{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		h.Set(kv[i], kv[i+1])
+	}
+
+	return h
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		header http.Header
+		kind   httpbody.Kind
+	}{
+		{header(), httpbody.KindText},
+		{header("Content-Type", "application/json; charset=utf-8"), httpbody.KindJSON},
+		{header("Content-Type", "application/problem+json"), httpbody.KindJSON},
+		{header("Content-Type", "application/x-www-form-urlencoded"), httpbody.KindForm},
+		{header("Content-Type", "multipart/form-data; boundary=x"), httpbody.KindMultipart},
+		{header("Content-Type", "application/gzip"), httpbody.KindEncoded},
+		{header("Content-Type", "application/json", "Content-Encoding", "gzip"), httpbody.KindEncoded},
+		{header("Content-Type", "application/json", "Content-Encoding", "identity"), httpbody.KindJSON},
+		{header("Content-Type", "text/html"), httpbody.KindText},
+		{header("Content-Type", "application/soap+xml"), httpbody.KindText},
+		{header("Content-Type", "image/png"), httpbody.KindBinary},
+		{header("Content-Type", "application/octet-stream"), httpbody.KindBinary},
+		{header("Content-Type", ";;invalid"), httpbody.KindBinary},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.kind, httpbody.KindOf(tt.header), tt.header)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	t.Run("accumulate multiple write", func(t *testing.T) {
+		buf := httpbody.NewBuffer(10)
+		n, err := buf.Write([]byte("hello "))
+		assert.NoError(t, err)
+		assert.Equal(t, 6, n)
+
+		n, err = buf.Write([]byte("world"))
+		assert.NoError(t, err)
+		assert.Equal(t, 5, n)
+
+		assert.Equal(t, "hello worl", string(buf.Bytes()))
+		assert.EqualValues(t, 11, buf.Len())
+		assert.True(t, buf.Truncated())
+	})
+
+	t.Run("zero max only counts", func(t *testing.T) {
+		buf := httpbody.NewBuffer(0)
+		_, _ = buf.Write([]byte("hello"))
+		assert.Empty(t, buf.Bytes())
+		assert.EqualValues(t, 5, buf.Len())
+		assert.True(t, buf.Truncated())
+	})
+}
+
+func capture(h http.Header, max int, body string) httpbody.Body {
+	buf := httpbody.NewBuffer(max)
+	_, _ = buf.Write([]byte(body))
+	return httpbody.Capture(h, buf, buf.Len())
+}
+
+func TestCapture(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		body := capture(header("Content-Type", "application/json"), 10, "")
+		assert.Nil(t, body.Value)
+		assert.Zero(t, body.Len)
+		assert.NoError(t, body.Err)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		body := capture(header("Content-Type", "application/json"), 100, `{"foo":"bar"}`)
+		assert.Equal(t, map[string]any{"foo": "bar"}, body.Value)
+		assert.EqualValues(t, 13, body.Len)
+		assert.False(t, body.Truncated)
+		assert.NoError(t, body.Err)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		body := capture(header("Content-Type", "application/json"), 100, `{"foo"`)
+		assert.Equal(t, `{"foo"`, body.Value)
+		assert.Error(t, body.Err)
+	})
+
+	t.Run("plain text is not an error", func(t *testing.T) {
+		body := capture(header("Content-Type", "text/plain"), 100, "hello")
+		assert.Equal(t, "hello", body.Value)
+		assert.NoError(t, body.Err)
+	})
+
+	t.Run("form", func(t *testing.T) {
+		body := capture(header("Content-Type", "application/x-www-form-urlencoded"), 100, "a=1&b=2")
+		assert.Equal(t, url.Values{"a": {"1"}, "b": {"2"}}, body.Value)
+	})
+
+	t.Run("truncated with marker and total length", func(t *testing.T) {
+		body := capture(header("Content-Type", "application/json"), 10, `{"foo":"bar","baz":"qux"}`)
+		assert.Equal(t, `{"foo":"ba...[truncated, 25 bytes total]`, body.Value)
+		assert.EqualValues(t, 25, body.Len)
+		assert.True(t, body.Truncated)
+		assert.NoError(t, body.Err)
+	})
+
+	t.Run("truncated does not cut utf-8 character", func(t *testing.T) {
+		body := capture(header("Content-Type", "text/plain"), 4, "abcé")
+		assert.Equal(t, "abc...[truncated, 5 bytes total]", body.Value)
+	})
+
+	t.Run("non utf-8 text is summarized", func(t *testing.T) {
+		body := capture(header(), 100, string([]byte{0xff, 0xfe, 0x00}))
+		assert.Equal(t, "[binary body: unknown content type, 3 bytes]", body.Value)
+	})
+
+	t.Run("summarized", func(t *testing.T) {
+		assert.Equal(t, "[multipart body: multipart/form-data; boundary=x, 5 bytes]",
+			capture(header("Content-Type", "multipart/form-data; boundary=x"), 100, "hello").Value)
+		assert.Equal(t, "[encoded body: br, 5 bytes]",
+			capture(header("Content-Type", "text/plain", "Content-Encoding", "br"), 100, "hello").Value)
+		assert.Equal(t, "[binary body: image/png, 5 bytes]",
+			capture(header("Content-Type", "image/png"), 100, "hello").Value)
+	})
+}
+
+func TestCaptureReader(t *testing.T) {
+	t.Run("nil body", func(t *testing.T) {
+		body, replay, err := httpbody.CaptureReader(header(), nil, 0, 10)
+		assert.NoError(t, err)
+		assert.Nil(t, replay)
+		assert.Nil(t, body.Value)
+	})
+
+	t.Run("replay the whole body", func(t *testing.T) {
+		original := strings.Repeat("a", 100)
+		body, replay, err := httpbody.CaptureReader(header("Content-Type", "text/plain"), io.NopCloser(strings.NewReader(original)), -1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, "aaaaaaaaaa...[truncated]", body.Value)
+		assert.EqualValues(t, -1, body.Len)
+		assert.True(t, body.Truncated)
+
+		b, err := io.ReadAll(replay)
+		require.NoError(t, err)
+		assert.Equal(t, original, string(b))
+	})
+
+	t.Run("use content length as total", func(t *testing.T) {
+		original := strings.Repeat("a", 100)
+		body, _, err := httpbody.CaptureReader(header("Content-Type", "text/plain"), io.NopCloser(strings.NewReader(original)), 100, 10)
+		require.NoError(t, err)
+		assert.Equal(t, "aaaaaaaaaa...[truncated, 100 bytes total]", body.Value)
+		assert.EqualValues(t, 100, body.Len)
+	})
+
+	t.Run("non readable body is not read", func(t *testing.T) {
+		reader := bytes.NewReader([]byte{0x00, 0x01})
+		body, replay, err := httpbody.CaptureReader(header("Content-Type", "image/png"), io.NopCloser(reader), 2, 10)
+		require.NoError(t, err)
+		assert.Equal(t, "[binary body: image/png, 2 bytes]", body.Value)
+		assert.Equal(t, 2, reader.Len())
+
+		b, err := io.ReadAll(replay)
+		require.NoError(t, err)
+		assert.Equal(t, []byte{0x00, 0x01}, b)
+	})
+}
@@ -1,17 +1,17 @@
 package httpclientmw
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpbody"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/redact"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/requestid"
 	"go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/trace/noop"
 )
@@ -22,15 +22,17 @@ const (
 
 // OutgoingLog holds log data for an outgoing HTTP request or response.
 type OutgoingLog struct {
-	Method      string            `json:"method,omitempty"`
-	Host        string            `json:"host,omitempty"`
-	Path        string            `json:"path,omitempty"`
-	StatusCode  int               `json:"statusCode,omitempty"`
-	Header      map[string]string `json:"header,omitempty"`
-	Body        any               `json:"body,omitempty"`
-	BodyLen     int64             `json:"bodyLen,omitempty"`
-	Error       string            `json:"error,omitempty"`
-	ElapsedTime int64             `json:"elapsedTime,omitempty"`
+	Method        string            `json:"method,omitempty"`
+	Host          string            `json:"host,omitempty"`
+	Path          string            `json:"path,omitempty"`
+	StatusCode    int               `json:"statusCode,omitempty"`
+	Header        map[string]string `json:"header,omitempty"`
+	Body          any               `json:"body,omitempty"`
+	BodyLen       int64             `json:"bodyLen,omitempty"` // true total length, -1 if unknown
+	BodyTruncated bool              `json:"bodyTruncated,omitempty"`
+	QueryParams   url.Values        `json:"queryParams,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	ElapsedTime   int64             `json:"elapsedTime,omitempty"`
 }
 
 type Opt func(*roundTripper) error
@@ -86,6 +88,33 @@ func WithTracer(t trace.TracerProvider) Opt {
 	}
 }
 
+// WithRedactPolicy set the policy to mask sensitive header, query parameter and body before it is logged.
+// Default to redact.Default policy. Use empty policy from redact.New() to log everything as is.
+func WithRedactPolicy(p *redact.Policy) Opt {
+	return func(tripper *roundTripper) error {
+		if p == nil {
+			return fmt.Errorf("cannot use nil redact policy")
+		}
+
+		tripper.redactPolicy = p
+		return nil
+	}
+}
+
+// WithMaxBodyBytes set the maximum bytes of request and response body to be logged.
+// The body is truncated with marker, while the true total length is still logged. Default to httpbody.DefaultMaxBytes.
+// Zero means the body is never logged, only its length.
+func WithMaxBodyBytes(n int) Opt {
+	return func(tripper *roundTripper) error {
+		if n < 0 {
+			return fmt.Errorf("max body bytes cannot be negative")
+		}
+
+		tripper.maxBodyBytes = n
+		return nil
+	}
+}
+
 // roundTripper hold an implementation of http.RoundTripper
 type roundTripper struct {
 	base           http.RoundTripper
@@ -93,6 +122,8 @@ type roundTripper struct {
 	logger         *slog.Logger
 	tracerProvider trace.TracerProvider
 	tracer         trace.Tracer
+	redactPolicy   *redact.Policy
+	maxBodyBytes   int
 }
 
 var _ http.RoundTripper = (*roundTripper)(nil)
@@ -112,6 +143,8 @@ func NewHttpRoundTripper(opts ...Opt) http.RoundTripper {
 		logger:         slog.Default(),
 		tracerProvider: noopTracer,
 		tracer:         newTracer(noopTracer),
+		redactPolicy:   nil,
+		maxBodyBytes:   httpbody.DefaultMaxBytes,
 	}
 
 	for _, opt := range opts {
@@ -128,6 +161,15 @@ func NewHttpRoundTripper(opts ...Opt) http.RoundTripper {
 
 func (r *roundTripper) applyConfig() {
 	r.tracer = newTracer(r.tracerProvider)
+
+	if r.redactPolicy == nil {
+		defaultPolicy, err := redact.Default()
+		if err != nil {
+			panic(err)
+		}
+
+		r.redactPolicy = defaultPolicy
+	}
 }
 
 // RoundTrip do a http.RoundTrip and log the request/response body.
@@ -136,6 +178,17 @@ func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 		return nil, fmt.Errorf("http: nil Request")
 	}
 
+	// Forward the request ID of the incoming request, so the downstream service can log the same ID.
+	// The request is cloned because RoundTripper must not modify the caller's request header.
+	if id := requestid.FromContext(req.Context()); id != "" && req.Header.Get(requestid.Header) == "" {
+		req = req.Clone(req.Context())
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+
+		req.Header.Set(requestid.Header, id)
+	}
+
 	t0 := time.Now()
 	ctx := req.Context()
 
@@ -147,43 +200,25 @@ func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	ctx, span := r.tracer.Start(ctx, fmt.Sprintf("HTTP %s %s", req.Method, reqURL.EscapedPath()))
 	defer span.End()
 
-	// Capture outgoing request body, then restore it so the base transport can read it.
-	var (
-		reqBodyBuf = &bytes.Buffer{}
-		reqBodyLen int64
-		reqErrCum  error
-	)
-
-	if req.Body != nil {
-		if n, err := io.Copy(reqBodyBuf, req.Body); err != nil {
-			reqErrCum = errors.Join(reqErrCum, fmt.Errorf("copy request body: %w", err))
-			reqBodyBuf = &bytes.Buffer{}
-		} else {
-			reqBodyLen = n
-		}
-
-		if err := req.Body.Close(); err != nil {
-			reqErrCum = errors.Join(reqErrCum, fmt.Errorf("close request body: %w", err))
-		}
-
-		req.Body = io.NopCloser(bytes.NewReader(reqBodyBuf.Bytes()))
-	}
+	// Capture at most maxBodyBytes of outgoing request body, then replace it with the replaying body,
+	// so the base transport still sends the complete body.
+	reqBody, replayReqBody, err := httpbody.CaptureReader(req.Header, req.Body, req.ContentLength, r.maxBodyBytes)
+	req.Body = replayReqBody
 
-	var reqBodyCaptured any
-	if reqBodyBuf.Len() > 0 {
-		if err := json.Unmarshal(reqBodyBuf.Bytes(), &reqBodyCaptured); err != nil {
-			reqErrCum = errors.Join(reqErrCum, fmt.Errorf("unmarshal request body: %w", err))
-			reqBodyCaptured = reqBodyBuf.String()
-		}
+	reqErrCum := reqBody.Err
+	if err != nil {
+		reqErrCum = errors.Join(fmt.Errorf("read request body: %w", err), reqErrCum)
 	}
 
 	reqLog := OutgoingLog{
-		Method:  req.Method,
-		Host:    req.Host,
-		Path:    reqURL.Path,
-		Header:  toSimpleMap(req.Header),
-		Body:    reqBodyCaptured,
-		BodyLen: reqBodyLen,
+		Method:        req.Method,
+		Host:          req.Host,
+		Path:          reqURL.Path,
+		Header:        toSimpleMap(r.redactPolicy.Header(req.Header)),
+		Body:          r.redactPolicy.Body(reqBody.Value),
+		BodyLen:       reqBody.Len,
+		BodyTruncated: reqBody.Truncated,
+		QueryParams:   r.redactPolicy.Query(reqURL.Query()),
 	}
 	if reqErrCum != nil {
 		reqLog.Error = reqErrCum.Error()
@@ -200,45 +235,26 @@ func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 		return nil, nil
 	}
 
-	// Capture response body, then restore it so the caller can read it.
-	var (
-		respBodyBuf = &bytes.Buffer{}
-		respBodyLen int64
-		respErrCum  error
-	)
-
-	if resp.Body != nil {
-		if n, err := io.Copy(respBodyBuf, resp.Body); err != nil {
-			respErrCum = errors.Join(respErrCum, fmt.Errorf("copy response body: %w", err))
-			respBodyBuf = &bytes.Buffer{}
-		} else {
-			respBodyLen = n
-		}
+	// Capture at most maxBodyBytes of response body, then replace it with the replaying body,
+	// so the caller still reads the complete body.
+	respBody, replayRespBody, err := httpbody.CaptureReader(resp.Header, resp.Body, resp.ContentLength, r.maxBodyBytes)
+	resp.Body = replayRespBody
 
-		if err := resp.Body.Close(); err != nil {
-			respErrCum = errors.Join(respErrCum, fmt.Errorf("close response body: %w", err))
-		}
-
-		resp.Body = io.NopCloser(bytes.NewReader(respBodyBuf.Bytes()))
-	}
-
-	var respBodyCaptured any
-	if respBodyBuf.Len() > 0 {
-		if err := json.Unmarshal(respBodyBuf.Bytes(), &respBodyCaptured); err != nil {
-			respErrCum = errors.Join(respErrCum, fmt.Errorf("unmarshal response body: %w", err))
-			respBodyCaptured = respBodyBuf.String()
-		}
+	respErrCum := respBody.Err
+	if err != nil {
+		respErrCum = errors.Join(fmt.Errorf("read response body: %w", err), respErrCum)
 	}
 
 	respLog := OutgoingLog{
-		Method:      req.Method,
-		Host:        req.Host,
-		Path:        reqURL.Path,
-		StatusCode:  resp.StatusCode,
-		Header:      toSimpleMap(resp.Header),
-		Body:        respBodyCaptured,
-		BodyLen:     respBodyLen,
-		ElapsedTime: time.Since(t0).Milliseconds(),
+		Method:        req.Method,
+		Host:          req.Host,
+		Path:          reqURL.Path,
+		StatusCode:    resp.StatusCode,
+		Header:        toSimpleMap(r.redactPolicy.Header(resp.Header)),
+		Body:          r.redactPolicy.Body(respBody.Value),
+		BodyLen:       respBody.Len,
+		BodyTruncated: respBody.Truncated,
+		ElapsedTime:   time.Since(t0).Milliseconds(),
 	}
 	if respErrCum != nil {
 		respLog.Error = respErrCum.Error()
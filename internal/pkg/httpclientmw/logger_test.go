@@ -2,16 +2,21 @@ package httpclientmw
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/redact"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/requestid"
 )
 
 var noopTracer = noop.NewTracerProvider()
@@ -80,6 +85,37 @@ func TestWithTracer(t *testing.T) {
 	})
 }
 
+func TestWithMaxBodyBytes(t *testing.T) {
+	t.Run("on negative", func(t *testing.T) {
+		opt := WithMaxBodyBytes(-1)
+		err := opt(roundTripperInstance)
+		assert.Error(t, err)
+	})
+
+	t.Run("on positive", func(t *testing.T) {
+		opt := WithMaxBodyBytes(1024)
+		err := opt(roundTripperInstance)
+		assert.NoError(t, err)
+	})
+}
+
+func TestWithRedactPolicy(t *testing.T) {
+	t.Run("on nil", func(t *testing.T) {
+		opt := WithRedactPolicy(nil)
+		err := opt(roundTripperInstance)
+		assert.Error(t, err)
+	})
+
+	t.Run("on non-nil", func(t *testing.T) {
+		p, err := redact.New()
+		require.NoError(t, err)
+
+		opt := WithRedactPolicy(p)
+		err = opt(roundTripperInstance)
+		assert.NoError(t, err)
+	})
+}
+
 func TestNewHttpRoundTripper(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		mw := NewHttpRoundTripper()
@@ -321,6 +357,55 @@ func TestRoundTripper_RoundTrip(t *testing.T) {
 	})
 }
 
+func TestRoundTripper_RoundTrip_Redact(t *testing.T) {
+	const secret = "s3cr3t-value"
+
+	transport := newMockHTTPRoundTripper()
+	transport.CallRoundTrip = func(request *http.Request) (*http.Response, error) {
+		// the actual request still has the original value
+		assert.Equal(t, "Bearer "+secret, request.Header.Get("Authorization"))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: map[string][]string{
+				"Content-Type": {"application/json"},
+				"Set-Cookie":   {"session=" + secret},
+			},
+			Body: io.NopCloser(bytes.NewBufferString(`{"refresh_token":"` + secret + `"}`)),
+		}, nil
+	}
+
+	logBuf := &bytes.Buffer{}
+	mw := NewHttpRoundTripper(
+		WithBaseRoundTripper(transport),
+		WithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))),
+	)
+
+	reqURL, _ := url.Parse("https://localhost/login?api_key=" + secret + "&page=1")
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL:    reqURL,
+		Header: map[string][]string{
+			"Authorization": {"Bearer " + secret},
+		},
+		Body: io.NopCloser(bytes.NewBufferString(`{"username":"john","cardNumber":"` + secret + `"}`)),
+	}
+
+	resp, err := mw.RoundTrip(req)
+	require.NoError(t, err)
+
+	// the actual response is not changed
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(respBody), secret)
+
+	out := logBuf.String()
+	assert.NotContains(t, out, secret)
+	assert.Contains(t, out, redact.Mask)
+	assert.Contains(t, out, `"username":"john"`)
+	assert.Contains(t, out, `"page":["1"]`)
+}
+
 type mockHTTPRoundTrip struct {
 	Error         error
 	CallRoundTrip func(request *http.Request) (*http.Response, error)
@@ -390,3 +475,139 @@ func (c *closer) Read(p []byte) (n int, err error) {
 func (c *closer) Close() error {
 	return c.err
 }
+
+func TestRoundTripper_RoundTrip_BodyCapture(t *testing.T) {
+	reqBody := strings.Repeat("a", 100)
+	respBody := strings.Repeat("b", 200)
+
+	transport := newMockHTTPRoundTripper()
+	transport.CallRoundTrip = func(request *http.Request) (*http.Response, error) {
+		// the base transport still sends the complete body
+		b, err := io.ReadAll(request.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, reqBody, string(b))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: map[string][]string{
+				"Content-Type": {"text/plain"},
+			},
+			Body: io.NopCloser(bytes.NewBufferString(respBody)),
+		}, nil
+	}
+
+	logBuf := &bytes.Buffer{}
+	mw := NewHttpRoundTripper(
+		WithBaseRoundTripper(transport),
+		WithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))),
+		WithMaxBodyBytes(10),
+	)
+
+	reqURL, _ := url.Parse("https://localhost/upload")
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL:    reqURL,
+		Header: map[string][]string{
+			"Content-Type": {"text/plain"},
+		},
+		Body: io.NopCloser(bytes.NewBufferString(reqBody)),
+	}
+
+	resp, err := mw.RoundTrip(req)
+	require.NoError(t, err)
+
+	// the caller still reads the complete body
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, respBody, string(b))
+
+	out := logBuf.String()
+	assert.Contains(t, out, `"body":"aaaaaaaaaa...[truncated]"`) // total length is unknown without Content-Length
+	assert.Contains(t, out, `"body":"bbbbbbbbbb...[truncated]"`)
+	assert.Contains(t, out, `"bodyTruncated":true`)
+	assert.Contains(t, out, `"bodyLen":-1`)
+	assert.NotContains(t, out, reqBody)
+	assert.NotContains(t, out, respBody)
+}
+
+func TestRoundTripper_RoundTrip_BodySummary(t *testing.T) {
+	transport := newMockHTTPRoundTripper()
+	transport.CallRoundTrip = func(request *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header: map[string][]string{
+				"Content-Type":     {"application/json"},
+				"Content-Encoding": {"gzip"},
+			},
+			ContentLength: 4,
+			Body:          io.NopCloser(bytes.NewReader([]byte{0x1f, 0x8b, 0x08, 0x00})),
+		}, nil
+	}
+
+	logBuf := &bytes.Buffer{}
+	mw := NewHttpRoundTripper(
+		WithBaseRoundTripper(transport),
+		WithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))),
+	)
+
+	reqURL, _ := url.Parse("https://localhost/download")
+	req := &http.Request{
+		Method:        http.MethodPost,
+		URL:           reqURL,
+		Header:        map[string][]string{"Content-Type": {"application/octet-stream"}},
+		ContentLength: 3,
+		Body:          io.NopCloser(bytes.NewReader([]byte{0x00, 0x01, 0x02})),
+	}
+
+	resp, err := mw.RoundTrip(req)
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x1f, 0x8b, 0x08, 0x00}, b)
+
+	out := logBuf.String()
+	assert.Contains(t, out, `"body":"[binary body: application/octet-stream, 3 bytes]"`)
+	assert.Contains(t, out, `"body":"[encoded body: gzip, 4 bytes]"`)
+}
+
+func TestRoundTripper_RoundTrip_RequestID(t *testing.T) {
+	var forwardedID string
+
+	transport := newMockHTTPRoundTripper()
+	transport.CallRoundTrip = func(request *http.Request) (*http.Response, error) {
+		forwardedID = request.Header.Get(requestid.Header)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}
+
+	mw := NewHttpRoundTripper(WithBaseRoundTripper(transport))
+
+	t.Run("forward from context", func(t *testing.T) {
+		reqURL, _ := url.Parse("https://localhost/users")
+		req := (&http.Request{Method: http.MethodGet, URL: reqURL}).
+			WithContext(requestid.NewContext(context.Background(), "req-123"))
+
+		_, err := mw.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, "req-123", forwardedID)
+		assert.Empty(t, req.Header.Get(requestid.Header)) // caller's request is not modified
+	})
+
+	t.Run("keep explicit header", func(t *testing.T) {
+		reqURL, _ := url.Parse("https://localhost/users")
+		req := (&http.Request{Method: http.MethodGet, URL: reqURL, Header: http.Header{}}).
+			WithContext(requestid.NewContext(context.Background(), "req-123"))
+		req.Header.Set(requestid.Header, "explicit")
+
+		_, err := mw.RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, "explicit", forwardedID)
+	})
+
+	t.Run("without request id", func(t *testing.T) {
+		reqURL, _ := url.Parse("https://localhost/users")
+		_, err := mw.RoundTrip(&http.Request{Method: http.MethodGet, URL: reqURL})
+		require.NoError(t, err)
+		assert.Empty(t, forwardedID)
+	})
+}
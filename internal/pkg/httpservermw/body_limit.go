@@ -0,0 +1,119 @@
+package httpservermw
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
+)
+
+// RejectReasonBodyTooLarge and RejectReasonTooManyInFlight are the "reason" label of http_rejected_requests_total.
+const (
+	RejectReasonBodyTooLarge    = "body_too_large"
+	RejectReasonTooManyInFlight = "too_many_in_flight"
+)
+
+type BodyLimitOpt func(*bodyLimitMiddleware) error
+
+// BodyLimitWithMaxBytes set the maximum bytes of request body. Default to 10 MiB, zero means unlimited.
+func BodyLimitWithMaxBytes(n int64) BodyLimitOpt {
+	return func(m *bodyLimitMiddleware) error {
+		if n < 0 {
+			return fmt.Errorf("body limit cannot be negative")
+		}
+
+		m.maxBytes = n
+		return nil
+	}
+}
+
+// BodyLimitWithMetric set metrics.Metric to count the rejected request as http_rejected_requests_total.
+func BodyLimitWithMetric(m metrics.Metric) BodyLimitOpt {
+	return func(b *bodyLimitMiddleware) error {
+		b.metric = m
+		return nil
+	}
+}
+
+type bodyLimitMiddleware struct {
+	maxBytes int64
+	metric   metrics.Metric
+}
+
+// BodyLimitMiddleware limits the request body size.
+// The request with Content-Length larger than the limit is rejected immediately with 413 respbuilder.Error JSON body.
+// Otherwise (i.e. chunked request), the body is wrapped with http.MaxBytesReader, so reading beyond the limit returns
+// *http.MaxBytesError to the handler, which should respond 413 (see restapi HTTP error handler).
+func BodyLimitMiddleware(next http.Handler, opts ...BodyLimitOpt) http.Handler {
+	m := &bodyLimitMiddleware{
+		maxBytes: 10 << 20,
+	}
+
+	for _, opt := range opts {
+		err := opt(m)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if m.maxBytes <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req == nil || req.Body == nil || req.Body == http.NoBody {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		if req.ContentLength > m.maxBytes {
+			m.reject(req)
+
+			// Tell the client to stop sending the rest of the body, net/http closes the connection after the response.
+			w.Header().Set("Connection", "close")
+			writeError(req.Context(), w, http.StatusRequestEntityTooLarge, respbuilder.ErrRequestTooLarge,
+				fmt.Errorf("request body cannot be larger than %d bytes", m.maxBytes),
+			)
+			return
+		}
+
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(w, req.Body, m.maxBytes)}
+		req.Body = body
+		next.ServeHTTP(w, req)
+
+		if body.exceeded.Load() {
+			m.reject(req)
+		}
+	})
+}
+
+func (m *bodyLimitMiddleware) reject(req *http.Request) {
+	if m.metric == nil {
+		return
+	}
+
+	m.metric.GetCounterVec("http_rejected_requests_total", "method", "path", "reason").
+		WithValues(req.Method, RouteFromRequest(req), RejectReasonBodyTooLarge).
+		Incr(1)
+}
+
+// limitedBody records whether the handler reads beyond the limit of http.MaxBytesReader.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded atomic.Bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var errMaxBytes *http.MaxBytesError
+	if errors.As(err, &errMaxBytes) {
+		b.exceeded.Store(true)
+	}
+
+	return n, err
+}
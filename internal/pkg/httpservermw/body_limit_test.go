@@ -0,0 +1,75 @@
+package httpservermw_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	promMetric, err := metrics.NewPrometheus()
+	require.NoError(t, err)
+
+	var readErr error
+	handler := httpservermw.BodyLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+		if readErr != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}),
+		httpservermw.BodyLimitWithMaxBytes(10),
+		httpservermw.BodyLimitWithMetric(promMetric),
+	)
+
+	t.Run("within limit", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("0123456789")))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.NoError(t, readErr)
+	})
+
+	t.Run("content length exceeds limit", func(t *testing.T) {
+		readErr = nil
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("0123456789a")))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+		assert.Contains(t, resp.Body.String(), `"code":"E2"`)
+		assert.NoError(t, readErr, "handler must not be called")
+	})
+
+	t.Run("chunked body exceeds limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("0123456789abcdef"))
+		req.ContentLength = -1
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+
+		var errMaxBytes *http.MaxBytesError
+		assert.ErrorAs(t, readErr, &errMaxBytes)
+	})
+
+	out := scrapeMetrics(t, promMetric.HandlerFunc())
+	assert.Contains(t, out, `http_rejected_requests_total{method="POST",path="/upload",reason="body_too_large"} 2`)
+}
+
+func TestBodyLimitMiddleware_Unlimited(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := httpservermw.BodyLimitMiddleware(next, httpservermw.BodyLimitWithMaxBytes(0))
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("a", 11<<20))))
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
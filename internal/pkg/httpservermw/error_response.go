@@ -0,0 +1,19 @@
+package httpservermw
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
+)
+
+// writeError responds with respbuilder.Error JSON body, the same structure as the error returned by the handlers.
+func writeError(ctx context.Context, w http.ResponseWriter, httpStatus int, respCode respbuilder.RespCodeErr, err error) {
+	b, _ := json.Marshal(respbuilder.ErrorCtx(ctx, respCode, err))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(httpStatus)
+	_, _ = w.Write(b)
+}
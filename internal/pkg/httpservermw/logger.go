@@ -2,7 +2,6 @@ package httpservermw
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -10,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpbody"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/redact"
 	"go.opentelemetry.io/otel/propagation"
 	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
 	"go.opentelemetry.io/otel/trace"
@@ -83,11 +84,139 @@ func LogMwWithFilter(f Filter) LoggerOpt {
 	}
 }
 
+// LogMwWithRedactPolicy set the policy to mask sensitive header, query parameter and body before it is logged.
+// Default to redact.Default policy. Use empty policy from redact.New() to log everything as is.
+func LogMwWithRedactPolicy(p *redact.Policy) LoggerOpt {
+	return func(tripper *LogMiddleware) error {
+		if p == nil {
+			return fmt.Errorf("cannot use nil redact policy")
+		}
+
+		tripper.redactPolicy = p
+		return nil
+	}
+}
+
+// LogMwWithMaxBodyBytes set the maximum bytes of request and response body to be logged.
+// The body is truncated with marker, while the true total length is still logged. Default to httpbody.DefaultMaxBytes.
+// Zero means the body is never logged, only its length.
+func LogMwWithMaxBodyBytes(n int) LoggerOpt {
+	return func(tripper *LogMiddleware) error {
+		if n < 0 {
+			return fmt.Errorf("max body bytes cannot be negative")
+		}
+
+		tripper.maxBodyBytes = n
+		return nil
+	}
+}
+
+// LogMwWithPolicy set the default LogPolicy for all routes. Default to LogPolicyFull.
+func LogMwWithPolicy(policy LogPolicy) LoggerOpt {
+	return func(tripper *LogMiddleware) error {
+		policy, err := ParseLogPolicy(string(policy))
+		if err != nil {
+			return err
+		}
+
+		tripper.policy = policy
+		return nil
+	}
+}
+
+// LogMwWithRoutePolicy set the LogPolicy for the route template (i.e. /users/:id) resolved by RouteMiddleware,
+// overriding the default policy.
+func LogMwWithRoutePolicy(route string, policy LogPolicy) LoggerOpt {
+	return func(tripper *LogMiddleware) error {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			return fmt.Errorf("route of log policy cannot be empty")
+		}
+
+		policy, err := ParseLogPolicy(string(policy))
+		if err != nil {
+			return fmt.Errorf("route %s: %w", route, err)
+		}
+
+		if tripper.routePolicies == nil {
+			tripper.routePolicies = map[string]LogPolicy{}
+		}
+
+		tripper.routePolicies[route] = policy
+		return nil
+	}
+}
+
+// LogMwWithSampleRate set the probability (0 to 1) of request to be logged. Default to 1, meaning all requests are logged.
+// The 5xx and slow requests (see LogMwWithSlowThreshold) are always logged regardless of the sampling.
+// Use 0 to only log the 5xx and slow requests.
+func LogMwWithSampleRate(rate float64) LoggerOpt {
+	return func(tripper *LogMiddleware) error {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("log sample rate must be between 0 and 1, got %v", rate)
+		}
+
+		tripper.sampler.rate = rate
+		return nil
+	}
+}
+
+// LogMwWithRateLimit set the maximum number of sampled requests logged per second. Default to 0, meaning unlimited.
+// The 5xx and slow requests are always logged and not counted.
+// When set, the request log is written after the request is served, because the budget depends on the response.
+func LogMwWithRateLimit(perSecond int) LoggerOpt {
+	return func(tripper *LogMiddleware) error {
+		if perSecond < 0 {
+			return fmt.Errorf("log rate limit cannot be negative")
+		}
+
+		tripper.sampler.perSecond = perSecond
+		return nil
+	}
+}
+
+// LogMwWithSlowThreshold set the duration of slow request which is always logged regardless of the sampling.
+// Default to 0, meaning disabled.
+func LogMwWithSlowThreshold(d time.Duration) LoggerOpt {
+	return func(tripper *LogMiddleware) error {
+		if d < 0 {
+			return fmt.Errorf("log slow threshold cannot be negative")
+		}
+
+		tripper.slowThreshold = d
+		return nil
+	}
+}
+
 type LogMiddleware struct {
 	logger           *slog.Logger
 	tracerProvider   trace.TracerProvider
 	spanStartOptions []trace.SpanStartOption
 	filter           Filter
+	redactPolicy     *redact.Policy
+	maxBodyBytes     int
+	policy           LogPolicy
+	routePolicies    map[string]LogPolicy
+	sampler          *logSampler
+	slowThreshold    time.Duration
+}
+
+// routePolicy returns the LogPolicy of the route, fallback to the default policy.
+func (l *LogMiddleware) routePolicy(route string) LogPolicy {
+	if policy, ok := l.routePolicies[route]; ok {
+		return policy
+	}
+
+	return l.policy
+}
+
+// alwaysLog returns true for the request which is logged regardless of the sampling: 5xx or slow request.
+func (l *LogMiddleware) alwaysLog(statusCode int, elapsed time.Duration) bool {
+	if statusCode >= http.StatusInternalServerError {
+		return true
+	}
+
+	return l.slowThreshold > 0 && elapsed >= l.slowThreshold
 }
 
 // LoggingMiddleware is a middleware that logs incoming requests
@@ -96,6 +225,12 @@ func LoggingMiddleware(next http.Handler, opts ...LoggerOpt) http.Handler {
 		logger:           slog.Default(),
 		tracerProvider:   noop.NewTracerProvider(),
 		spanStartOptions: make([]trace.SpanStartOption, 0),
+		redactPolicy:     nil,
+		maxBodyBytes:     httpbody.DefaultMaxBytes,
+		policy:           LogPolicyFull,
+		routePolicies:    map[string]LogPolicy{},
+		sampler:          &logSampler{rate: 1},
+		slowThreshold:    0,
 	}
 
 	for _, opt := range opts {
@@ -105,6 +240,15 @@ func LoggingMiddleware(next http.Handler, opts ...LoggerOpt) http.Handler {
 		}
 	}
 
+	if l.redactPolicy == nil {
+		defaultPolicy, err := redact.Default()
+		if err != nil {
+			panic(err)
+		}
+
+		l.redactPolicy = defaultPolicy
+	}
+
 	tracer := l.tracerProvider.Tracer(instrumentationName)
 	l.spanStartOptions = append(l.spanStartOptions, trace.WithSpanKind(trace.SpanKindServer))
 
@@ -126,6 +270,14 @@ func LoggingMiddleware(next http.Handler, opts ...LoggerOpt) http.Handler {
 			return
 		}
 
+		// use route template to keep the span name low-cardinality, and to find the log policy
+		route := RouteFromRequest(req)
+		policy := l.routePolicy(route)
+		if policy == LogPolicyOff {
+			next.ServeHTTP(w, req)
+			return
+		}
+
 		if req.Context() != nil {
 			parentCtx = req.Context()
 
@@ -142,11 +294,11 @@ func LoggingMiddleware(next http.Handler, opts ...LoggerOpt) http.Handler {
 		}
 
 		// parent span for this logging middleware
-		spanName := fmt.Sprintf("%s %s [Log MW]", req.Method, reqURL.EscapedPath())
+		spanName := fmt.Sprintf("%s %s [Log MW]", req.Method, route)
 
 		var parentSpan trace.Span
 		parentCtx, parentSpan = tracer.Start(parentCtx, spanName, l.spanStartOptions...)
-		parentSpan.SetAttributes(semconv.URLFull(reqURL.String()))
+		parentSpan.SetAttributes(semconv.URLFull(l.redactPolicy.URL(reqURL)))
 		defer parentSpan.End()
 
 		// create child span for this request and pass it to the actual handler span.
@@ -156,14 +308,26 @@ func LoggingMiddleware(next http.Handler, opts ...LoggerOpt) http.Handler {
 
 		// append to map only when the http.Request is not nil
 		reqCtx, reqSpan = tracer.Start(reqCtx, "Capture request")
-		captReqCtx := captureRequest(reqCtx, &captureRequestOpt{
+		captReqCtx, requestLog := captureRequest(reqCtx, &captureRequestOpt{
 			T0:               t0,
 			Request:          req,
+			Route:            route,
 			Tracer:           tracer,
 			SpanStartOptions: l.spanStartOptions,
-			Logger:           l.logger,
+			RedactPolicy:     l.redactPolicy,
+			MaxBodyBytes:     l.maxBodyBytes,
+			Policy:           policy,
 		})
 
+		// The request which is not sampled is held, and only logged when the response turns out to be 5xx or slow.
+		// With the rate limit, the sampled request is also held, because the budget is only counted after the
+		// response is known to not be 5xx or slow.
+		sampled := l.sampler.Sample()
+		loggedBeforeServe := sampled && !l.sampler.Limited()
+		if loggedBeforeServe {
+			l.logger.InfoContext(captReqCtx, reqLogMsg, slog.Any("request", requestLog))
+		}
+
 		// ending the capture request span right before we do actual ServeHTTP.
 		// meaning that *this* span only to capture how many times needed to get the request body
 		reqCtx.Done()
@@ -180,7 +344,12 @@ func LoggingMiddleware(next http.Handler, opts ...LoggerOpt) http.Handler {
 		}()
 
 		// Pass the request to the next handler
-		respRec := newResponseWriter(w)
+		maxRespBodyBytes := 0 // only count the response body length
+		if policy == LogPolicyFull {
+			maxRespBodyBytes = l.maxBodyBytes
+		}
+
+		respRec := newResponseWriter(w, maxRespBodyBytes)
 
 		// inject Traceparent to response recorder header,
 		// next it will write to actual writer response header
@@ -189,35 +358,41 @@ func LoggingMiddleware(next http.Handler, opts ...LoggerOpt) http.Handler {
 		// use the child request span context, so the handler will continue the child span for this request context
 		next.ServeHTTP(respRec, req.WithContext(reqCtx))
 
-		respBodyLen := int64(len(respRec.body))
+		elapsed := time.Since(t0)
+		if !loggedBeforeServe {
+			if !l.alwaysLog(respRec.statusCode, elapsed) && (!sampled || !l.sampler.Allow()) {
+				return
+			}
+
+			l.logger.InfoContext(captReqCtx, reqLogMsg, slog.Any("request", requestLog))
+		}
 
 		// Log or process the captured status code, headers, and body
 		respLog := AccessLog{
 			Method:      req.Method,
 			Host:        req.Host,
 			Path:        reqURL.Path,
+			Route:       route,
 			StatusCode:  respRec.statusCode,
-			Header:      HttpHeaderToSimpleMap(respRec.headers),
-			Body:        nil,
-			BodyLen:     respBodyLen,
+			BodyLen:     respRec.body.Len(),
 			QueryParams: nil,
 			Error:       "",
-			ElapsedTime: time.Since(t0).Milliseconds(),
+			ElapsedTime: elapsed.Milliseconds(),
 		}
 
-		var respBodyCaptured any
-		var respBodyDecoderErr error
-		if respBodyLen > 0 {
-			respBodyDecoderErr = json.Unmarshal(respRec.body, &respBodyCaptured)
+		if policy != LogPolicyMetadata {
+			respLog.Header = HttpHeaderToSimpleMap(l.redactPolicy.Header(respRec.headers))
 		}
 
-		if respBodyDecoderErr != nil {
-			respBodyCaptured = string(respRec.body) // Fallback with the real request body string
-			respLog.Error = fmt.Sprintf("error unmarshal response body: %s", respBodyDecoderErr.Error())
+		if policy == LogPolicyFull {
+			respBody := httpbody.Capture(respRec.headers, respRec.body, respRec.body.Len())
+			respLog.Body = l.redactPolicy.Body(respBody.Value)
+			respLog.BodyTruncated = respBody.Truncated
+			if respBody.Err != nil {
+				respLog.Error = fmt.Sprintf("response body: %s", respBody.Err.Error())
+			}
 		}
 
-		respLog.Body = respBodyCaptured
-
 		l.logger.InfoContext(respCtx, "capture incoming response payload", slog.Any("response", respLog))
 	}
 
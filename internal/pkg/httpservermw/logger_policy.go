@@ -0,0 +1,86 @@
+package httpservermw
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogPolicy is how much of the request and response is logged by LoggingMiddleware.
+type LogPolicy string
+
+const (
+	// LogPolicyFull logs the headers, query parameters and bodies. This is the default policy.
+	LogPolicyFull LogPolicy = "full"
+
+	// LogPolicyHeaders logs the headers and query parameters, the body is not read and only its length is logged.
+	LogPolicyHeaders LogPolicy = "headers"
+
+	// LogPolicyMetadata only logs method, path, route, status code, body length and elapsed time.
+	LogPolicyMetadata LogPolicy = "metadata"
+
+	// LogPolicyOff skips the logging and tracing of LoggingMiddleware, the same as returning false in Filter.
+	LogPolicyOff LogPolicy = "off"
+)
+
+// ParseLogPolicy parses case-insensitive policy name: full, headers, metadata or off.
+func ParseLogPolicy(s string) (LogPolicy, error) {
+	policy := LogPolicy(strings.ToLower(strings.TrimSpace(s)))
+	switch policy {
+	case LogPolicyFull, LogPolicyHeaders, LogPolicyMetadata, LogPolicyOff:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown log policy '%s'", s)
+	}
+}
+
+// logSampler decides whether the request is logged.
+// The sampling rate is decided before the request is served, but the rate limit is only counted in Allow
+// after the response is known, so the 5xx and slow requests which are always logged don't use the budget.
+type logSampler struct {
+	rate      float64 // probability of request to be logged, 1 means always
+	perSecond int     // maximum number of sampled request per second, 0 means unlimited
+
+	lock        sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+// Sample returns true if the request is picked by the sampling rate.
+func (s *logSampler) Sample() bool {
+	if s.rate <= 0 {
+		return false
+	}
+
+	return s.rate >= 1 || rand.Float64() < s.rate
+}
+
+// Limited returns true if the sampled request must also pass Allow after it is served.
+func (s *logSampler) Limited() bool {
+	return s.perSecond > 0
+}
+
+// Allow takes one from the per second budget, it returns false when the budget is exhausted.
+func (s *logSampler) Allow() bool {
+	if s.perSecond <= 0 {
+		return true
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.windowStart) >= time.Second {
+		s.windowStart = now
+		s.count = 0
+	}
+
+	if s.count >= s.perSecond {
+		return false
+	}
+
+	s.count++
+	return true
+}
@@ -1,30 +1,34 @@
 package httpservermw
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
 
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpbody"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/redact"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type captureRequestOpt struct {
 	T0               time.Time
 	Request          *http.Request
+	Route            string
 	Tracer           trace.Tracer
 	SpanStartOptions []trace.SpanStartOption
-	Logger           *slog.Logger
+	RedactPolicy     *redact.Policy
+	MaxBodyBytes     int
+	Policy           LogPolicy
 }
 
-func captureRequest(parentCtx context.Context, opt *captureRequestOpt) context.Context {
-	const reqLogMsg = "capture incoming request payload"
+const reqLogMsg = "capture incoming request payload"
+
+// captureRequest returns the request log, the caller decides whether it is logged or not.
+// The returned context must be used when logging it, so the log is attached to the capture request span.
+func captureRequest(parentCtx context.Context, opt *captureRequestOpt) (context.Context, AccessLog) {
 	captReqCtx, captReqSpan := opt.Tracer.Start(parentCtx, reqLogMsg, opt.SpanStartOptions...)
 	defer captReqSpan.End()
 
@@ -33,78 +37,61 @@ func captureRequest(parentCtx context.Context, opt *captureRequestOpt) context.C
 	// Get common info regarding the source of request
 	// ensure request log variable only scoped here
 	var (
-		reqHeader   = HttpHeaderToSimpleMap(req.Header)
-		queryParams url.Values
-		reqBodyBuf  = &bytes.Buffer{}
-		reqBodyLen  int64
-		errCum      error
+		reqHeader map[string]string
+		reqBody   = httpbody.Body{Len: req.ContentLength}
+		errCum    error
 	)
 
-	if req.Body != nil {
-		if reqBodyCopied, _err := io.Copy(reqBodyBuf, req.Body); _err != nil {
-			errCum = errors.Join(errCum, fmt.Errorf("error copy request body: %w", _err))
-			reqBodyBuf = &bytes.Buffer{}
-		} else {
-			reqBodyLen = reqBodyCopied
-		}
-
-		// Don't forget to close the body buffer.
-		if _err := req.Body.Close(); _err != nil {
-			errCum = errors.Join(errCum, fmt.Errorf("error closing request body: %w", _err))
-		}
-
-		req.Body = io.NopCloser(reqBodyBuf)
-	}
-
-	var reqBodyCaptured any
-	var reqBodyDecoderErr error
-	if len(reqBodyBuf.Bytes()) > 0 {
-		reqBodyDecoderErr = json.Unmarshal(reqBodyBuf.Bytes(), &reqBodyCaptured)
+	if opt.Policy != LogPolicyMetadata {
+		reqHeader = HttpHeaderToSimpleMap(opt.RedactPolicy.Header(req.Header))
 	}
 
-	if reqBodyDecoderErr != nil {
-		errCum = errors.Join(errCum, fmt.Errorf("error unmarshal request body: %w", reqBodyDecoderErr))
-
-		reqBodyLen = int64(reqBodyBuf.Len())
-		reqBodyCaptured = reqBodyBuf.String() // Fallback with the real request body string
-	}
+	// The body is not read at all unless the policy is full.
+	if req.Body != nil && opt.Policy == LogPolicyFull {
+		// Only the first MaxBodyBytes is read, the handler still reads the complete body from the replay reader.
+		var _err error
+		reqBody, req.Body, _err = httpbody.CaptureReader(req.Header, req.Body, req.ContentLength, opt.MaxBodyBytes)
+		if _err != nil {
+			errCum = errors.Join(errCum, fmt.Errorf("error read request body: %w", _err))
+		}
 
-	if _err := req.ParseForm(); _err != nil {
-		errCum = errors.Join(errCum, fmt.Errorf("error parse request form: %w", _err))
+		if reqBody.Err != nil {
+			errCum = errors.Join(errCum, fmt.Errorf("request body: %w", reqBody.Err))
+		}
 	}
 
-	// Cloned the request, for safety reason (i.e. parse form should not affect the original request)
-	reqCloned := req.Clone(captReqCtx)
-	reqCloned.Form = req.Form
-	reqCloned.Body = io.NopCloser(reqBodyBuf)
-
-	queryParams = reqCloned.Form
-
 	reqURL := req.URL
 	if reqURL == nil {
 		reqURL = &url.URL{}
 	}
 
+	// Only parse the URL query, the form body is already captured as the body.
+	// Calling req.ParseForm here will read the whole body into memory before the handler does.
+	var queryParams url.Values
+	if opt.Policy != LogPolicyMetadata {
+		queryParams = opt.RedactPolicy.Query(reqURL.Query())
+	}
+
 	requestLatency := time.Since(opt.T0).Milliseconds()
 
 	requestLog := AccessLog{
-		Method:      opt.Request.Method,
-		Host:        req.Host,
-		Path:        reqURL.Path,
-		StatusCode:  0,
-		Header:      reqHeader,
-		Body:        reqBodyCaptured,
-		BodyLen:     reqBodyLen,
-		QueryParams: queryParams,
-		Error:       "",
-		ElapsedTime: requestLatency,
+		Method:        opt.Request.Method,
+		Host:          req.Host,
+		Path:          reqURL.Path,
+		Route:         opt.Route,
+		StatusCode:    0,
+		Header:        reqHeader,
+		Body:          opt.RedactPolicy.Body(reqBody.Value),
+		BodyLen:       reqBody.Len,
+		BodyTruncated: reqBody.Truncated,
+		QueryParams:   queryParams,
+		Error:         "",
+		ElapsedTime:   requestLatency,
 	}
 
 	if errCum != nil {
 		requestLog.Error = errCum.Error()
 	}
 
-	opt.Logger.InfoContext(captReqCtx, reqLogMsg, slog.Any("request", requestLog))
-
-	return captReqCtx
+	return captReqCtx, requestLog
 }
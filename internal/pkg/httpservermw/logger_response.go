@@ -2,37 +2,63 @@ package httpservermw
 
 import (
 	"net/http"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpbody"
 )
 
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
-	body       []byte
-	headers    http.Header
+	statusCode  int
+	body        *httpbody.Buffer
+	headers     http.Header
+	wroteHeader bool
 }
 
 var _ http.ResponseWriter = (*responseWriter)(nil)
 
-func newResponseWriter(w http.ResponseWriter) *responseWriter {
+// newResponseWriter captures at most maxBodyBytes of response body, while counting all bytes written.
+func newResponseWriter(w http.ResponseWriter, maxBodyBytes int) *responseWriter {
 	return &responseWriter{
 		ResponseWriter: w,
 		statusCode:     http.StatusOK, // Default status code
-		body:           make([]byte, 0),
+		body:           httpbody.NewBuffer(maxBodyBytes),
 		headers:        http.Header{},
 	}
 }
 
 func (rw *responseWriter) WriteHeader(statusCode int) {
-	rw.statusCode = statusCode
-	rw.headers = rw.ResponseWriter.Header().Clone()
+	if !rw.wroteHeader && statusCode >= http.StatusOK {
+		rw.statusCode = statusCode
+		rw.headers = rw.ResponseWriter.Header().Clone()
+		rw.wroteHeader = true
+	}
+
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Write accumulates the written bytes (up to max) across multiple calls.
 func (rw *responseWriter) Write(b []byte) (int, error) {
-	rw.body = b
-	return rw.ResponseWriter.Write(b)
+	if !rw.wroteHeader {
+		// Write without WriteHeader means 200 OK, and the header is sent at this point.
+		rw.headers = rw.ResponseWriter.Header().Clone()
+		rw.wroteHeader = true
+	}
+
+	n, err := rw.ResponseWriter.Write(b)
+	_, _ = rw.body.Write(b[:n])
+	return n, err
 }
 
 func (rw *responseWriter) Header() http.Header {
 	return rw.ResponseWriter.Header()
 }
+
+// Flush delegates to the underlying writer, so streaming response is not blocked by this logger middleware.
+func (rw *responseWriter) Flush() {
+	_ = http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Unwrap is used by http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -2,17 +2,22 @@ package httpservermw_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/redact"
 )
 
 var logMwTest = &httpservermw.LogMiddleware{}
@@ -53,6 +58,71 @@ func TestLogMwWithFilter(t *testing.T) {
 	})
 }
 
+func TestLogMwWithRedactPolicy(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		opt := httpservermw.LogMwWithRedactPolicy(nil)
+		err := opt(logMwTest)
+		assert.Error(t, err)
+	})
+
+	t.Run("non-nil", func(t *testing.T) {
+		p, err := redact.New()
+		require.NoError(t, err)
+
+		opt := httpservermw.LogMwWithRedactPolicy(p)
+		err = opt(logMwTest)
+		assert.NoError(t, err)
+	})
+}
+
+func TestLogMwWithMaxBodyBytes(t *testing.T) {
+	t.Run("negative", func(t *testing.T) {
+		err := httpservermw.LogMwWithMaxBodyBytes(-1)(logMwTest)
+		assert.Error(t, err)
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		err := httpservermw.LogMwWithMaxBodyBytes(0)(logMwTest)
+		assert.NoError(t, err)
+	})
+}
+
+func TestLogMwWithPolicy(t *testing.T) {
+	t.Run("unknown", func(t *testing.T) {
+		err := httpservermw.LogMwWithPolicy("verbose")(logMwTest)
+		assert.Error(t, err)
+	})
+
+	t.Run("case insensitive", func(t *testing.T) {
+		err := httpservermw.LogMwWithPolicy("HEADERS")(logMwTest)
+		assert.NoError(t, err)
+	})
+}
+
+func TestLogMwWithRoutePolicy(t *testing.T) {
+	t.Run("empty route", func(t *testing.T) {
+		err := httpservermw.LogMwWithRoutePolicy(" ", httpservermw.LogPolicyOff)(logMwTest)
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown policy", func(t *testing.T) {
+		err := httpservermw.LogMwWithRoutePolicy("/ping", "verbose")(logMwTest)
+		assert.Error(t, err)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		err := httpservermw.LogMwWithRoutePolicy("/ping", httpservermw.LogPolicyOff)(&httpservermw.LogMiddleware{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestLogMwWithSampling(t *testing.T) {
+	assert.Error(t, httpservermw.LogMwWithSampleRate(-0.1)(logMwTest))
+	assert.Error(t, httpservermw.LogMwWithSampleRate(1.1)(logMwTest))
+	assert.Error(t, httpservermw.LogMwWithRateLimit(-1)(logMwTest))
+	assert.Error(t, httpservermw.LogMwWithSlowThreshold(-time.Second)(logMwTest))
+}
+
 func TestLoggingMiddleware(t *testing.T) {
 	handlerMock := &mockHandler{
 		responseCode: http.StatusOK,
@@ -172,3 +242,311 @@ func TestLoggingMiddleware(t *testing.T) {
 		assert.Equal(t, handlerMock.responseHeader, httpservermw.HttpHeaderToSimpleMap(resp.Header()))
 	})
 }
+
+func TestLoggingMiddleware_Redact(t *testing.T) {
+	const secret = "s3cr3t-value"
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the actual handler still receives the original value
+		assert.Equal(t, "Bearer "+secret, r.Header.Get("Authorization"))
+
+		b, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), secret)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session="+secret)
+		_, _ = w.Write([]byte(`{"access_token":"` + secret + `","user":{"pin":"` + secret + `"}}`))
+	})
+
+	policy, err := redact.Default(
+		redact.WithBodyPaths("user.pin"),
+		redact.WithPatterns(`\b\d{16}\b`),
+	)
+	require.NoError(t, err)
+
+	logBuf := &bytes.Buffer{}
+	mw := httpservermw.LoggingMiddleware(handler,
+		httpservermw.LogMwWithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))),
+		httpservermw.LogMwWithRedactPolicy(policy),
+	)
+
+	reqBody := `{"username":"john","password":"` + secret + `","note":"card 4111111111111111"}`
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/login?token="+secret+"&page=1", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+secret)
+	req.Header.Set("Cookie", "session="+secret)
+
+	resp := httptest.NewRecorder()
+	mw.ServeHTTP(resp, req)
+
+	// the actual response is not changed
+	assert.Contains(t, resp.Body.String(), secret)
+	assert.Contains(t, resp.Header().Get("Set-Cookie"), secret)
+
+	out := logBuf.String()
+	assert.NotContains(t, out, secret)
+	assert.NotContains(t, out, "4111111111111111")
+	assert.Contains(t, out, redact.Mask)
+	assert.Contains(t, out, `"username":"john"`)
+	assert.Contains(t, out, `"page":["1"]`)
+}
+
+// logLines decodes each JSON log line, keyed by the "request" or "response" attribute.
+func logLines(t *testing.T, buf *bytes.Buffer) map[string]map[string]any {
+	t.Helper()
+
+	out := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+
+		for _, key := range []string{"request", "response"} {
+			if v, ok := m[key].(map[string]any); ok {
+				out[key] = v
+			}
+		}
+	}
+
+	return out
+}
+
+func TestLoggingMiddleware_BodyCapture(t *testing.T) {
+	newMw := func(handler http.Handler, maxBodyBytes int) (http.Handler, *bytes.Buffer) {
+		logBuf := &bytes.Buffer{}
+		return httpservermw.LoggingMiddleware(handler,
+			httpservermw.LogMwWithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))),
+			httpservermw.LogMwWithMaxBodyBytes(maxBodyBytes),
+		), logBuf
+	}
+
+	t.Run("multiple write is accumulated", func(t *testing.T) {
+		mw, logBuf := newMw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"foo":`))
+			_, _ = w.Write([]byte(`"bar"}`))
+		}), 1024)
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, `{"foo":"bar"}`, resp.Body.String())
+
+		logs := logLines(t, logBuf)
+		assert.Equal(t, map[string]any{"foo": "bar"}, logs["response"]["body"])
+		assert.EqualValues(t, 13, logs["response"]["bodyLen"])
+		assert.Equal(t, "application/json", logs["response"]["header"].(map[string]any)["Content-Type"])
+	})
+
+	t.Run("large body is truncated but the handler receives full body", func(t *testing.T) {
+		reqBody := strings.Repeat("a", 100)
+		respBody := strings.Repeat("b", 200)
+
+		mw, logBuf := newMw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, reqBody, string(b))
+
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(respBody))
+		}), 10)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "text/plain")
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, req)
+		assert.Equal(t, respBody, resp.Body.String())
+
+		logs := logLines(t, logBuf)
+		assert.Equal(t, "aaaaaaaaaa...[truncated, 100 bytes total]", logs["request"]["body"])
+		assert.EqualValues(t, 100, logs["request"]["bodyLen"])
+		assert.Equal(t, true, logs["request"]["bodyTruncated"])
+
+		assert.Equal(t, "bbbbbbbbbb...[truncated, 200 bytes total]", logs["response"]["body"])
+		assert.EqualValues(t, 200, logs["response"]["bodyLen"])
+		assert.Equal(t, true, logs["response"]["bodyTruncated"])
+	})
+
+	t.Run("multipart and gzip body are summarized", func(t *testing.T) {
+		reqBody := "--boundary\r\nContent-Disposition: form-data; name=\"file\"\r\n\r\nfile content\r\n--boundary--\r\n"
+
+		mw, logBuf := newMw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, reqBody, string(b))
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write([]byte{0x1f, 0x8b, 0x08, 0x00})
+		}), 1024)
+
+		req := httptest.NewRequest(http.MethodPost, "/upload", bytes.NewBufferString(reqBody))
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, req)
+
+		logs := logLines(t, logBuf)
+		assert.Equal(t, fmt.Sprintf("[multipart body: multipart/form-data; boundary=boundary, %d bytes]", len(reqBody)), logs["request"]["body"])
+		assert.Equal(t, "[encoded body: gzip, 4 bytes]", logs["response"]["body"])
+		assert.Nil(t, logs["response"]["error"])
+	})
+
+	t.Run("binary body is summarized", func(t *testing.T) {
+		mw, logBuf := newMw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+		}), 1024)
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/image.png", nil))
+
+		logs := logLines(t, logBuf)
+		assert.Equal(t, "[binary body: image/png, 4 bytes]", logs["response"]["body"])
+	})
+}
+
+func TestLoggingMiddleware_Policy(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"foo":"bar"}`, string(b))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"FOO":"BAR"}`))
+	})
+
+	serve := func(opts ...httpservermw.LoggerOpt) map[string]map[string]any {
+		logBuf := &bytes.Buffer{}
+		opts = append(opts, httpservermw.LogMwWithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))))
+		mw := httpservermw.RouteMiddleware(httpservermw.LoggingMiddleware(handler, opts...), &mockRouteMatcher{})
+
+		req := httptest.NewRequest(http.MethodPost, "/users/1?page=1", bytes.NewBufferString(`{"foo":"bar"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, req)
+		assert.Equal(t, `{"FOO":"BAR"}`, resp.Body.String())
+		return logLines(t, logBuf)
+	}
+
+	t.Run("full", func(t *testing.T) {
+		logs := serve()
+		assert.Equal(t, map[string]any{"foo": "bar"}, logs["request"]["body"])
+		assert.NotNil(t, logs["request"]["header"])
+		assert.NotNil(t, logs["request"]["queryParams"])
+		assert.Equal(t, map[string]any{"FOO": "BAR"}, logs["response"]["body"])
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		logs := serve(httpservermw.LogMwWithPolicy(httpservermw.LogPolicyHeaders))
+		assert.Nil(t, logs["request"]["body"])
+		assert.EqualValues(t, 13, logs["request"]["bodyLen"])
+		assert.NotNil(t, logs["request"]["header"])
+		assert.NotNil(t, logs["request"]["queryParams"])
+
+		assert.Nil(t, logs["response"]["body"])
+		assert.EqualValues(t, 13, logs["response"]["bodyLen"])
+		assert.NotNil(t, logs["response"]["header"])
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		logs := serve(httpservermw.LogMwWithPolicy(httpservermw.LogPolicyMetadata))
+		assert.Nil(t, logs["request"]["body"])
+		assert.Nil(t, logs["request"]["header"])
+		assert.Nil(t, logs["request"]["queryParams"])
+		assert.Equal(t, "/users/:id", logs["request"]["route"])
+
+		assert.Nil(t, logs["response"]["body"])
+		assert.Nil(t, logs["response"]["header"])
+		assert.EqualValues(t, http.StatusOK, logs["response"]["statusCode"])
+		assert.EqualValues(t, 13, logs["response"]["bodyLen"])
+	})
+
+	t.Run("route policy overrides default", func(t *testing.T) {
+		logs := serve(
+			httpservermw.LogMwWithPolicy(httpservermw.LogPolicyMetadata),
+			httpservermw.LogMwWithRoutePolicy("/users/:id", httpservermw.LogPolicyOff),
+		)
+		assert.Empty(t, logs)
+
+		logs = serve(
+			httpservermw.LogMwWithPolicy(httpservermw.LogPolicyOff),
+			httpservermw.LogMwWithRoutePolicy("/users/:id", httpservermw.LogPolicyFull),
+		)
+		assert.Equal(t, map[string]any{"foo": "bar"}, logs["request"]["body"])
+	})
+}
+
+func TestLoggingMiddleware_Sampling(t *testing.T) {
+	serve := func(mw http.Handler, path string) {
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/slow":
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+
+	t.Run("only 5xx and slow requests", func(t *testing.T) {
+		logBuf := &bytes.Buffer{}
+		mw := httpservermw.LoggingMiddleware(handler,
+			httpservermw.LogMwWithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))),
+			httpservermw.LogMwWithSampleRate(0),
+			httpservermw.LogMwWithSlowThreshold(10*time.Millisecond),
+		)
+
+		serve(mw, "/ok")
+		assert.Empty(t, logBuf.String())
+
+		serve(mw, "/error")
+		logs := logLines(t, logBuf)
+		assert.Equal(t, "/error", logs["request"]["path"])
+		assert.EqualValues(t, http.StatusInternalServerError, logs["response"]["statusCode"])
+
+		logBuf.Reset()
+		serve(mw, "/slow")
+		logs = logLines(t, logBuf)
+		assert.Equal(t, "/slow", logs["request"]["path"])
+		assert.Equal(t, "/slow", logs["response"]["path"])
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		logBuf := &bytes.Buffer{}
+		mw := httpservermw.LoggingMiddleware(handler,
+			httpservermw.LogMwWithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))),
+			httpservermw.LogMwWithRateLimit(1),
+		)
+
+		serve(mw, "/ok")
+		serve(mw, "/ok")
+		assert.Equal(t, 2, strings.Count(logBuf.String(), `"path":"/ok"`)) // request and response of the first one
+
+		serve(mw, "/error")
+		assert.Equal(t, 2, strings.Count(logBuf.String(), `"path":"/error"`))
+	})
+	t.Run("5xx and slow requests are not counted", func(t *testing.T) {
+		logBuf := &bytes.Buffer{}
+		mw := httpservermw.LoggingMiddleware(handler,
+			httpservermw.LogMwWithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))),
+			httpservermw.LogMwWithRateLimit(1),
+			httpservermw.LogMwWithSlowThreshold(10*time.Millisecond),
+		)
+
+		serve(mw, "/error")
+		serve(mw, "/slow")
+		serve(mw, "/ok")
+		serve(mw, "/ok")
+		assert.Equal(t, 2, strings.Count(logBuf.String(), `"path":"/error"`))
+		assert.Equal(t, 2, strings.Count(logBuf.String(), `"path":"/slow"`))
+		assert.Equal(t, 2, strings.Count(logBuf.String(), `"path":"/ok"`)) // the budget is still left for the first one
+	})
+}
@@ -0,0 +1,138 @@
+package httpservermw
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
+)
+
+type MaxInFlightOpt func(*maxInFlightMiddleware) error
+
+// MaxInFlightWithLimit set the maximum number of concurrent requests. Default to 1000, zero means unlimited.
+func MaxInFlightWithLimit(n int) MaxInFlightOpt {
+	return func(m *maxInFlightMiddleware) error {
+		if n < 0 {
+			return fmt.Errorf("max in-flight requests cannot be negative")
+		}
+
+		m.limit = n
+		return nil
+	}
+}
+
+// MaxInFlightWithRetryAfter set the Retry-After header of the rejected request, rounded up to seconds. Default to 1 second.
+func MaxInFlightWithRetryAfter(d time.Duration) MaxInFlightOpt {
+	return func(m *maxInFlightMiddleware) error {
+		if d <= 0 {
+			return fmt.Errorf("retry after must be positive")
+		}
+
+		m.retryAfter = strconv.Itoa(int(math.Ceil(d.Seconds())))
+		return nil
+	}
+}
+
+// MaxInFlightWithFilter set the filter of the limited request, return false to always serve the request
+// regardless of the limit, i.e. health probes, so the overloaded pod is not restarted by the liveness probe.
+func MaxInFlightWithFilter(f Filter) MaxInFlightOpt {
+	return func(m *maxInFlightMiddleware) error {
+		m.filter = f
+		return nil
+	}
+}
+
+// MaxInFlightWithMetric set metrics.Metric to count the rejected request as http_rejected_requests_total,
+// and the current number of requests as http_requests_in_flight.
+func MaxInFlightWithMetric(m metrics.Metric) MaxInFlightOpt {
+	return func(i *maxInFlightMiddleware) error {
+		i.metric = m
+		return nil
+	}
+}
+
+type maxInFlightMiddleware struct {
+	limit      int
+	retryAfter string
+	filter     Filter
+	metric     metrics.Metric
+
+	// semaphore holds one token for each in-flight request.
+	semaphore chan struct{}
+}
+
+// MaxInFlightMiddleware limits the number of concurrent requests to protect the server from overload.
+// When the limit is reached, the request is rejected immediately (without queueing) with 503 respbuilder.Error JSON body
+// and Retry-After header, so the client or load balancer can retry to the other instance.
+func MaxInFlightMiddleware(next http.Handler, opts ...MaxInFlightOpt) http.Handler {
+	m := &maxInFlightMiddleware{
+		limit:      1000,
+		retryAfter: "1",
+	}
+
+	for _, opt := range opts {
+		err := opt(m)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if m.limit <= 0 {
+		return next
+	}
+
+	m.semaphore = make(chan struct{}, m.limit)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req == nil || (m.filter != nil && !m.filter(req)) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		select {
+		case m.semaphore <- struct{}{}:
+		default:
+			m.reject(w, req)
+			return
+		}
+
+		m.gauge(1)
+		defer func() {
+			<-m.semaphore
+			m.gauge(-1)
+		}()
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (m *maxInFlightMiddleware) reject(w http.ResponseWriter, req *http.Request) {
+	if m.metric != nil {
+		m.metric.GetCounterVec("http_rejected_requests_total", "method", "path", "reason").
+			WithValues(req.Method, RouteFromRequest(req), RejectReasonTooManyInFlight).
+			Incr(1)
+	}
+
+	w.Header().Set("Retry-After", m.retryAfter)
+	writeError(req.Context(), w, http.StatusServiceUnavailable, respbuilder.ErrOverloaded,
+		fmt.Errorf("server is handling too many requests, please retry later"),
+	)
+}
+
+func (m *maxInFlightMiddleware) gauge(delta int64) {
+	if m.metric == nil {
+		return
+	}
+
+	gauge := m.metric.GetGaugeVec("http_requests_in_flight").WithValues()
+	if delta < 0 {
+		gauge.Decr(-delta)
+		return
+	}
+
+	gauge.Incr(delta)
+}
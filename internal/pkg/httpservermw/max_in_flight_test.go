@@ -0,0 +1,75 @@
+package httpservermw_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+)
+
+func TestMaxInFlightMiddleware(t *testing.T) {
+	promMetric, err := metrics.NewPrometheus()
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := httpservermw.MaxInFlightMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-release
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}),
+		httpservermw.MaxInFlightWithLimit(1),
+		httpservermw.MaxInFlightWithRetryAfter(1500*time.Millisecond),
+		httpservermw.MaxInFlightWithMetric(promMetric),
+		httpservermw.MaxInFlightWithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/healthz/live"
+		}),
+	)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	t.Run("rejected when limit reached", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/users", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get("Retry-After"))
+		assert.Contains(t, resp.Body.String(), `"code":"E3"`)
+	})
+
+	t.Run("filtered request is not limited", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/healthz/live", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	out := scrapeMetrics(t, promMetric.HandlerFunc())
+	assert.Contains(t, out, `http_rejected_requests_total{method="GET",path="/users",reason="too_many_in_flight"} 1`)
+	assert.Contains(t, out, `http_requests_in_flight 1`)
+
+	close(release)
+	wg.Wait()
+
+	t.Run("accepted after released", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/users", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	assert.Contains(t, scrapeMetrics(t, promMetric.HandlerFunc()), `http_requests_in_flight 0`)
+}
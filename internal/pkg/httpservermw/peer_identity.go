@@ -0,0 +1,62 @@
+package httpservermw
+
+import (
+	"context"
+	"net/http"
+)
+
+// PeerIdentity is the identity of the client from its TLS certificate (mTLS).
+type PeerIdentity struct {
+	CommonName   string   `json:"commonName"`
+	DNSNames     []string `json:"dnsNames,omitempty"`
+	URIs         []string `json:"uris,omitempty"` // i.e. SPIFFE ID spiffe://cluster.local/ns/default/sa/client
+	Organization []string `json:"organization,omitempty"`
+	SerialNumber string   `json:"serialNumber"`
+
+	// Verified is true when the certificate is verified against the client CA,
+	// it is always false when the server doesn't verify the client certificate.
+	Verified bool `json:"verified"`
+}
+
+type peerIdentityCtxKey struct{}
+
+// PeerIdentityMiddleware puts the PeerIdentity of the client certificate into the request context,
+// read it using PeerIdentityFromContext. The request without client certificate is passed as is.
+func PeerIdentityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req == nil || req.TLS == nil || len(req.TLS.PeerCertificates) <= 0 {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		// The first certificate is the leaf certificate of the client.
+		cert := req.TLS.PeerCertificates[0]
+		identity := PeerIdentity{
+			CommonName:   cert.Subject.CommonName,
+			DNSNames:     cert.DNSNames,
+			Organization: cert.Subject.Organization,
+			Verified:     len(req.TLS.VerifiedChains) > 0,
+		}
+
+		if cert.SerialNumber != nil {
+			identity.SerialNumber = cert.SerialNumber.Text(16)
+		}
+
+		for _, uri := range cert.URIs {
+			identity.URIs = append(identity.URIs, uri.String())
+		}
+
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), peerIdentityCtxKey{}, identity)))
+	})
+}
+
+// PeerIdentityFromContext returns the PeerIdentity put by PeerIdentityMiddleware,
+// return false if the client doesn't send the certificate.
+func PeerIdentityFromContext(ctx context.Context) (PeerIdentity, bool) {
+	if ctx == nil {
+		return PeerIdentity{}, false
+	}
+
+	identity, ok := ctx.Value(peerIdentityCtxKey{}).(PeerIdentity)
+	return identity, ok
+}
@@ -0,0 +1,73 @@
+package httpservermw_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+)
+
+func TestPeerIdentityMiddleware(t *testing.T) {
+	var identity httpservermw.PeerIdentity
+	var ok bool
+	handler := httpservermw.PeerIdentityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok = httpservermw.PeerIdentityFromContext(r.Context())
+	}))
+
+	t.Run("without tls", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.False(t, ok)
+	})
+
+	t.Run("without client certificate", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		assert.False(t, ok)
+	})
+
+	t.Run("verified client certificate", func(t *testing.T) {
+		spiffeID, _ := url.Parse("spiffe://cluster.local/ns/default/sa/client")
+		cert := &x509.Certificate{
+			SerialNumber: big.NewInt(255),
+			Subject:      pkix.Name{CommonName: "client", Organization: []string{"acme"}},
+			DNSNames:     []string{"client.default.svc"},
+			URIs:         []*url.URL{spiffeID},
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		assert.True(t, ok)
+		assert.Equal(t, httpservermw.PeerIdentity{
+			CommonName:   "client",
+			DNSNames:     []string{"client.default.svc"},
+			URIs:         []string{"spiffe://cluster.local/ns/default/sa/client"},
+			Organization: []string{"acme"},
+			SerialNumber: "ff",
+			Verified:     true,
+		}, identity)
+	})
+}
+
+func TestPeerIdentityFromContext(t *testing.T) {
+	_, ok := httpservermw.PeerIdentityFromContext(nil)
+	assert.False(t, ok)
+
+	_, ok = httpservermw.PeerIdentityFromContext(context.Background())
+	assert.False(t, ok)
+}
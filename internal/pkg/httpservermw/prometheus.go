@@ -1,13 +1,10 @@
 package httpservermw
 
 import (
-	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"net/http/httptest"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
@@ -23,27 +20,41 @@ func PrometheusWithMetric(m metrics.Metric) PrometheusOpt {
 	}
 }
 
+// PrometheusWithMetricsPath set the path to serve the metrics. Default to /metrics.
+// Set to empty to not serve the metrics, i.e. when it is served on the separate admin port.
+func PrometheusWithMetricsPath(path string) PrometheusOpt {
+	return func(p *Prometheus) error {
+		p.metricsPath = path
+		return nil
+	}
+}
+
 type Prometheus struct {
 	baseMux http.Handler // required base multiplexer net/http server
 
 	// options must be passed via PrometheusOpt function
-	logger *slog.Logger
-	metric metrics.Metric
+	logger      *slog.Logger
+	metric      metrics.Metric
+	metricsPath string
 }
 
 var _ http.Handler = (*Prometheus)(nil)
 
 // PrometheusMiddleware creates http.Handler and do some counter for HTTP statistic (request counter, etc),
 // then will continue the request into next baseMux http.Handler.
-// If user request to path /metrics, it will serve the metric instead of doing HTTP statistic.
+// If user request to path /metrics (see PrometheusWithMetricsPath), it will serve the metric instead of doing HTTP statistic.
+//
+// The "path" label uses the route template from RouteMiddleware, so RouteMiddleware must wrap this middleware.
+// Otherwise, raw URL path is used.
 func PrometheusMiddleware(baseMux http.Handler, opts ...PrometheusOpt) (*Prometheus, error) {
 	if baseMux == nil {
 		return nil, fmt.Errorf("prometheus middleware: cannot use nil http.Handler")
 	}
 
 	prom := &Prometheus{
-		baseMux: baseMux,
-		logger:  slog.Default(),
+		baseMux:     baseMux,
+		logger:      slog.Default(),
+		metricsPath: "/metrics",
 	}
 
 	for _, opt := range opts {
@@ -72,7 +83,7 @@ func (p *Prometheus) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if req.URL != nil && req.URL.Path == "/metrics" && p.metric.HandlerFunc() != nil {
+	if p.metricsPath != "" && req.URL.Path == p.metricsPath && p.metric.HandlerFunc() != nil {
 		// If user request /metrics endpoint,
 		// then return the Prometheus metrics.
 
@@ -83,9 +94,10 @@ func (p *Prometheus) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// capture response for statistic purpose
-	respRec := httptest.NewRecorder()
-	p.baseMux.ServeHTTP(respRec, req) // continue to the next http.Handler
+	// capture status code and response size for statistic purpose,
+	// the response is written directly into the actual writer.
+	sw := newStatusWriter(w)
+	p.baseMux.ServeHTTP(sw, req) // continue to the next http.Handler
 
 	// We don't create global variable for any Prometheus stats (counter, gauge, etc) here.
 	// Local variable is easier to debug since it scoped in this function.
@@ -94,34 +106,21 @@ func (p *Prometheus) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	// which easier to track!
 
 	// ** Doing some stats counter/gauge/anything here...
+	// Use route template (i.e. /users/:id) instead of raw URL path to prevent unbounded series per ID.
+	route := RouteFromRequest(req)
+
 	// Always increment request counter
 	p.metric.
 		GetCounterVec("http_requests_total", "code", "method", "path").
-		WithValues(strconv.Itoa(respRec.Code), req.Method, req.URL.Path).
+		WithValues(strconv.Itoa(sw.statusCode), req.Method, route).
 		Incr(1)
 
-	p.metric.GetTimerVec("http_requests_duration", "code", "method", "path").
-		WithValues(strconv.Itoa(respRec.Code), req.Method, req.URL.Path).
+	p.metric.GetTimerVec("http_requests_duration_seconds", "code", "method", "path").
+		WithValues(strconv.Itoa(sw.statusCode), req.Method, route).
 		Timing(time.Since(t0).Nanoseconds())
 
-	// Write headers to actual writer.
-	for k, v := range respRec.Header() {
-		w.Header().Set(k, strings.Join(v, " "))
-	}
-
-	// Write response status code.
-	w.WriteHeader(respRec.Code)
-
-	// Write response body.
-	if n, err := w.Write(respRec.Body.Bytes()); err != nil {
-		ctx := context.Background()
-		if req.Context() != nil {
-			ctx = req.Context()
-		}
-
-		p.logger.ErrorContext(ctx, "prometheus middleware writing response body error",
-			slog.Int("bytes_written", n),
-			slog.Any("error", err),
-		)
-	}
+	p.metric.
+		GetCounterVec("http_response_size_bytes_total", "code", "method", "path").
+		WithValues(strconv.Itoa(sw.statusCode), req.Method, route).
+		Incr(sw.bytesWritten)
 }
@@ -1 +1,187 @@
 package httpservermw_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+)
+
+func newPrometheusMiddleware(t *testing.T, next http.Handler) http.Handler {
+	t.Helper()
+
+	promMetric, err := metrics.NewPrometheus()
+	require.NoError(t, err)
+
+	handler, err := httpservermw.PrometheusMiddleware(next, httpservermw.PrometheusWithMetric(promMetric))
+	require.NoError(t, err)
+	return handler
+}
+
+func scrapeMetrics(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	return resp.Body.String()
+}
+
+func TestPrometheusMiddleware(t *testing.T) {
+	t.Run("nil handler", func(t *testing.T) {
+		handler, err := httpservermw.PrometheusMiddleware(nil)
+		assert.Nil(t, handler)
+		assert.Error(t, err)
+	})
+
+	t.Run("pass-through status, header and body", func(t *testing.T) {
+		handler := newPrometheusMiddleware(t, &mockHandler{
+			responseCode: http.StatusCreated,
+			responseHeader: map[string]string{
+				"Content-Type": "application/json",
+			},
+			responseBody: `{"FOO":"BAR"}`,
+		})
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/users", nil))
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+		assert.Equal(t, `{"FOO":"BAR"}`, resp.Body.String())
+
+		out := scrapeMetrics(t, handler)
+		assert.Contains(t, out, `http_requests_total{code="201",method="POST",path="/users"} 1`)
+		assert.Contains(t, out, `http_response_size_bytes_total{code="201",method="POST",path="/users"} 13`)
+	})
+
+	t.Run("default status code", func(t *testing.T) {
+		handler := newPrometheusMiddleware(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}))
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/ok", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, scrapeMetrics(t, handler), `http_requests_total{code="200",method="GET",path="/ok"} 1`)
+	})
+
+	t.Run("flush is passed to the actual writer", func(t *testing.T) {
+		handler := newPrometheusMiddleware(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: first\n\n"))
+
+			flusher, ok := w.(http.Flusher)
+			require.True(t, ok)
+			flusher.Flush()
+		}))
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/events", nil))
+		assert.True(t, resp.Flushed)
+		assert.Equal(t, "data: first\n\n", resp.Body.String())
+	})
+
+	t.Run("response controller reach the actual writer", func(t *testing.T) {
+		handler := newPrometheusMiddleware(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := http.NewResponseController(w).Flush()
+			assert.NoError(t, err)
+		}))
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/events", nil))
+		assert.True(t, resp.Flushed)
+	})
+
+	t.Run("read from counts bytes", func(t *testing.T) {
+		body := strings.Repeat("a", 64*1024)
+		handler := newPrometheusMiddleware(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			readerFrom, ok := w.(io.ReaderFrom)
+			require.True(t, ok)
+
+			_, err := readerFrom.ReadFrom(bytes.NewBufferString(body))
+			assert.NoError(t, err)
+		}))
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/download", nil))
+		assert.Equal(t, body, resp.Body.String())
+		assert.Contains(t, scrapeMetrics(t, handler), `http_response_size_bytes_total{code="200",method="GET",path="/download"} 65536`)
+	})
+
+	t.Run("hijack on real server", func(t *testing.T) {
+		handler := newPrometheusMiddleware(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, rw, err := http.NewResponseController(w).Hijack()
+			require.NoError(t, err)
+			defer func() {
+				_ = conn.Close()
+			}()
+
+			_, _ = rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			_ = rw.Flush()
+		}))
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		require.NoError(t, err)
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "hijacked", string(b))
+	})
+}
+
+func TestPrometheusWithMetricsPath(t *testing.T) {
+	promMetric, err := metrics.NewPrometheus()
+	require.NoError(t, err)
+
+	next := &mockHandler{responseCode: http.StatusNotFound}
+
+	t.Run("custom path", func(t *testing.T) {
+		handler, err := httpservermw.PrometheusMiddleware(next,
+			httpservermw.PrometheusWithMetric(promMetric),
+			httpservermw.PrometheusWithMetricsPath("/internal/metrics"),
+		)
+		require.NoError(t, err)
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/internal/metrics", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), "# TYPE")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		handler, err := httpservermw.PrometheusMiddleware(next,
+			httpservermw.PrometheusWithMetric(promMetric),
+			httpservermw.PrometheusWithMetricsPath(""),
+		)
+		require.NoError(t, err)
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
@@ -0,0 +1,243 @@
+package httpservermw
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
+)
+
+// RejectReasonRateLimited is the "reason" label of http_rejected_requests_total when the client exceeds the rate limit.
+const RejectReasonRateLimited = "rate_limited"
+
+// RateLimitKeyFunc returns the client identity to be limited, return false to not limit the request.
+type RateLimitKeyFunc func(req *http.Request) (string, bool)
+
+// RateLimitKeyByIP returns the client IP from the remote address.
+// When the server is behind the proxy (i.e. Ingress), set the header added by the proxy: X-Real-IP or X-Forwarded-For.
+// For X-Forwarded-For, the last address is used, because it is added by the proxy while the rest can be spoofed by the client.
+func RateLimitKeyByIP(header string) RateLimitKeyFunc {
+	header = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(header))
+	return func(req *http.Request) (string, bool) {
+		if header != "" {
+			values := strings.Split(req.Header.Get(header), ",")
+			if ip := strings.TrimSpace(values[len(values)-1]); ip != "" {
+				return "ip:" + ip, true
+			}
+		}
+
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			host = req.RemoteAddr
+		}
+
+		return "ip:" + host, host != ""
+	}
+}
+
+// RateLimitKeyByHeader returns the value of the header, i.e. X-API-Key. The request without the header is not limited,
+// combine it with the other key using RateLimitKeyFirst.
+func RateLimitKeyByHeader(name string) RateLimitKeyFunc {
+	name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+	return func(req *http.Request) (string, bool) {
+		value := strings.TrimSpace(req.Header.Get(name))
+		return "header:" + value, value != ""
+	}
+}
+
+// RateLimitKeyByPeerIdentity returns the verified client certificate common name (mTLS) put by PeerIdentityMiddleware.
+func RateLimitKeyByPeerIdentity() RateLimitKeyFunc {
+	return func(req *http.Request) (string, bool) {
+		peer, ok := PeerIdentityFromContext(req.Context())
+		if !ok || !peer.Verified || peer.CommonName == "" {
+			return "", false
+		}
+
+		return "peer:" + peer.CommonName, true
+	}
+}
+
+// RateLimitKeyFirst returns the first key found, i.e. the API key, then fallback to the client IP.
+func RateLimitKeyFirst(keyFuncs ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(req *http.Request) (string, bool) {
+		for _, keyFunc := range keyFuncs {
+			if key, ok := keyFunc(req); ok {
+				return key, true
+			}
+		}
+
+		return "", false
+	}
+}
+
+type RateLimitOpt func(*rateLimitMiddleware) error
+
+// RateLimitWithLimit set the default limit of each client. Default to 100 requests per minute.
+func RateLimitWithLimit(limit RateLimit) RateLimitOpt {
+	return func(m *rateLimitMiddleware) error {
+		if err := limit.validate(); err != nil {
+			return err
+		}
+
+		m.limit = limit
+		return nil
+	}
+}
+
+// RateLimitWithRouteLimit overrides the limit of the route template (see RouteMiddleware), i.e: /login.
+// The requests of this route are counted separately from the other routes.
+func RateLimitWithRouteLimit(route string, limit RateLimit) RateLimitOpt {
+	return func(m *rateLimitMiddleware) error {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			return fmt.Errorf("rate limit route cannot be empty")
+		}
+
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("rate limit of route %s: %w", route, err)
+		}
+
+		m.routeLimits[route] = limit
+		return nil
+	}
+}
+
+// RateLimitWithKeyFunc set how to identify the client. Default to RateLimitKeyByIP without proxy header.
+func RateLimitWithKeyFunc(keyFunc RateLimitKeyFunc) RateLimitOpt {
+	return func(m *rateLimitMiddleware) error {
+		if keyFunc == nil {
+			return fmt.Errorf("rate limit key func cannot be nil")
+		}
+
+		m.keyFunc = keyFunc
+		return nil
+	}
+}
+
+// RateLimitWithStore set the store of the request count. Default to RateLimitMemoryStore with token bucket.
+func RateLimitWithStore(store RateLimitStore) RateLimitOpt {
+	return func(m *rateLimitMiddleware) error {
+		if store == nil {
+			return fmt.Errorf("rate limit store cannot be nil")
+		}
+
+		m.store = store
+		return nil
+	}
+}
+
+// RateLimitWithFilter set the filter of the limited request, return false to not limit the request, i.e. health probes.
+func RateLimitWithFilter(f Filter) RateLimitOpt {
+	return func(m *rateLimitMiddleware) error {
+		m.filter = f
+		return nil
+	}
+}
+
+// RateLimitWithMetric set metrics.Metric to count the rejected request as http_rejected_requests_total,
+// and the number of tracked clients as http_rate_limit_keys.
+func RateLimitWithMetric(metric metrics.Metric) RateLimitOpt {
+	return func(m *rateLimitMiddleware) error {
+		m.metric = metric
+		return nil
+	}
+}
+
+type rateLimitMiddleware struct {
+	limit       RateLimit
+	routeLimits map[string]RateLimit
+	keyFunc     RateLimitKeyFunc
+	store       RateLimitStore
+	filter      Filter
+	metric      metrics.Metric
+}
+
+// RateLimitMiddleware limits the number of requests of each client identified by the RateLimitKeyFunc.
+// Every limited response has the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers
+// (IETF draft RateLimit header fields), and the rejected request is responded with 429 respbuilder.Error JSON body
+// and Retry-After header.
+//
+// The route limit uses the route template, so RouteMiddleware must wrap this middleware.
+func RateLimitMiddleware(next http.Handler, opts ...RateLimitOpt) http.Handler {
+	m := &rateLimitMiddleware{
+		limit:       RateLimit{Requests: 100, Window: time.Minute},
+		routeLimits: make(map[string]RateLimit),
+		keyFunc:     RateLimitKeyByIP(""),
+	}
+
+	for _, opt := range opts {
+		err := opt(m)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if m.store == nil {
+		store, err := NewRateLimitMemoryStore()
+		if err != nil {
+			panic(err)
+		}
+
+		m.store = store
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req == nil || (m.filter != nil && !m.filter(req)) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		key, ok := m.keyFunc(req)
+		if !ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		route := RouteFromRequest(req)
+		limit, hasRouteLimit := m.routeLimits[route]
+		if hasRouteLimit {
+			key = route + "|" + key
+		} else {
+			limit = m.limit
+		}
+
+		result := m.store.Allow(key, limit, time.Now())
+		if m.metric != nil {
+			m.metric.GetGaugeVec("http_rate_limit_keys").WithValues().Set(int64(m.store.Len()))
+		}
+
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Requests, ceilSeconds(limit.Window)))
+
+		if result.Allowed {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		if m.metric != nil {
+			m.metric.GetCounterVec("http_rejected_requests_total", "method", "path", "reason").
+				WithValues(req.Method, route, RejectReasonRateLimited).
+				Incr(1)
+		}
+
+		header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+		writeError(req.Context(), w, http.StatusTooManyRequests, respbuilder.ErrTooManyRequests,
+			fmt.Errorf("too many requests, please retry after %s seconds", ceilSeconds(result.RetryAfter)),
+		)
+	})
+}
+
+// ceilSeconds returns the duration in seconds rounded up, since the headers only accept the integer seconds.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,306 @@
+package httpservermw
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitAlgorithm is how the requests are counted within the window.
+type RateLimitAlgorithm string
+
+const (
+	// RateLimitTokenBucket refills the bucket continuously at Requests per Window, and allows bursts up to Burst requests.
+	RateLimitTokenBucket RateLimitAlgorithm = "token_bucket"
+
+	// RateLimitSlidingWindow allows Requests in any Window, approximated by weighting the count of the previous window.
+	RateLimitSlidingWindow RateLimitAlgorithm = "sliding_window"
+)
+
+// RateLimit is the number of Requests allowed per Window for each client.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+
+	// Burst is the capacity of token bucket, default to Requests. It is not used by the sliding window.
+	Burst int
+}
+
+// ParseRateLimit parses the rate limit in format requests/window, i.e: "100/1m" or "10/1s".
+// The burst of token bucket can be appended after the comma, i.e: "100/1m,20".
+func ParseRateLimit(s string) (RateLimit, error) {
+	s, burstStr, hasBurst := strings.Cut(strings.TrimSpace(s), ",")
+	requestsStr, windowStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit '%s', must be in format requests/window such as 100/1m", s)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(requestsStr))
+	if err != nil {
+		return RateLimit{}, fmt.Errorf("invalid rate limit requests '%s': %w", requestsStr, err)
+	}
+
+	window, err := time.ParseDuration(strings.TrimSpace(windowStr))
+	if err != nil {
+		return RateLimit{}, fmt.Errorf("invalid rate limit window '%s': %w", windowStr, err)
+	}
+
+	limit := RateLimit{Requests: requests, Window: window}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(strings.TrimSpace(burstStr))
+		if err != nil {
+			return RateLimit{}, fmt.Errorf("invalid rate limit burst '%s': %w", burstStr, err)
+		}
+	}
+
+	return limit, limit.validate()
+}
+
+func (l RateLimit) validate() error {
+	if l.Requests <= 0 {
+		return fmt.Errorf("rate limit requests must be positive")
+	}
+
+	if l.Window <= 0 {
+		return fmt.Errorf("rate limit window must be positive")
+	}
+
+	if l.Burst < 0 {
+		return fmt.Errorf("rate limit burst cannot be negative")
+	}
+
+	return nil
+}
+
+func (l RateLimit) capacity() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+
+	return l.Requests
+}
+
+// RateLimitResult is the decision of the request, used to write the RateLimit-* response headers.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+
+	// Reset is the time until the quota is fully restored (token bucket) or the current window ends (sliding window).
+	Reset time.Duration
+
+	// RetryAfter is the time until the next request is allowed, zero when the request is allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the request count of each key.
+// Implement this to share the limit across instances, i.e. using Redis.
+type RateLimitStore interface {
+	Allow(key string, limit RateLimit, now time.Time) RateLimitResult
+	Len() int
+}
+
+type RateLimitMemoryStoreOpt func(*RateLimitMemoryStore) error
+
+// RateLimitMemoryWithAlgorithm set the algorithm. Default to RateLimitTokenBucket.
+func RateLimitMemoryWithAlgorithm(algorithm RateLimitAlgorithm) RateLimitMemoryStoreOpt {
+	return func(s *RateLimitMemoryStore) error {
+		switch algorithm {
+		case RateLimitTokenBucket, RateLimitSlidingWindow:
+			s.algorithm = algorithm
+			return nil
+		default:
+			return fmt.Errorf("unknown rate limit algorithm '%s'", algorithm)
+		}
+	}
+}
+
+// RateLimitMemoryWithMaxKeys set the maximum number of keys kept in memory, the least recently used key is evicted
+// when it is full. Default to 100000.
+func RateLimitMemoryWithMaxKeys(n int) RateLimitMemoryStoreOpt {
+	return func(s *RateLimitMemoryStore) error {
+		if n <= 0 {
+			return fmt.Errorf("rate limit max keys must be positive")
+		}
+
+		s.maxKeys = n
+		return nil
+	}
+}
+
+// rateLimitEntry is the state of one key, the fields are used based on the algorithm.
+type rateLimitEntry struct {
+	key      string
+	lastSeen time.Time
+
+	// expireAfter is the idle duration after which the entry has the same state as the new one, so it can be evicted.
+	expireAfter time.Duration
+
+	// token bucket
+	tokens     float64
+	lastRefill time.Time
+
+	// sliding window
+	windowStart time.Time
+	current     int
+	previous    int
+}
+
+// RateLimitMemoryStore is RateLimitStore in memory of this instance, so the limit is per instance.
+// The keys are kept in least recently used order, the idle key is evicted on the next request,
+// and the least recently used key is evicted when the store is full.
+type RateLimitMemoryStore struct {
+	algorithm RateLimitAlgorithm
+	maxKeys   int
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is the most recently used
+}
+
+var _ RateLimitStore = (*RateLimitMemoryStore)(nil)
+
+func NewRateLimitMemoryStore(opts ...RateLimitMemoryStoreOpt) (*RateLimitMemoryStore, error) {
+	s := &RateLimitMemoryStore{
+		algorithm: RateLimitTokenBucket,
+		maxKeys:   100000,
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+
+	for _, opt := range opts {
+		err := opt(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Len returns the number of keys in the store.
+func (s *RateLimitMemoryStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.lru.Len()
+}
+
+func (s *RateLimitMemoryStore) Allow(key string, limit RateLimit, now time.Time) RateLimitResult {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.evictIdle(now)
+
+	var entry *rateLimitEntry
+	if elem, exist := s.entries[key]; exist {
+		entry = elem.Value.(*rateLimitEntry)
+		s.lru.MoveToFront(elem)
+	} else {
+		// The store is full, evict the least recently used key for the new key.
+		for s.lru.Len() >= s.maxKeys {
+			s.remove(s.lru.Back())
+		}
+
+		entry = &rateLimitEntry{
+			key:         key,
+			tokens:      float64(limit.capacity()),
+			lastRefill:  now,
+			windowStart: now.Truncate(limit.Window),
+		}
+		s.entries[key] = s.lru.PushFront(entry)
+	}
+
+	entry.lastSeen = now
+
+	if s.algorithm == RateLimitSlidingWindow {
+		// The previous window count is not used anymore after two windows.
+		entry.expireAfter = 2 * limit.Window
+		return allowSlidingWindow(entry, limit, now)
+	}
+
+	// The bucket is full again after the time to refill the capacity.
+	entry.expireAfter = time.Duration(float64(limit.capacity()) / float64(limit.Requests) * float64(limit.Window))
+	return allowTokenBucket(entry, limit, now)
+}
+
+// evictIdle removes the idle keys from the least recently used, their state is the same as the new key.
+func (s *RateLimitMemoryStore) evictIdle(now time.Time) {
+	for elem := s.lru.Back(); elem != nil; elem = s.lru.Back() {
+		entry := elem.Value.(*rateLimitEntry)
+		if now.Sub(entry.lastSeen) < entry.expireAfter {
+			return
+		}
+
+		s.remove(elem)
+	}
+}
+
+func (s *RateLimitMemoryStore) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.entries, elem.Value.(*rateLimitEntry).key)
+}
+
+func allowTokenBucket(entry *rateLimitEntry, limit RateLimit, now time.Time) RateLimitResult {
+	capacity := float64(limit.capacity())
+	perToken := limit.Window / time.Duration(limit.Requests) // time to refill one token
+
+	elapsed := now.Sub(entry.lastRefill)
+	if elapsed > 0 {
+		entry.tokens = math.Min(capacity, entry.tokens+elapsed.Seconds()/perToken.Seconds())
+		entry.lastRefill = now
+	}
+
+	result := RateLimitResult{Limit: limit.capacity()}
+	if entry.tokens >= 1 {
+		entry.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - entry.tokens) * float64(perToken))
+	}
+
+	result.Remaining = int(entry.tokens)
+	result.Reset = time.Duration((capacity - entry.tokens) * float64(perToken))
+	return result
+}
+
+func allowSlidingWindow(entry *rateLimitEntry, limit RateLimit, now time.Time) RateLimitResult {
+	windowStart := now.Truncate(limit.Window)
+	switch {
+	case windowStart.Sub(entry.windowStart) >= 2*limit.Window:
+		entry.previous, entry.current = 0, 0
+	case windowStart.After(entry.windowStart):
+		entry.previous, entry.current = entry.current, 0
+	}
+	entry.windowStart = windowStart
+
+	// The previous window count is weighted by its portion that still overlaps with the sliding window.
+	elapsed := now.Sub(windowStart)
+	weight := float64(limit.Window-elapsed) / float64(limit.Window)
+	count := float64(entry.previous)*weight + float64(entry.current)
+
+	result := RateLimitResult{
+		Limit: limit.Requests,
+		Reset: limit.Window - elapsed,
+	}
+
+	if count+1 <= float64(limit.Requests) {
+		entry.current++
+		count++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = result.Reset
+		if entry.previous > 0 && entry.current < limit.Requests {
+			// Wait until enough of the previous window slides out.
+			needed := (count + 1 - float64(limit.Requests)) / float64(entry.previous)
+			result.RetryAfter = time.Duration(math.Ceil(needed * float64(limit.Window)))
+		}
+	}
+
+	result.Remaining = max(0, limit.Requests-int(math.Ceil(count)))
+	return result
+}
@@ -0,0 +1,118 @@
+package httpservermw_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	limit, err := httpservermw.ParseRateLimit("100/1m")
+	assert.NoError(t, err)
+	assert.Equal(t, httpservermw.RateLimit{Requests: 100, Window: time.Minute}, limit)
+
+	limit, err = httpservermw.ParseRateLimit(" 10 / 1s , 20 ")
+	assert.NoError(t, err)
+	assert.Equal(t, httpservermw.RateLimit{Requests: 10, Window: time.Second, Burst: 20}, limit)
+
+	for _, s := range []string{"100", "a/1m", "100/a", "0/1m", "100/0s", "100/1m,a", "100/1m,-1"} {
+		_, err = httpservermw.ParseRateLimit(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestRateLimitMemoryStore_TokenBucket(t *testing.T) {
+	store, err := httpservermw.NewRateLimitMemoryStore()
+	require.NoError(t, err)
+
+	// 1 token per second, burst of 3
+	limit := httpservermw.RateLimit{Requests: 60, Window: time.Minute, Burst: 3}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 2; i >= 0; i-- {
+		result := store.Allow("client", limit, now)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := store.Allow("client", limit, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// the other client has its own bucket
+	assert.True(t, store.Allow("other", limit, now).Allowed)
+
+	// one token is refilled after one second
+	result = store.Allow("client", limit, now.Add(time.Second))
+	assert.True(t, result.Allowed)
+	assert.False(t, store.Allow("client", limit, now.Add(time.Second)).Allowed)
+}
+
+func TestRateLimitMemoryStore_SlidingWindow(t *testing.T) {
+	store, err := httpservermw.NewRateLimitMemoryStore(
+		httpservermw.RateLimitMemoryWithAlgorithm(httpservermw.RateLimitSlidingWindow),
+	)
+	require.NoError(t, err)
+
+	limit := httpservermw.RateLimit{Requests: 4, Window: time.Minute}
+	windowStart := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, store.Allow("client", limit, windowStart.Add(30*time.Second)).Allowed)
+	}
+
+	result := store.Allow("client", limit, windowStart.Add(30*time.Second))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 30*time.Second, result.RetryAfter)
+
+	// At the quarter of the next window, the previous count weights 3 of 4, so only one request is allowed.
+	next := windowStart.Add(75 * time.Second)
+	assert.True(t, store.Allow("client", limit, next).Allowed)
+	result = store.Allow("client", limit, next)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 15*time.Second, result.RetryAfter)
+
+	// After two windows, the previous count is not used.
+	for i := 0; i < 4; i++ {
+		assert.True(t, store.Allow("client", limit, windowStart.Add(3*time.Minute)).Allowed)
+	}
+}
+
+func TestRateLimitMemoryStore_Eviction(t *testing.T) {
+	store, err := httpservermw.NewRateLimitMemoryStore(httpservermw.RateLimitMemoryWithMaxKeys(2))
+	require.NoError(t, err)
+
+	limit := httpservermw.RateLimit{Requests: 1, Window: time.Minute}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(t, store.Allow("a", limit, now).Allowed)
+	assert.True(t, store.Allow("b", limit, now).Allowed)
+	assert.False(t, store.Allow("a", limit, now).Allowed) // "a" is now the most recently used
+	assert.Equal(t, 2, store.Len())
+
+	// full, the least recently used "b" is evicted
+	assert.True(t, store.Allow("c", limit, now).Allowed)
+	assert.Equal(t, 2, store.Len())
+	assert.False(t, store.Allow("a", limit, now).Allowed)
+	assert.True(t, store.Allow("b", limit, now).Allowed, "evicted key starts with full quota")
+
+	// idle keys are evicted after the bucket is refilled
+	assert.True(t, store.Allow("d", limit, now.Add(time.Hour)).Allowed)
+	assert.Equal(t, 1, store.Len())
+}
+
+func TestNewRateLimitMemoryStore_InvalidOption(t *testing.T) {
+	_, err := httpservermw.NewRateLimitMemoryStore(httpservermw.RateLimitMemoryWithAlgorithm("fixed_window"))
+	assert.Error(t, err)
+
+	_, err = httpservermw.NewRateLimitMemoryStore(httpservermw.RateLimitMemoryWithMaxKeys(0))
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package httpservermw_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	promMetric, err := metrics.NewPrometheus()
+	require.NoError(t, err)
+
+	handler := httpservermw.RouteMiddleware(httpservermw.RateLimitMiddleware(&mockHandler{responseCode: http.StatusOK},
+		httpservermw.RateLimitWithLimit(httpservermw.RateLimit{Requests: 2, Window: time.Minute}),
+		httpservermw.RateLimitWithRouteLimit("/users/:id", httpservermw.RateLimit{Requests: 1, Window: time.Minute}),
+		httpservermw.RateLimitWithMetric(promMetric),
+		httpservermw.RateLimitWithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/healthz/live"
+		}),
+	), &mockRouteMatcher{})
+
+	request := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("default limit", func(t *testing.T) {
+		resp := request("/orders", "10.0.0.1:1234")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", resp.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", resp.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=60", resp.Header().Get("RateLimit-Policy"))
+
+		assert.Equal(t, http.StatusOK, request("/orders", "10.0.0.1:1235").Code)
+
+		resp = request("/orders", "10.0.0.1:1236")
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+		assert.Contains(t, resp.Body.String(), `"code":"E4"`)
+	})
+
+	t.Run("other client", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("/orders", "10.0.0.2:1234").Code)
+	})
+
+	t.Run("route limit is counted separately", func(t *testing.T) {
+		resp := request("/users/1", "10.0.0.1:1234")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "1", resp.Header().Get("RateLimit-Limit"))
+
+		assert.Equal(t, http.StatusTooManyRequests, request("/users/2", "10.0.0.1:1234").Code)
+	})
+
+	t.Run("filtered request is not limited", func(t *testing.T) {
+		resp := request("/healthz/live", "10.0.0.1:1234")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Empty(t, resp.Header().Get("RateLimit-Limit"))
+	})
+
+	out := scrapeMetrics(t, promMetric.HandlerFunc())
+	assert.Contains(t, out, `http_rejected_requests_total{method="GET",path="unmatched",reason="rate_limited"} 1`)
+	assert.Contains(t, out, `http_rejected_requests_total{method="GET",path="/users/:id",reason="rate_limited"} 1`)
+	assert.Contains(t, out, `http_rate_limit_keys 3`)
+}
+
+func TestRateLimitMiddleware_InvalidOption(t *testing.T) {
+	next := &mockHandler{responseCode: http.StatusOK}
+	assert.Panics(t, func() {
+		httpservermw.RateLimitMiddleware(next, httpservermw.RateLimitWithLimit(httpservermw.RateLimit{}))
+	})
+
+	assert.Panics(t, func() {
+		httpservermw.RateLimitMiddleware(next, httpservermw.RateLimitWithKeyFunc(nil))
+	})
+}
+
+func TestRateLimitKeyFunc(t *testing.T) {
+	newRequest := func(header, value string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+
+		return req
+	}
+
+	t.Run("ip", func(t *testing.T) {
+		key, ok := httpservermw.RateLimitKeyByIP("")(newRequest("X-Forwarded-For", "1.1.1.1"))
+		assert.True(t, ok)
+		assert.Equal(t, "ip:10.0.0.1", key)
+	})
+
+	t.Run("ip from proxy header", func(t *testing.T) {
+		key, ok := httpservermw.RateLimitKeyByIP("x-forwarded-for")(newRequest("X-Forwarded-For", "1.1.1.1, 2.2.2.2"))
+		assert.True(t, ok)
+		assert.Equal(t, "ip:2.2.2.2", key)
+
+		key, ok = httpservermw.RateLimitKeyByIP("X-Real-IP")(newRequest("", ""))
+		assert.True(t, ok)
+		assert.Equal(t, "ip:10.0.0.1", key)
+	})
+
+	t.Run("header", func(t *testing.T) {
+		key, ok := httpservermw.RateLimitKeyByHeader("X-API-Key")(newRequest("X-API-Key", "abc"))
+		assert.True(t, ok)
+		assert.Equal(t, "header:abc", key)
+
+		_, ok = httpservermw.RateLimitKeyByHeader("X-API-Key")(newRequest("", ""))
+		assert.False(t, ok)
+	})
+
+	t.Run("peer identity", func(t *testing.T) {
+		var key string
+		var ok bool
+		handler := httpservermw.PeerIdentityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok = httpservermw.RateLimitKeyByPeerIdentity()(r)
+		}))
+
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client"}}
+		req := newRequest("", "")
+		req.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		assert.True(t, ok)
+		assert.Equal(t, "peer:client", key)
+
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest("", ""))
+		assert.False(t, ok)
+	})
+
+	t.Run("first", func(t *testing.T) {
+		keyFunc := httpservermw.RateLimitKeyFirst(
+			httpservermw.RateLimitKeyByHeader("X-API-Key"),
+			httpservermw.RateLimitKeyByIP(""),
+		)
+
+		key, _ := keyFunc(newRequest("X-API-Key", "abc"))
+		assert.Equal(t, "header:abc", key)
+
+		key, _ = keyFunc(newRequest("", ""))
+		assert.Equal(t, "ip:10.0.0.1", key)
+
+		_, ok := httpservermw.RateLimitKeyFirst()(newRequest("", ""))
+		assert.False(t, ok)
+	})
+}
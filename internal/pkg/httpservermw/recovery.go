@@ -0,0 +1,129 @@
+package httpservermw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
+)
+
+type RecoveryOpt func(*recoveryMiddleware) error
+
+// RecoveryWithLogger set logger to log the recovered panic. Default to slog.Default.
+func RecoveryWithLogger(logger *slog.Logger) RecoveryOpt {
+	return func(m *recoveryMiddleware) error {
+		if logger == nil {
+			m.logger = slog.Default()
+			return nil
+		}
+
+		m.logger = logger
+		return nil
+	}
+}
+
+// RecoveryWithMetric set metrics.Metric to count the recovered panic as http_panics_total.
+func RecoveryWithMetric(m metrics.Metric) RecoveryOpt {
+	return func(r *recoveryMiddleware) error {
+		r.metric = m
+		return nil
+	}
+}
+
+type recoveryMiddleware struct {
+	logger *slog.Logger
+	metric metrics.Metric
+}
+
+// RecoveryMiddleware recovers the panic from the next handler, then responds with 500 respbuilder.Error JSON body.
+// The panic value and stack trace are logged, recorded as exception event in the active span with error status,
+// and counted in http_panics_total metric.
+//
+// The http.ErrAbortHandler panic is re-panicked as is, so net/http still aborts the response silently.
+// When the response header is already sent, the 500 response cannot be written anymore,
+// so it panics with http.ErrAbortHandler to abort the response instead of sending a partial response as complete.
+//
+// To record the panic in the span, this middleware must be placed after the middleware that starts the span (otelhttp).
+func RecoveryMiddleware(next http.Handler, opts ...RecoveryOpt) http.Handler {
+	m := &recoveryMiddleware{
+		logger: slog.Default(),
+	}
+
+	for _, opt := range opts {
+		err := opt(m)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sw := newStatusWriter(w)
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			m.handlePanic(sw, req, rec, debug.Stack())
+		}()
+
+		next.ServeHTTP(sw, req)
+	})
+}
+
+func (m *recoveryMiddleware) handlePanic(sw *statusWriter, req *http.Request, rec any, stack []byte) {
+	err, ok := rec.(error)
+	if !ok {
+		err = fmt.Errorf("%v", rec)
+	}
+
+	var (
+		method string
+		route  = RouteUnmatched
+		ctx    = context.Background()
+	)
+
+	if req != nil {
+		method = req.Method
+		route = RouteFromRequest(req)
+		ctx = req.Context()
+	}
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err, trace.WithAttributes(
+		semconv.ExceptionType(fmt.Sprintf("%T", rec)),
+		semconv.ExceptionStacktrace(string(stack)),
+	))
+	span.SetStatus(codes.Error, fmt.Sprintf("panic: %s", err.Error()))
+
+	m.logger.ErrorContext(ctx, "panic recovered",
+		slog.String("method", method),
+		slog.String("route", route),
+		slog.String("panic", err.Error()),
+		slog.String("stack", string(stack)),
+	)
+
+	if m.metric != nil {
+		m.metric.GetCounterVec("http_panics_total", "method", "path").WithValues(method, route).Incr(1)
+	}
+
+	if sw.wroteHeader {
+		panic(http.ErrAbortHandler)
+	}
+
+	// The panic message is not sent to the client, because it may contain internal detail.
+	writeError(ctx, sw, http.StatusInternalServerError, respbuilder.ErrGeneral, errors.New(http.StatusText(http.StatusInternalServerError)))
+}
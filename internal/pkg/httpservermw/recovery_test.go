@@ -0,0 +1,109 @@
+package httpservermw_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/requestid"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	t.Run("no panic", func(t *testing.T) {
+		mw := httpservermw.RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusCreated, resp.Code)
+	})
+
+	t.Run("panic is converted into 500 response", func(t *testing.T) {
+		spanRecorder := tracetest.NewSpanRecorder()
+		tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)).Tracer("test")
+
+		promMetric, err := metrics.NewPrometheus()
+		require.NoError(t, err)
+
+		logBuf := &bytes.Buffer{}
+		mw := httpservermw.RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "100")
+			panic("something went wrong")
+		}),
+			httpservermw.RecoveryWithLogger(slog.New(slog.NewJSONHandler(logBuf, nil))),
+			httpservermw.RecoveryWithMetric(promMetric),
+		)
+
+		ctx, span := tracer.Start(requestid.NewContext(context.Background(), "req-123"), "request")
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/users", nil).WithContext(ctx))
+		span.End()
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+		assert.Empty(t, resp.Header().Get("Content-Length"))
+
+		var body respbuilder.RespStructureErr
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, "req-123", body.RequestID)
+		assert.Equal(t, http.StatusText(http.StatusInternalServerError), body.Error.Message)
+		assert.NotContains(t, resp.Body.String(), "something went wrong")
+
+		assert.Contains(t, logBuf.String(), `"panic":"something went wrong"`)
+		assert.Contains(t, logBuf.String(), "recovery_test.go")
+
+		spans := spanRecorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		require.Len(t, spans[0].Events(), 1)
+		assert.Equal(t, "exception", spans[0].Events()[0].Name)
+
+		var hasStack bool
+		for _, attr := range spans[0].Events()[0].Attributes {
+			if attr.Key == "exception.stacktrace" {
+				hasStack = true
+				assert.Contains(t, attr.Value.AsString(), "recovery_test.go")
+			}
+		}
+		assert.True(t, hasStack)
+
+		assert.Contains(t, scrapeMetrics(t, promMetric.HandlerFunc()), `http_panics_total{method="GET",path="/users"} 1`)
+	})
+
+	t.Run("abort handler is re-panicked", func(t *testing.T) {
+		mw := httpservermw.RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+
+	t.Run("response already sent is aborted", func(t *testing.T) {
+		mw := httpservermw.RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("partial"))
+			panic("something went wrong")
+		}), httpservermw.RecoveryWithLogger(slog.New(slog.DiscardHandler)))
+
+		resp := httptest.NewRecorder()
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			mw.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+}
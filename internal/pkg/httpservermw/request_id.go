@@ -0,0 +1,88 @@
+package httpservermw
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/requestid"
+)
+
+type RequestIDOpt func(*requestIDMiddleware) error
+
+// RequestIDWithHeader set the header name to accept and echo the request ID. Default to requestid.Header (X-Request-ID).
+func RequestIDWithHeader(name string) RequestIDOpt {
+	return func(m *requestIDMiddleware) error {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("request id header cannot be empty")
+		}
+
+		m.header = textproto.CanonicalMIMEHeaderKey(name)
+		return nil
+	}
+}
+
+// RequestIDWithGenerator set the function to generate the request ID when the incoming request doesn't have valid one.
+// Default to requestid.New.
+func RequestIDWithGenerator(generator func() string) RequestIDOpt {
+	return func(m *requestIDMiddleware) error {
+		if generator == nil {
+			return fmt.Errorf("request id generator cannot be nil")
+		}
+
+		m.generator = generator
+		return nil
+	}
+}
+
+type requestIDMiddleware struct {
+	header    string
+	generator func() string
+}
+
+// RequestIDMiddleware accepts the request ID from the incoming header, or generates a new one when it is missing or invalid
+// (see requestid.Valid). The request ID is put into the request context (read it using requestid.FromContext),
+// set back into the request header, and echoed in the response header.
+//
+// This middleware should be the outermost one, so the logs, spans and error responses of the next middlewares
+// can use the same request ID.
+func RequestIDMiddleware(next http.Handler, opts ...RequestIDOpt) http.Handler {
+	m := &requestIDMiddleware{
+		header:    requestid.Header,
+		generator: requestid.New,
+	}
+
+	for _, opt := range opts {
+		err := opt(m)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req == nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		id := req.Header.Get(m.header)
+		if !requestid.Valid(id) {
+			id = m.generator()
+		}
+
+		// Set before the handler writes the response, so it is always sent even on error response.
+		w.Header().Set(m.header, id)
+
+		req = req.WithContext(requestid.NewContext(req.Context(), id))
+		req.Header = req.Header.Clone()
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+
+		req.Header.Set(m.header, id)
+
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,84 @@
+package httpservermw_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/requestid"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var (
+		ctxID    string
+		headerID string
+	)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID = requestid.FromContext(r.Context())
+		headerID = r.Header.Get(requestid.Header)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	t.Run("accept incoming request id", func(t *testing.T) {
+		mw := httpservermw.RequestIDMiddleware(handler)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestid.Header, "incoming-id")
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, req)
+		assert.Equal(t, "incoming-id", ctxID)
+		assert.Equal(t, "incoming-id", headerID)
+		assert.Equal(t, "incoming-id", resp.Header().Get(requestid.Header))
+	})
+
+	t.Run("generate when missing", func(t *testing.T) {
+		mw := httpservermw.RequestIDMiddleware(handler)
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.NotEmpty(t, ctxID)
+		assert.Equal(t, ctxID, headerID)
+		assert.Equal(t, ctxID, resp.Header().Get(requestid.Header))
+	})
+
+	t.Run("replace invalid incoming request id", func(t *testing.T) {
+		mw := httpservermw.RequestIDMiddleware(handler, httpservermw.RequestIDWithGenerator(func() string {
+			return "generated-id"
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestid.Header, "<script>alert(1)</script>")
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, req)
+		assert.Equal(t, "generated-id", ctxID)
+		assert.Equal(t, "generated-id", resp.Header().Get(requestid.Header))
+	})
+
+	t.Run("custom header", func(t *testing.T) {
+		mw := httpservermw.RequestIDMiddleware(handler, httpservermw.RequestIDWithHeader("x-correlation-id"))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Correlation-Id", "correlation-id")
+
+		resp := httptest.NewRecorder()
+		mw.ServeHTTP(resp, req)
+		assert.Equal(t, "correlation-id", ctxID)
+		assert.Equal(t, "correlation-id", resp.Header().Get("X-Correlation-Id"))
+	})
+
+	t.Run("invalid option", func(t *testing.T) {
+		assert.Panics(t, func() {
+			httpservermw.RequestIDMiddleware(handler, httpservermw.RequestIDWithHeader(""))
+		})
+
+		assert.Panics(t, func() {
+			httpservermw.RequestIDMiddleware(handler, httpservermw.RequestIDWithGenerator(nil))
+		})
+	})
+}
@@ -0,0 +1,66 @@
+package httpservermw
+
+import (
+	"context"
+	"net/http"
+)
+
+// RouteUnmatched is the route template used when the request doesn't match any registered route (i.e. 404).
+// Using a single bucket prevents the unbounded metric series or span names from random URL path.
+const RouteUnmatched = "unmatched"
+
+// RouteMatcher resolves the registered route template of the request, for example /users/:id.
+// It must return empty string if no route matched.
+type RouteMatcher interface {
+	MatchRoute(req *http.Request) string
+}
+
+type routeCtxKey struct{}
+
+// RouteMiddleware resolves the route template once using the RouteMatcher and put it into the request context,
+// so the next middlewares (metrics, tracing and logging) can use the same low-cardinality value instead of raw URL path.
+//
+// This middleware must be placed before any middleware that calls RouteFromRequest.
+func RouteMiddleware(next http.Handler, matcher RouteMatcher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req == nil || req.URL == nil || matcher == nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		route := matcher.MatchRoute(req)
+		if route == "" {
+			route = RouteUnmatched
+		}
+
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), routeCtxKey{}, route)))
+	})
+}
+
+// RouteFromContext returns the route template put by RouteMiddleware, return false if it doesn't exist.
+func RouteFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+
+	route, ok := ctx.Value(routeCtxKey{}).(string)
+	return route, ok
+}
+
+// RouteFromRequest returns the route template of the request.
+// It fallbacks to the raw URL path when RouteMiddleware is not used.
+func RouteFromRequest(req *http.Request) string {
+	if req == nil {
+		return RouteUnmatched
+	}
+
+	if route, ok := RouteFromContext(req.Context()); ok {
+		return route
+	}
+
+	if req.URL == nil {
+		return RouteUnmatched
+	}
+
+	return req.URL.Path
+}
@@ -0,0 +1,67 @@
+package httpservermw_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yusufsyaifudin/go-project-structure/internal/pkg/httpservermw"
+)
+
+type mockRouteMatcher struct{}
+
+func (m *mockRouteMatcher) MatchRoute(req *http.Request) string {
+	if strings.HasPrefix(req.URL.Path, "/users/") {
+		return "/users/:id"
+	}
+
+	return ""
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	var route string
+	handler := httpservermw.RouteMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route = httpservermw.RouteFromRequest(r)
+	}), &mockRouteMatcher{})
+
+	t.Run("matched", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/123", nil))
+		assert.Equal(t, "/users/:id", route)
+	})
+
+	t.Run("unmatched", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/random/123", nil))
+		assert.Equal(t, httpservermw.RouteUnmatched, route)
+	})
+}
+
+func TestRouteFromRequest(t *testing.T) {
+	t.Run("nil request", func(t *testing.T) {
+		assert.Equal(t, httpservermw.RouteUnmatched, httpservermw.RouteFromRequest(nil))
+	})
+
+	t.Run("without route middleware", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/123", nil)
+		assert.Equal(t, "/users/123", httpservermw.RouteFromRequest(req))
+
+		_, ok := httpservermw.RouteFromContext(req.Context())
+		assert.False(t, ok)
+	})
+}
+
+func TestPrometheusMiddleware_RouteTemplate(t *testing.T) {
+	handler := newPrometheusMiddleware(t, &mockHandler{responseCode: http.StatusOK})
+	handler = httpservermw.RouteMiddleware(handler, &mockRouteMatcher{})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/not-found/3", nil))
+
+	out := scrapeMetrics(t, handler)
+	assert.Contains(t, out, `http_requests_total{code="200",method="GET",path="/users/:id"} 2`)
+	assert.Contains(t, out, `http_requests_total{code="200",method="GET",path="unmatched"} 1`)
+	assert.NotContains(t, out, `path="/users/1"`)
+}
@@ -3,7 +3,6 @@ package httpservermw
 import (
 	"fmt"
 	"net/http"
-	"net/url"
 
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
@@ -31,12 +30,7 @@ func SpanInjectorMiddleware(next http.Handler, filter Filter) http.Handler {
 		// Only act when the route is excluded from the main OTel middleware.
 		// For included routes, otelhttp has already put a real exported span in the context.
 		if r != nil && filter != nil && !filter(r) {
-			reqURL := r.URL
-			if reqURL == nil {
-				reqURL = &url.URL{}
-			}
-
-			ctx, span := tracer.Start(r.Context(), fmt.Sprintf("%s %s", r.Method, reqURL.EscapedPath()))
+			ctx, span := tracer.Start(r.Context(), fmt.Sprintf("%s %s", r.Method, RouteFromRequest(r)))
 			defer span.End()
 
 			r = r.WithContext(ctx)
@@ -0,0 +1,78 @@
+package httpservermw
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// statusWriter is a pass-through http.ResponseWriter which captures the status code and the number of bytes written.
+// Unlike httptest.ResponseRecorder, it writes directly into the underlying writer without buffering,
+// so streaming response (Server-Sent Events, chunked download, large file) still works as expected.
+//
+// It implements http.Flusher, http.Hijacker and io.ReaderFrom by delegating to the underlying writer,
+// and Unwrap, so http.ResponseController can reach any other optional interface of the underlying writer.
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int64
+	wroteHeader  bool
+}
+
+var (
+	_ http.ResponseWriter = (*statusWriter)(nil)
+	_ http.Flusher        = (*statusWriter)(nil)
+	_ http.Hijacker       = (*statusWriter)(nil)
+	_ io.ReaderFrom       = (*statusWriter)(nil)
+)
+
+func newStatusWriter(w http.ResponseWriter) *statusWriter {
+	return &statusWriter{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK, // Default status code when handler doesn't call WriteHeader
+	}
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	// Informational headers (1xx) can be written multiple times before the final one.
+	if !w.wroteHeader && statusCode >= http.StatusOK {
+		w.statusCode = statusCode
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytesWritten += int64(n)
+	return n, err
+}
+
+// ReadFrom uses the io.ReaderFrom of underlying writer if any (i.e. sendfile on *http.response).
+func (w *statusWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.wroteHeader = true
+
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.bytesWritten += n
+	return n, err
+}
+
+func (w *statusWriter) Flush() {
+	w.wroteHeader = true
+
+	// Error is ignored because http.Flusher doesn't have any way to return it.
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap is used by http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
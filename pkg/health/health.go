@@ -0,0 +1,330 @@
+// Package health runs the named health checks registered by the components,
+// and reports the liveness, readiness and startup status of the application.
+package health
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Probe is the kind of health probe, mapped into Kubernetes liveness, readiness and startup probes.
+type Probe string
+
+const (
+	// Liveness tells whether the process is still working, failing it makes the container restarted.
+	// Only register the check that can be fixed by restarting, never the external dependency.
+	Liveness Probe = "live"
+
+	// Readiness tells whether the instance can receive traffic. It fails until started and during shutdown.
+	Readiness Probe = "ready"
+
+	// Startup tells whether the application finished the initialization. It fails until MarkStarted is called.
+	Startup Probe = "startup"
+)
+
+// Status of the check or the whole report.
+type Status string
+
+const (
+	StatusOK Status = "ok"
+
+	// StatusDegraded means only non-critical checks are failing, the probe still passes.
+	StatusDegraded Status = "degraded"
+
+	StatusFailing Status = "failing"
+)
+
+// CheckFunc returns nil if the component is healthy. It must respect the context deadline.
+type CheckFunc func(ctx context.Context) error
+
+type CheckOpt func(*check) error
+
+// CheckWithTimeout set the maximum time of one check run, the check is failing when it exceeds. Default to 2 seconds.
+func CheckWithTimeout(timeout time.Duration) CheckOpt {
+	return func(c *check) error {
+		if timeout <= 0 {
+			return fmt.Errorf("health check timeout must be positive")
+		}
+
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// CheckWithCritical set whether the failure of this check fails the probe.
+// The failure of non-critical check only makes the status degraded. Default to true.
+func CheckWithCritical(critical bool) CheckOpt {
+	return func(c *check) error {
+		c.critical = critical
+		return nil
+	}
+}
+
+// CheckWithCacheTTL reuses the last result within the ttl, so the frequent probes don't overload the dependency.
+// Zero means the check runs on every probe. Default to zero.
+func CheckWithCacheTTL(ttl time.Duration) CheckOpt {
+	return func(c *check) error {
+		if ttl < 0 {
+			return fmt.Errorf("health check cache ttl cannot be negative")
+		}
+
+		c.cacheTTL = ttl
+		return nil
+	}
+}
+
+// CheckWithProbes set which probes run this check. Default to Readiness only.
+func CheckWithProbes(probes ...Probe) CheckOpt {
+	return func(c *check) error {
+		if len(probes) <= 0 {
+			return fmt.Errorf("health check must have at least one probe")
+		}
+
+		for _, probe := range probes {
+			switch probe {
+			case Liveness, Readiness, Startup:
+			default:
+				return fmt.Errorf("unknown health probe '%s'", probe)
+			}
+		}
+
+		c.probes = probes
+		return nil
+	}
+}
+
+// CheckResult is the result of one check.
+type CheckResult struct {
+	Name      string    `json:"name"`
+	Status    Status    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Cached    bool      `json:"cached,omitempty"`
+}
+
+// Report is the result of the probe.
+type Report struct {
+	Probe  Probe  `json:"probe"`
+	Status Status `json:"status"`
+
+	// Reason explains the failing status which is not caused by the checks, i.e. the server is shutting down.
+	Reason string        `json:"reason,omitempty"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+// Healthy returns true if the probe passes, including the degraded status.
+func (r Report) Healthy() bool {
+	return r.Status != StatusFailing
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	timeout  time.Duration
+	critical bool
+	cacheTTL time.Duration
+	probes   []Probe
+
+	// runLock serializes the run of the cached check, so the concurrent probes share the same result.
+	runLock sync.Mutex
+	last    *CheckResult
+}
+
+// Health holds the registered checks, and the startup and readiness state of the application.
+type Health struct {
+	lock   sync.RWMutex
+	checks []*check
+
+	started  atomic.Bool
+	notReady atomic.Bool
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+// Register adds the named check. The name must be unique.
+func (h *Health) Register(name string, fn CheckFunc, opts ...CheckOpt) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("health check name cannot be empty")
+	}
+
+	if fn == nil {
+		return fmt.Errorf("health check '%s' cannot use nil function", name)
+	}
+
+	c := &check{
+		name:     name,
+		fn:       fn,
+		timeout:  2 * time.Second,
+		critical: true,
+		probes:   []Probe{Readiness},
+	}
+
+	for _, opt := range opts {
+		err := opt(c)
+		if err != nil {
+			return fmt.Errorf("health check '%s': %w", name, err)
+		}
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, existing := range h.checks {
+		if existing.name == name {
+			return fmt.Errorf("health check '%s' is already registered", name)
+		}
+	}
+
+	h.checks = append(h.checks, c)
+	return nil
+}
+
+// MarkStarted flips the startup probe and allows the readiness probe to pass.
+// Call it when the initialization is done, i.e. the server is listening.
+func (h *Health) MarkStarted() {
+	h.started.Store(true)
+}
+
+// SetReady changes the readiness state. Call SetReady(false) at the beginning of shutdown sequence,
+// so the load balancer stops sending new traffic before the connections are drained.
+func (h *Health) SetReady(ready bool) {
+	h.notReady.Store(!ready)
+}
+
+// Check runs all checks of the probe concurrently and returns the report.
+func (h *Health) Check(ctx context.Context, probe Probe) Report {
+	report := Report{
+		Probe:  probe,
+		Status: StatusOK,
+	}
+
+	switch {
+	case probe == Startup && !h.started.Load():
+		report.Status, report.Reason = StatusFailing, "application is starting"
+	case probe == Readiness && !h.started.Load():
+		report.Status, report.Reason = StatusFailing, "application is starting"
+	case probe == Readiness && h.notReady.Load():
+		report.Status, report.Reason = StatusFailing, "application is shutting down"
+	}
+
+	checks := h.probeChecks(probe)
+	if len(checks) <= 0 {
+		return report
+	}
+
+	report.Checks = make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusOK {
+			continue
+		}
+
+		if result.Critical {
+			report.Status = StatusFailing
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func (h *Health) probeChecks(probe Probe) []*check {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	checks := make([]*check, 0, len(h.checks))
+	for _, c := range h.checks {
+		for _, p := range c.probes {
+			if p == probe {
+				checks = append(checks, c)
+				break
+			}
+		}
+	}
+
+	return checks
+}
+
+// run returns the cached result if still fresh, otherwise runs the check.
+func (c *check) run(ctx context.Context) CheckResult {
+	if c.cacheTTL <= 0 {
+		return c.exec(ctx)
+	}
+
+	c.runLock.Lock()
+	defer c.runLock.Unlock()
+
+	if c.last != nil && time.Since(c.last.CheckedAt) < c.cacheTTL {
+		result := *c.last
+		result.Cached = true
+		return result
+	}
+
+	// The cached result is shared by the later probes, so it must not depend on the caller context which may be
+	// canceled (i.e. the client disconnect), only on the check timeout.
+	result := c.exec(context.WithoutCancel(ctx))
+	c.last = &result
+	return result
+}
+
+// exec runs the check function within the timeout. The check which doesn't respect the context deadline
+// is reported as failing when the timeout reached, and left running in the background.
+func (c *check) exec(ctx context.Context) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+
+		done <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("health check timed out after %s: %w", c.timeout, ctx.Err())
+	}
+
+	result := CheckResult{
+		Name:      c.name,
+		Status:    StatusOK,
+		Critical:  c.critical,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+
+	return result
+}
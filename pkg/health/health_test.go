@@ -0,0 +1,185 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/health"
+)
+
+func okCheck(_ context.Context) error {
+	return nil
+}
+
+func failCheck(_ context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestHealth_Register(t *testing.T) {
+	h := health.New()
+
+	assert.Error(t, h.Register(" ", okCheck))
+	assert.Error(t, h.Register("db", nil))
+	assert.Error(t, h.Register("db", okCheck, health.CheckWithTimeout(0)))
+	assert.Error(t, h.Register("db", okCheck, health.CheckWithCacheTTL(-time.Second)))
+	assert.Error(t, h.Register("db", okCheck, health.CheckWithProbes()))
+	assert.Error(t, h.Register("db", okCheck, health.CheckWithProbes("unknown")))
+
+	require.NoError(t, h.Register("db", okCheck))
+	assert.Error(t, h.Register("db", okCheck))
+}
+
+func TestHealth_Check(t *testing.T) {
+	t.Run("not started", func(t *testing.T) {
+		h := health.New()
+
+		assert.True(t, h.Check(context.Background(), health.Liveness).Healthy())
+
+		report := h.Check(context.Background(), health.Readiness)
+		assert.Equal(t, health.StatusFailing, report.Status)
+		assert.Equal(t, "application is starting", report.Reason)
+
+		report = h.Check(context.Background(), health.Startup)
+		assert.Equal(t, health.StatusFailing, report.Status)
+
+		h.MarkStarted()
+		assert.Equal(t, health.StatusOK, h.Check(context.Background(), health.Readiness).Status)
+		assert.Equal(t, health.StatusOK, h.Check(context.Background(), health.Startup).Status)
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		h := health.New()
+		h.MarkStarted()
+		h.SetReady(false)
+
+		report := h.Check(context.Background(), health.Readiness)
+		assert.Equal(t, health.StatusFailing, report.Status)
+		assert.Equal(t, "application is shutting down", report.Reason)
+
+		// liveness is not affected, so the container is not restarted while draining the requests
+		assert.True(t, h.Check(context.Background(), health.Liveness).Healthy())
+
+		h.SetReady(true)
+		assert.True(t, h.Check(context.Background(), health.Readiness).Healthy())
+	})
+
+	t.Run("critical and non-critical checks", func(t *testing.T) {
+		h := health.New()
+		h.MarkStarted()
+
+		require.NoError(t, h.Register("db", okCheck))
+		require.NoError(t, h.Register("cache", failCheck, health.CheckWithCritical(false)))
+
+		report := h.Check(context.Background(), health.Readiness)
+		assert.Equal(t, health.StatusDegraded, report.Status)
+		assert.True(t, report.Healthy())
+		require.Len(t, report.Checks, 2)
+		assert.Equal(t, "db", report.Checks[0].Name)
+		assert.Equal(t, health.StatusOK, report.Checks[0].Status)
+		assert.Equal(t, "cache", report.Checks[1].Name)
+		assert.Equal(t, health.StatusFailing, report.Checks[1].Status)
+		assert.Equal(t, "connection refused", report.Checks[1].Error)
+
+		require.NoError(t, h.Register("queue", failCheck))
+		report = h.Check(context.Background(), health.Readiness)
+		assert.Equal(t, health.StatusFailing, report.Status)
+		assert.False(t, report.Healthy())
+	})
+
+	t.Run("check per probe", func(t *testing.T) {
+		h := health.New()
+		h.MarkStarted()
+
+		require.NoError(t, h.Register("db", failCheck))
+		require.NoError(t, h.Register("deadlock", okCheck, health.CheckWithProbes(health.Liveness)))
+		require.NoError(t, h.Register("migration", okCheck, health.CheckWithProbes(health.Startup, health.Readiness)))
+
+		report := h.Check(context.Background(), health.Liveness)
+		assert.Equal(t, health.StatusOK, report.Status)
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, "deadlock", report.Checks[0].Name)
+
+		report = h.Check(context.Background(), health.Startup)
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, "migration", report.Checks[0].Name)
+
+		report = h.Check(context.Background(), health.Readiness)
+		assert.Equal(t, health.StatusFailing, report.Status)
+		assert.Len(t, report.Checks, 2)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		h := health.New()
+		h.MarkStarted()
+
+		// this check ignores the context deadline
+		block := make(chan struct{})
+		defer close(block)
+
+		require.NoError(t, h.Register("slow", func(ctx context.Context) error {
+			<-block
+			return nil
+		}, health.CheckWithTimeout(10*time.Millisecond)))
+
+		report := h.Check(context.Background(), health.Readiness)
+		assert.Equal(t, health.StatusFailing, report.Status)
+		require.Len(t, report.Checks, 1)
+		assert.Contains(t, report.Checks[0].Error, "timed out")
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		h := health.New()
+		h.MarkStarted()
+
+		require.NoError(t, h.Register("panic", func(ctx context.Context) error {
+			panic("nil pointer")
+		}))
+
+		report := h.Check(context.Background(), health.Readiness)
+		assert.Equal(t, health.StatusFailing, report.Status)
+		assert.Equal(t, "panic: nil pointer", report.Checks[0].Error)
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		h := health.New()
+		h.MarkStarted()
+
+		var calls atomic.Int32
+		require.NoError(t, h.Register("db", func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		}, health.CheckWithCacheTTL(time.Hour)))
+
+		report := h.Check(context.Background(), health.Readiness)
+		assert.False(t, report.Checks[0].Cached)
+
+		report = h.Check(context.Background(), health.Readiness)
+		assert.True(t, report.Checks[0].Cached)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("cache is not affected by canceled probe", func(t *testing.T) {
+		h := health.New()
+		h.MarkStarted()
+
+		require.NoError(t, h.Register("db", func(ctx context.Context) error {
+			return ctx.Err()
+		}, health.CheckWithCacheTTL(time.Hour)))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report := h.Check(ctx, health.Readiness)
+		assert.Equal(t, health.StatusOK, report.Status)
+
+		report = h.Check(context.Background(), health.Readiness)
+		assert.True(t, report.Checks[0].Cached)
+		assert.Equal(t, health.StatusOK, report.Status)
+	})
+}
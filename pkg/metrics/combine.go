@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -96,9 +97,26 @@ func (c *Combine) HandlerFunc() http.HandlerFunc {
 }
 
 func (c *Combine) Close() error {
+	return c.CloseContext(context.Background())
+}
+
+// contextCloser is implemented by the metric that flushes to remote, such as OpenTelemetry.
+type contextCloser interface {
+	CloseContext(ctx context.Context) error
+}
+
+// CloseContext closes all metrics, the metric implementing CloseContext stops waiting when the context is done.
+func (c *Combine) CloseContext(ctx context.Context) error {
 	var err error
 	for _, metric := range c.metrics {
-		if _err := metric.Close(); _err != nil {
+		var _err error
+		if closer, ok := metric.(contextCloser); ok {
+			_err = closer.CloseContext(ctx)
+		} else {
+			_err = metric.Close()
+		}
+
+		if _err != nil {
 			err = errors.Join(err, _err)
 		}
 	}
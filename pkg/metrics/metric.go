@@ -35,7 +35,8 @@ type StatGaugeVec interface {
 }
 
 // StatTimer is a representation of a single timer metric stat, timing values
-// should be presented in nanoseconds for consistency. Interactions with this
+// should be presented in nanoseconds for consistency. The implementation converts
+// it into its own base unit (i.e. seconds for Prometheus). Interactions with this
 // stat are thread safe.
 type StatTimer interface {
 	// Timing sets a timing metric.
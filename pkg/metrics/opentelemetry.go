@@ -0,0 +1,265 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+type OpenTelemetryOpt func(*OpenTelemetry) error
+
+// OpenTelemetryWithPrefix set prefix for each metric
+func OpenTelemetryWithPrefix(prefix string) OpenTelemetryOpt {
+	return func(o *OpenTelemetry) error {
+		o.prefix = prefix
+		return nil
+	}
+}
+
+// OpenTelemetryWithResource set the resource describing this application.
+// Use the same resource as the tracer provider, so traces and metrics can be correlated in the backend.
+func OpenTelemetryWithResource(res *resource.Resource) OpenTelemetryOpt {
+	return func(o *OpenTelemetry) error {
+		if res == nil {
+			return fmt.Errorf("cannot use nil OpenTelemetry resource")
+		}
+
+		o.resource = res
+		return nil
+	}
+}
+
+// OpenTelemetryWithExporter push the metrics periodically using this exporter, i.e. OTLP exporter.
+// Can be called multiple times to push into multiple exporters.
+func OpenTelemetryWithExporter(exporter sdkmetric.Exporter) OpenTelemetryOpt {
+	return func(o *OpenTelemetry) error {
+		if exporter == nil {
+			return fmt.Errorf("cannot use nil OpenTelemetry metric exporter")
+		}
+
+		o.exporters = append(o.exporters, exporter)
+		return nil
+	}
+}
+
+// OpenTelemetryWithExportInterval set the interval of pushing metrics to the exporter. Default to 1 minute.
+func OpenTelemetryWithExportInterval(interval time.Duration) OpenTelemetryOpt {
+	return func(o *OpenTelemetry) error {
+		if interval <= 0 {
+			return fmt.Errorf("OpenTelemetry metric export interval must be positive")
+		}
+
+		o.exportInterval = interval
+		return nil
+	}
+}
+
+// OpenTelemetryWithReader add the metric reader as is, i.e. sdkmetric.NewManualReader for testing.
+func OpenTelemetryWithReader(reader sdkmetric.Reader) OpenTelemetryOpt {
+	return func(o *OpenTelemetry) error {
+		if reader == nil {
+			return fmt.Errorf("cannot use nil OpenTelemetry metric reader")
+		}
+
+		o.readers = append(o.readers, reader)
+		return nil
+	}
+}
+
+// OpenTelemetryWithHistogramOpts set the explicit bucket boundaries (in seconds) for all timer metrics.
+// Native histogram options are ignored. Default to prometheus.DefBuckets,
+// because OpenTelemetry default boundaries are meant for milliseconds.
+func OpenTelemetryWithHistogramOpts(opts HistogramOpts) OpenTelemetryOpt {
+	return func(o *OpenTelemetry) error {
+		if err := opts.validate(); err != nil {
+			return err
+		}
+
+		if len(opts.Buckets) > 0 {
+			o.buckets = opts.Buckets
+		}
+
+		return nil
+	}
+}
+
+// OpenTelemetry implements Metric using OpenTelemetry metrics SDK.
+// Counter is backed by Int64Counter, gauge by Int64UpDownCounter and timer by Float64Histogram in seconds.
+type OpenTelemetry struct {
+	prefix         string
+	resource       *resource.Resource
+	exporters      []sdkmetric.Exporter
+	exportInterval time.Duration
+	readers        []sdkmetric.Reader
+	buckets        []float64
+
+	provider *sdkmetric.MeterProvider
+	meter    metric.Meter
+
+	lock    sync.RWMutex
+	counter map[string]*otelCounterVec
+	gauge   map[string]*otelGaugeVec
+	timer   map[string]*otelTimerVec
+}
+
+var _ Metric = (*OpenTelemetry)(nil)
+
+func NewOpenTelemetry(opts ...OpenTelemetryOpt) (*OpenTelemetry, error) {
+	o := &OpenTelemetry{
+		prefix:         "",
+		resource:       resource.Default(),
+		exporters:      make([]sdkmetric.Exporter, 0),
+		exportInterval: time.Minute,
+		readers:        make([]sdkmetric.Reader, 0),
+		buckets:        prometheus.DefBuckets,
+
+		lock:    sync.RWMutex{},
+		counter: make(map[string]*otelCounterVec),
+		gauge:   make(map[string]*otelGaugeVec),
+		timer:   make(map[string]*otelTimerVec),
+	}
+
+	for _, opt := range opts {
+		err := opt(o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	providerOpts := []sdkmetric.Option{
+		sdkmetric.WithResource(o.resource),
+	}
+
+	for _, exporter := range o.exporters {
+		providerOpts = append(providerOpts, sdkmetric.WithReader(
+			sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(o.exportInterval)),
+		))
+	}
+
+	for _, reader := range o.readers {
+		providerOpts = append(providerOpts, sdkmetric.WithReader(reader))
+	}
+
+	o.provider = sdkmetric.NewMeterProvider(providerOpts...)
+	o.meter = o.provider.Meter("github.com/yusufsyaifudin/go-project-structure/pkg/metrics")
+	return o, nil
+}
+
+func (o *OpenTelemetry) GetCounterVec(name string, labelNames ...string) StatCounterVec {
+	o.lock.RLock()
+	counter, exist := o.counter[name]
+	if exist && counter != nil {
+		o.lock.RUnlock()
+		if !cmp.Equal(labelNames, counter.registeredLabels) {
+			panic(fmt.Errorf("counter vector name '%s' already registered: mismatch labels: %s vs %s", name, labelNames, counter.registeredLabels))
+		}
+
+		return counter
+	}
+	o.lock.RUnlock()
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	instrument, err := o.meter.Int64Counter(o.prefix+name,
+		metric.WithDescription(fmt.Sprintf("%s counter metric", name)),
+	)
+	if err != nil {
+		panic(fmt.Errorf("cannot create counter '%s': %w", name, err))
+	}
+
+	c := &otelCounterVec{
+		counter:          instrument,
+		registeredLabels: labelNames,
+	}
+
+	o.counter[name] = c
+	return c
+}
+
+func (o *OpenTelemetry) GetGaugeVec(name string, labelNames ...string) StatGaugeVec {
+	o.lock.RLock()
+	gauge, exist := o.gauge[name]
+	if exist && gauge != nil {
+		o.lock.RUnlock()
+		if !cmp.Equal(labelNames, gauge.registeredLabels) {
+			panic(fmt.Errorf("gauge vector name '%s' already registered: mismatch labels: %s vs %s", name, labelNames, gauge.registeredLabels))
+		}
+
+		return gauge
+	}
+	o.lock.RUnlock()
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	instrument, err := o.meter.Int64UpDownCounter(o.prefix+name,
+		metric.WithDescription(fmt.Sprintf("%s gauge metric", name)),
+	)
+	if err != nil {
+		panic(fmt.Errorf("cannot create gauge '%s': %w", name, err))
+	}
+
+	g := &otelGaugeVec{
+		gauge:            instrument,
+		registeredLabels: labelNames,
+	}
+
+	o.gauge[name] = g
+	return g
+}
+
+func (o *OpenTelemetry) GetTimerVec(name string, labelNames ...string) StatTimerVec {
+	o.lock.RLock()
+	timer, exist := o.timer[name]
+	if exist && timer != nil {
+		o.lock.RUnlock()
+		if !cmp.Equal(labelNames, timer.registeredLabels) {
+			panic(fmt.Errorf("timer vector name '%s' already registered: mismatch labels: %s vs %s", name, labelNames, timer.registeredLabels))
+		}
+
+		return timer
+	}
+	o.lock.RUnlock()
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	instrument, err := o.meter.Float64Histogram(o.prefix+name,
+		metric.WithDescription(fmt.Sprintf("%s timer metric in seconds", name)),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(o.buckets...),
+	)
+	if err != nil {
+		panic(fmt.Errorf("cannot create timer '%s': %w", name, err))
+	}
+
+	t := &otelTimerVec{
+		timing:           instrument,
+		registeredLabels: labelNames,
+	}
+
+	o.timer[name] = t
+	return t
+}
+
+// HandlerFunc returns nil since the metrics are pushed to the exporter.
+func (o *OpenTelemetry) HandlerFunc() http.HandlerFunc {
+	return nil
+}
+
+// Close flushes the remaining metrics to the exporters, then shutdown the meter provider.
+// It may block as long as the exporter retries, use CloseContext to bound the flush.
+func (o *OpenTelemetry) Close() error {
+	return o.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but stops waiting for the exporters when the context is done.
+func (o *OpenTelemetry) CloseContext(ctx context.Context) error {
+	return o.provider.Shutdown(ctx)
+}
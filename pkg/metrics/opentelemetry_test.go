@@ -0,0 +1,145 @@
+package metrics_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+)
+
+// collect reads all metrics from the manual reader, indexed by the metric name.
+func collect(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Metrics {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	out := make(map[string]metricdata.Metrics)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			out[m.Name] = m
+		}
+	}
+
+	return out
+}
+
+func TestNewOpenTelemetry(t *testing.T) {
+	opts := []metrics.OpenTelemetryOpt{
+		metrics.OpenTelemetryWithResource(nil),
+		metrics.OpenTelemetryWithExporter(nil),
+		metrics.OpenTelemetryWithReader(nil),
+		metrics.OpenTelemetryWithExportInterval(0),
+		metrics.OpenTelemetryWithHistogramOpts(metrics.HistogramOpts{Buckets: []float64{1, 0.5}}),
+	}
+
+	for _, opt := range opts {
+		m, err := metrics.NewOpenTelemetry(opt)
+		assert.Nil(t, m)
+		assert.Error(t, err)
+	}
+}
+
+func TestOpenTelemetry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	res := resource.NewSchemaless(attribute.String("service.name", "app"))
+
+	m, err := metrics.NewOpenTelemetry(
+		metrics.OpenTelemetryWithPrefix("app_"),
+		metrics.OpenTelemetryWithResource(res),
+		metrics.OpenTelemetryWithReader(reader),
+		metrics.OpenTelemetryWithHistogramOpts(metrics.HistogramOpts{
+			Buckets: []float64{0.1, 1},
+		}),
+	)
+	require.NoError(t, err)
+	assert.Nil(t, m.HandlerFunc())
+
+	m.GetCounterVec("requests_total", "code").WithValues("200").Incr(2)
+	m.GetCounterVec("requests_total", "code").WithValues("200").Incr(1)
+
+	gauge := m.GetGaugeVec("in_flight", "path")
+	gauge.WithValues("/a").Set(5)
+	gauge.WithValues("/a").Decr(2)
+	gauge.WithValues("/a").Set(10)
+	gauge.WithValues("/b").Incr(1)
+
+	m.GetTimerVec("latency_seconds", "code").WithValues("200").Timing((500 * time.Millisecond).Nanoseconds())
+
+	assert.Panics(t, func() {
+		m.GetCounterVec("requests_total", "method")
+	})
+
+	out := collect(t, reader)
+
+	counter, ok := out["app_requests_total"].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	assert.True(t, counter.IsMonotonic)
+	require.Len(t, counter.DataPoints, 1)
+	assert.Equal(t, int64(3), counter.DataPoints[0].Value)
+	assert.Equal(t, attribute.NewSet(attribute.String("code", "200")), counter.DataPoints[0].Attributes)
+
+	upDown, ok := out["app_in_flight"].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	assert.False(t, upDown.IsMonotonic)
+	values := make(map[string]int64)
+	for _, dp := range upDown.DataPoints {
+		path, _ := dp.Attributes.Value("path")
+		values[path.AsString()] = dp.Value
+	}
+	assert.Equal(t, map[string]int64{"/a": 10, "/b": 1}, values)
+
+	timer := out["app_latency_seconds"]
+	assert.Equal(t, "s", timer.Unit)
+	histogram, ok := timer.Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, histogram.DataPoints, 1)
+	assert.Equal(t, []float64{0.1, 1}, histogram.DataPoints[0].Bounds)
+	assert.Equal(t, []uint64{0, 1, 0}, histogram.DataPoints[0].BucketCounts)
+	assert.Equal(t, 0.5, histogram.DataPoints[0].Sum)
+
+	assert.NoError(t, m.Close())
+}
+
+// blockingExporter blocks the export until the context is done, like the exporter retrying the unreachable collector.
+type blockingExporter struct {
+	sdkmetric.Exporter
+}
+
+func (b *blockingExporter) Export(ctx context.Context, _ *metricdata.ResourceMetrics) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *blockingExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestOpenTelemetry_CloseContext(t *testing.T) {
+	exporter, err := stdoutmetric.New()
+	require.NoError(t, err)
+
+	m, err := metrics.NewOpenTelemetry(
+		metrics.OpenTelemetryWithExporter(&blockingExporter{Exporter: exporter}),
+		metrics.OpenTelemetryWithExportInterval(time.Hour),
+	)
+	require.NoError(t, err)
+
+	m.GetCounterVec("requests_total").WithValues().Incr(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	assert.Error(t, m.CloseContext(ctx))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
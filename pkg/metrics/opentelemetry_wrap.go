@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// otelAttributes pairs the label names with values as OpenTelemetry attributes.
+func otelAttributes(labelNames, labelValues []string) metric.MeasurementOption {
+	n := min(len(labelNames), len(labelValues))
+	attrs := make([]attribute.KeyValue, 0, n)
+	for i := 0; i < n; i++ {
+		attrs = append(attrs, attribute.String(labelNames[i], labelValues[i]))
+	}
+
+	return metric.WithAttributeSet(attribute.NewSet(attrs...))
+}
+
+type otelCounterVec struct {
+	counter          metric.Int64Counter
+	registeredLabels []string
+}
+
+var _ StatCounterVec = (*otelCounterVec)(nil)
+
+func (o *otelCounterVec) WithValues(labelValues ...string) StatCounter {
+	return &otelCounter{
+		counter: o.counter,
+		attrs:   otelAttributes(o.registeredLabels, labelValues),
+	}
+}
+
+type otelCounter struct {
+	counter metric.Int64Counter
+	attrs   metric.MeasurementOption
+}
+
+var _ StatCounter = (*otelCounter)(nil)
+
+func (o *otelCounter) Incr(count int64) {
+	o.counter.Add(context.Background(), count, o.attrs)
+}
+
+type otelGaugeVec struct {
+	gauge            metric.Int64UpDownCounter
+	registeredLabels []string
+
+	// values holds the current value of each series, so Set can be translated into the delta of up-down counter.
+	values sync.Map
+}
+
+var _ StatGaugeVec = (*otelGaugeVec)(nil)
+
+func (o *otelGaugeVec) WithValues(labelValues ...string) StatGauge {
+	value, _ := o.values.LoadOrStore(strings.Join(labelValues, "\xff"), &atomic.Int64{})
+	return &otelGauge{
+		gauge: o.gauge,
+		attrs: otelAttributes(o.registeredLabels, labelValues),
+		value: value.(*atomic.Int64),
+	}
+}
+
+type otelGauge struct {
+	gauge metric.Int64UpDownCounter
+	attrs metric.MeasurementOption
+	value *atomic.Int64
+}
+
+var _ StatGauge = (*otelGauge)(nil)
+
+func (o *otelGauge) Set(value int64) {
+	old := o.value.Swap(value)
+	o.gauge.Add(context.Background(), value-old, o.attrs)
+}
+
+func (o *otelGauge) Incr(count int64) {
+	o.value.Add(count)
+	o.gauge.Add(context.Background(), count, o.attrs)
+}
+
+func (o *otelGauge) Decr(count int64) {
+	o.value.Add(-count)
+	o.gauge.Add(context.Background(), -count, o.attrs)
+}
+
+type otelTimerVec struct {
+	timing           metric.Float64Histogram
+	registeredLabels []string
+}
+
+var _ StatTimerVec = (*otelTimerVec)(nil)
+
+func (o *otelTimerVec) WithValues(labelValues ...string) StatTimer {
+	return &otelTimer{
+		timing: o.timing,
+		attrs:  otelAttributes(o.registeredLabels, labelValues),
+	}
+}
+
+type otelTimer struct {
+	timing metric.Float64Histogram
+	attrs  metric.MeasurementOption
+}
+
+var _ StatTimer = (*otelTimer)(nil)
+
+// Timing receives delta in nanoseconds, then records it in seconds.
+func (o *otelTimer) Timing(delta int64) {
+	o.timing.Record(context.Background(), time.Duration(delta).Seconds(), o.attrs)
+}
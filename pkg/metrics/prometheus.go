@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/prometheus/client_golang/prometheus"
@@ -21,11 +22,68 @@ func PrometheusWithPrefix(prefix string) PrometheusOpt {
 	}
 }
 
+// HistogramOpts configures the histogram that backs the timer metric. The observed value is in seconds.
+type HistogramOpts struct {
+	// Buckets is the upper bounds (in seconds) of classic histogram buckets.
+	// Default to prometheus.DefBuckets when empty.
+	Buckets []float64
+
+	// NativeHistogramBucketFactor enables Prometheus native histogram when greater than 1, i.e: 1.1.
+	// The classic buckets are still exposed, so it is safe for the scraper that doesn't support native histogram.
+	NativeHistogramBucketFactor float64
+
+	// NativeHistogramMaxBucketNumber limits the number of native histogram buckets.
+	// Default to 160 when native histogram is enabled.
+	NativeHistogramMaxBucketNumber uint32
+}
+
+func (h HistogramOpts) validate() error {
+	for i := 1; i < len(h.Buckets); i++ {
+		if h.Buckets[i] <= h.Buckets[i-1] {
+			return fmt.Errorf("histogram buckets must be in increasing order: %v", h.Buckets)
+		}
+	}
+
+	if h.NativeHistogramBucketFactor != 0 && h.NativeHistogramBucketFactor <= 1 {
+		return fmt.Errorf("native histogram bucket factor must be greater than 1, got %v", h.NativeHistogramBucketFactor)
+	}
+
+	return nil
+}
+
+// PrometheusWithHistogramOpts set the default histogram options for all timer metrics.
+func PrometheusWithHistogramOpts(opts HistogramOpts) PrometheusOpt {
+	return func(p *Prometheus) error {
+		if err := opts.validate(); err != nil {
+			return err
+		}
+
+		p.histogramOpts = opts
+		return nil
+	}
+}
+
+// PrometheusWithTimerHistogramOpts set the histogram options for specific timer metric name (without prefix).
+// It overrides the options set by PrometheusWithHistogramOpts.
+func PrometheusWithTimerHistogramOpts(name string, opts HistogramOpts) PrometheusOpt {
+	return func(p *Prometheus) error {
+		if err := opts.validate(); err != nil {
+			return fmt.Errorf("timer '%s': %w", name, err)
+		}
+
+		p.timerHistogramOpts[name] = opts
+		return nil
+	}
+}
+
 type Prometheus struct {
 	prefix     string
 	registerer prometheus.Registerer
 	gatherer   prometheus.Gatherer
 
+	histogramOpts      HistogramOpts
+	timerHistogramOpts map[string]HistogramOpts
+
 	lock    sync.RWMutex
 	counter map[string]*promCounterVec
 	gauge   map[string]*promGaugeVec
@@ -41,10 +99,14 @@ func NewPrometheus(opts ...PrometheusOpt) (*Prometheus, error) {
 		prefix:     "",
 		registerer: registry,
 		gatherer:   registry,
-		lock:       sync.RWMutex{},
-		counter:    make(map[string]*promCounterVec),
-		gauge:      make(map[string]*promGaugeVec),
-		timer:      make(map[string]*promTimerVec),
+
+		histogramOpts:      HistogramOpts{},
+		timerHistogramOpts: make(map[string]HistogramOpts),
+
+		lock:    sync.RWMutex{},
+		counter: make(map[string]*promCounterVec),
+		gauge:   make(map[string]*promGaugeVec),
+		timer:   make(map[string]*promTimerVec),
 	}
 
 	for _, opt := range opts {
@@ -134,24 +196,42 @@ func (p *Prometheus) GetGaugeVec(name string, labelNames ...string) StatGaugeVec
 
 func (p *Prometheus) GetTimerVec(name string, labelNames ...string) StatTimerVec {
 	p.lock.RLock()
-	summary, exist := p.timer[name]
-	if exist && summary != nil {
+	timer, exist := p.timer[name]
+	if exist && timer != nil {
 		p.lock.RUnlock()
-		if !cmp.Equal(labelNames, summary.registeredLabels) {
-			panic(fmt.Errorf("timer vector name '%s' already registered: mismatch labels: %s vs %s", name, labelNames, summary.registeredLabels))
+		if !cmp.Equal(labelNames, timer.registeredLabels) {
+			panic(fmt.Errorf("timer vector name '%s' already registered: mismatch labels: %s vs %s", name, labelNames, timer.registeredLabels))
 		}
 
-		return summary
+		return timer
 	}
 	p.lock.RUnlock()
 
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	timerVec := prometheus.NewSummaryVec(prometheus.SummaryOpts{
-		Name:       name,
-		Help:       fmt.Sprintf("%s timer metric", name),
-		Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
-	}, labelNames)
+	histogramOpts, exist := p.timerHistogramOpts[name]
+	if !exist {
+		histogramOpts = p.histogramOpts
+	}
+
+	// Histogram is used instead of summary, because it can be aggregated across instances in PromQL.
+	promHistogramOpts := prometheus.HistogramOpts{
+		Name:    name,
+		Help:    fmt.Sprintf("%s timer metric in seconds", name),
+		Buckets: histogramOpts.Buckets,
+	}
+
+	if histogramOpts.NativeHistogramBucketFactor > 1 {
+		promHistogramOpts.NativeHistogramBucketFactor = histogramOpts.NativeHistogramBucketFactor
+		promHistogramOpts.NativeHistogramMaxBucketNumber = histogramOpts.NativeHistogramMaxBucketNumber
+		if promHistogramOpts.NativeHistogramMaxBucketNumber == 0 {
+			promHistogramOpts.NativeHistogramMaxBucketNumber = 160
+		}
+
+		promHistogramOpts.NativeHistogramMinResetDuration = time.Hour
+	}
+
+	timerVec := prometheus.NewHistogramVec(promHistogramOpts, labelNames)
 
 	p.registerer.MustRegister(timerVec)
 
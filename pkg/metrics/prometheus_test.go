@@ -0,0 +1,85 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+)
+
+func scrape(t *testing.T, m metrics.Metric) string {
+	t.Helper()
+
+	resp := httptest.NewRecorder()
+	m.HandlerFunc().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	return resp.Body.String()
+}
+
+func TestPrometheusWithHistogramOpts(t *testing.T) {
+	t.Run("unordered buckets", func(t *testing.T) {
+		_, err := metrics.NewPrometheus(metrics.PrometheusWithHistogramOpts(metrics.HistogramOpts{
+			Buckets: []float64{1, 0.5},
+		}))
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid native histogram factor", func(t *testing.T) {
+		_, err := metrics.NewPrometheus(metrics.PrometheusWithTimerHistogramOpts("foo", metrics.HistogramOpts{
+			NativeHistogramBucketFactor: 0.5,
+		}))
+		assert.Error(t, err)
+	})
+}
+
+func TestPrometheus_GetTimerVec(t *testing.T) {
+	t.Run("histogram in seconds", func(t *testing.T) {
+		m, err := metrics.NewPrometheus(
+			metrics.PrometheusWithPrefix("app_"),
+			metrics.PrometheusWithHistogramOpts(metrics.HistogramOpts{
+				Buckets: []float64{0.1, 1},
+			}),
+		)
+		require.NoError(t, err)
+
+		m.GetTimerVec("latency_seconds", "code").WithValues("200").Timing((500 * time.Millisecond).Nanoseconds())
+
+		out := scrape(t, m)
+		assert.Contains(t, out, "# TYPE app_latency_seconds histogram")
+		assert.Contains(t, out, `app_latency_seconds_bucket{code="200",le="0.1"} 0`)
+		assert.Contains(t, out, `app_latency_seconds_bucket{code="200",le="1"} 1`)
+		assert.Contains(t, out, `app_latency_seconds_sum{code="200"} 0.5`)
+	})
+
+	t.Run("per metric buckets", func(t *testing.T) {
+		m, err := metrics.NewPrometheus(
+			metrics.PrometheusWithTimerHistogramOpts("slow_seconds", metrics.HistogramOpts{
+				Buckets: []float64{10, 60},
+			}),
+		)
+		require.NoError(t, err)
+
+		m.GetTimerVec("slow_seconds").WithValues().Timing((30 * time.Second).Nanoseconds())
+		m.GetTimerVec("fast_seconds").WithValues().Timing(time.Millisecond.Nanoseconds())
+
+		out := scrape(t, m)
+		assert.Contains(t, out, `slow_seconds_bucket{le="10"} 0`)
+		assert.Contains(t, out, `slow_seconds_bucket{le="60"} 1`)
+		assert.Contains(t, out, `fast_seconds_bucket{le="0.005"} 1`) // default buckets
+	})
+
+	t.Run("mismatch labels", func(t *testing.T) {
+		m, err := metrics.NewPrometheus()
+		require.NoError(t, err)
+
+		m.GetTimerVec("foo_seconds", "a")
+		assert.Panics(t, func() {
+			m.GetTimerVec("foo_seconds", "b")
+		})
+	})
+}
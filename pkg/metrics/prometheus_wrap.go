@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -60,7 +62,7 @@ func (p *promGauge) Decr(count int64) {
 }
 
 type promTimerVec struct {
-	timing           *prometheus.SummaryVec
+	timing           *prometheus.HistogramVec
 	registeredLabels []string
 }
 
@@ -78,6 +80,7 @@ type promTimer struct {
 
 var _ StatTimer = (*promTimer)(nil)
 
+// Timing receives delta in nanoseconds, then observe it in seconds as the Prometheus base unit.
 func (p *promTimer) Timing(delta int64) {
-	p.timing.Observe(float64(delta))
+	p.timing.Observe(time.Duration(delta).Seconds())
 }
@@ -0,0 +1,303 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsdTagFormat is how the label names and values are sent into the StatsD server.
+type StatsdTagFormat string
+
+const (
+	// StatsdTagNone appends the label values into metric name separated by dot, i.e: http_requests_total.200.GET
+	// This is for the vanilla StatsD server that doesn't support tags.
+	StatsdTagNone StatsdTagFormat = "NONE"
+
+	// StatsdTagDogStatsD sends the label as DogStatsD tags, i.e: http_requests_total:1|c|#code:200,method:GET
+	StatsdTagDogStatsD StatsdTagFormat = "DOGSTATSD"
+)
+
+type StatsdOpt func(*Statsd) error
+
+// StatsdWithAddress set the StatsD server UDP address. Default to localhost:8125
+func StatsdWithAddress(address string) StatsdOpt {
+	return func(s *Statsd) error {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			return fmt.Errorf("statsd address cannot be empty")
+		}
+
+		s.address = address
+		return nil
+	}
+}
+
+// StatsdWithPrefix set prefix for each metric
+func StatsdWithPrefix(prefix string) StatsdOpt {
+	return func(s *Statsd) error {
+		s.prefix = prefix
+		return nil
+	}
+}
+
+// StatsdWithTagFormat set how the labels are sent. Default to StatsdTagDogStatsD.
+func StatsdWithTagFormat(format StatsdTagFormat) StatsdOpt {
+	return func(s *Statsd) error {
+		format = StatsdTagFormat(strings.ToUpper(strings.TrimSpace(string(format))))
+		switch format {
+		case StatsdTagNone, StatsdTagDogStatsD:
+			s.tagFormat = format
+			return nil
+		case "":
+			s.tagFormat = StatsdTagDogStatsD
+			return nil
+		default:
+			return fmt.Errorf("unknown statsd tag format '%s'", format)
+		}
+	}
+}
+
+// StatsdWithFlushInterval set the interval to send the buffered metrics.
+// Zero means no client-side buffering: each metric is sent immediately in its own packet.
+// Default to 1 second.
+func StatsdWithFlushInterval(interval time.Duration) StatsdOpt {
+	return func(s *Statsd) error {
+		if interval < 0 {
+			return fmt.Errorf("statsd flush interval cannot be negative")
+		}
+
+		s.flushInterval = interval
+		return nil
+	}
+}
+
+// StatsdWithMaxPacketSize set the maximum size of UDP packet in bytes, the buffer is flushed before exceeding this.
+// Default to 1432 which is safe for most network (Ethernet MTU minus IP and UDP header).
+func StatsdWithMaxPacketSize(size int) StatsdOpt {
+	return func(s *Statsd) error {
+		if size <= 0 {
+			return fmt.Errorf("statsd max packet size must be positive")
+		}
+
+		s.maxPacketSize = size
+		return nil
+	}
+}
+
+// Statsd implements Metric by pushing counters, gauges and timers to StatsD server over UDP.
+// Metrics are buffered in client-side and sent as multi-metric packets every flush interval,
+// or earlier when the buffer reach the max packet size.
+type Statsd struct {
+	address       string
+	prefix        string
+	tagFormat     StatsdTagFormat
+	flushInterval time.Duration
+	maxPacketSize int
+
+	conn net.Conn
+
+	lock   sync.Mutex
+	buf    []byte
+	closed bool
+
+	closeChan chan struct{}
+	wg        sync.WaitGroup
+}
+
+var _ Metric = (*Statsd)(nil)
+
+func NewStatsd(opts ...StatsdOpt) (*Statsd, error) {
+	s := &Statsd{
+		address:       "localhost:8125",
+		prefix:        "",
+		tagFormat:     StatsdTagDogStatsD,
+		flushInterval: time.Second,
+		maxPacketSize: 1432,
+		closeChan:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		err := opt(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := net.Dial("udp", s.address)
+	if err != nil {
+		return nil, fmt.Errorf("statsd: cannot dial udp %s: %w", s.address, err)
+	}
+
+	s.conn = conn
+	s.buf = make([]byte, 0, s.maxPacketSize)
+
+	if s.flushInterval > 0 {
+		s.wg.Add(1)
+		go s.flushLoop()
+	}
+
+	return s, nil
+}
+
+func (s *Statsd) GetCounterVec(name string, labelNames ...string) StatCounterVec {
+	return &statsdCounterVec{
+		statsdVec: s.newVec(name, labelNames),
+	}
+}
+
+func (s *Statsd) GetGaugeVec(name string, labelNames ...string) StatGaugeVec {
+	return &statsdGaugeVec{
+		statsdVec: s.newVec(name, labelNames),
+	}
+}
+
+func (s *Statsd) GetTimerVec(name string, labelNames ...string) StatTimerVec {
+	return &statsdTimerVec{
+		statsdVec: s.newVec(name, labelNames),
+	}
+}
+
+// HandlerFunc returns nil since StatsD is push based.
+func (s *Statsd) HandlerFunc() http.HandlerFunc {
+	return nil
+}
+
+// Close stops the flush loop, sends the remaining buffered metrics and closes the UDP connection.
+func (s *Statsd) Close() error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil
+	}
+
+	s.closed = true
+	s.lock.Unlock()
+
+	close(s.closeChan)
+	s.wg.Wait()
+
+	s.lock.Lock()
+	err := s.flushLocked()
+	s.lock.Unlock()
+
+	if _err := s.conn.Close(); _err != nil {
+		err = errors.Join(err, _err)
+	}
+
+	return err
+}
+
+func (s *Statsd) flushLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.closeChan:
+			return
+		case <-ticker.C:
+			s.lock.Lock()
+			_ = s.flushLocked() // UDP is fire and forget, there is nowhere to report the error
+			s.lock.Unlock()
+		}
+	}
+}
+
+// write appends one metric line into the buffer, flushing first if the line doesn't fit in the packet.
+func (s *Statsd) write(line string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	if len(s.buf) > 0 && len(s.buf)+1+len(line) > s.maxPacketSize {
+		_ = s.flushLocked()
+	}
+
+	if len(s.buf) > 0 {
+		s.buf = append(s.buf, '\n')
+	}
+	s.buf = append(s.buf, line...)
+
+	if s.flushInterval <= 0 {
+		_ = s.flushLocked()
+	}
+}
+
+func (s *Statsd) flushLocked() error {
+	if len(s.buf) <= 0 {
+		return nil
+	}
+
+	_, err := s.conn.Write(s.buf)
+	s.buf = s.buf[:0]
+	if err != nil {
+		return fmt.Errorf("statsd: cannot send metrics: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Statsd) newVec(name string, labelNames []string) *statsdVec {
+	return &statsdVec{
+		statsd:     s,
+		name:       sanitizeStatsd(s.prefix + name),
+		labelNames: labelNames,
+	}
+}
+
+// statsdVec holds the metric name and label names, then resolves the metric key when label values are given.
+type statsdVec struct {
+	statsd     *Statsd
+	name       string
+	labelNames []string
+}
+
+// key returns the metric name and the tag suffix (including the "|#" separator) for the label values.
+func (v *statsdVec) key(labelValues []string) (name string, tags string) {
+	n := min(len(v.labelNames), len(labelValues))
+	if n <= 0 {
+		return v.name, ""
+	}
+
+	if v.statsd.tagFormat == StatsdTagNone {
+		parts := make([]string, 0, n+1)
+		parts = append(parts, v.name)
+		for i := 0; i < n; i++ {
+			parts = append(parts, sanitizeStatsd(labelValues[i]))
+		}
+
+		return strings.Join(parts, "."), ""
+	}
+
+	pairs := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		pairs = append(pairs, sanitizeStatsd(v.labelNames[i])+":"+sanitizeStatsd(labelValues[i]))
+	}
+
+	return v.name, "|#" + strings.Join(pairs, ",")
+}
+
+// statsdReplacer replaces characters that have special meaning in StatsD line protocol.
+var statsdReplacer = strings.NewReplacer(
+	":", "_",
+	"|", "_",
+	"@", "_",
+	"#", "_",
+	",", "_",
+	" ", "_",
+	"\n", "_",
+)
+
+func sanitizeStatsd(s string) string {
+	return statsdReplacer.Replace(s)
+}
@@ -0,0 +1,153 @@
+package metrics_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/metrics"
+)
+
+// listenUDP starts local UDP listener and returns the function to read all received metric lines.
+func listenUDP(t *testing.T) (string, func() []string) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	read := func() []string {
+		lines := make([]string, 0)
+		buf := make([]byte, 65535)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return lines
+			}
+
+			lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+		}
+	}
+
+	return conn.LocalAddr().String(), read
+}
+
+func TestNewStatsd(t *testing.T) {
+	t.Run("invalid options", func(t *testing.T) {
+		opts := []metrics.StatsdOpt{
+			metrics.StatsdWithAddress(""),
+			metrics.StatsdWithTagFormat("influx"),
+			metrics.StatsdWithFlushInterval(-time.Second),
+			metrics.StatsdWithMaxPacketSize(0),
+		}
+
+		for _, opt := range opts {
+			s, err := metrics.NewStatsd(opt)
+			assert.Nil(t, s)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("no handler func", func(t *testing.T) {
+		s, err := metrics.NewStatsd()
+		require.NoError(t, err)
+		assert.Nil(t, s.HandlerFunc())
+		assert.NoError(t, s.Close())
+		assert.NoError(t, s.Close()) // closing twice is safe
+	})
+}
+
+func TestStatsd_DogStatsD(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	s, err := metrics.NewStatsd(
+		metrics.StatsdWithAddress(addr),
+		metrics.StatsdWithPrefix("app."),
+		metrics.StatsdWithFlushInterval(time.Hour), // only flushed on Close
+	)
+	require.NoError(t, err)
+
+	s.GetCounterVec("http_requests_total", "code", "path").WithValues("200", "/users/:id").Incr(2)
+	gauge := s.GetGaugeVec("in_flight").WithValues()
+	gauge.Set(-3)
+	gauge.Incr(1)
+	gauge.Decr(2)
+	gauge.Incr(-3)
+	gauge.Decr(-4)
+	s.GetTimerVec("latency", "code").WithValues("500").Timing((1500 * time.Microsecond).Nanoseconds())
+
+	// nothing sent before flush
+	assert.Empty(t, read())
+
+	require.NoError(t, s.Close())
+	assert.Equal(t, []string{
+		"app.http_requests_total:2|c|#code:200,path:/users/_id",
+		"app.in_flight:0|g",
+		"app.in_flight:-3|g",
+		"app.in_flight:+1|g",
+		"app.in_flight:-2|g",
+		"app.in_flight:-3|g",
+		"app.in_flight:+4|g",
+		"app.latency:1.5|ms|#code:500",
+	}, read())
+}
+
+func TestStatsd_TagNone(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	s, err := metrics.NewStatsd(
+		metrics.StatsdWithAddress(addr),
+		metrics.StatsdWithTagFormat(metrics.StatsdTagNone),
+		metrics.StatsdWithFlushInterval(0), // unbuffered
+	)
+	require.NoError(t, err)
+	defer func() {
+		_ = s.Close()
+	}()
+
+	s.GetCounterVec("requests", "code", "method").WithValues("200", "GET").Incr(1)
+	assert.Equal(t, []string{"requests.200.GET:1|c"}, read())
+}
+
+func TestStatsd_FlushInterval(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	s, err := metrics.NewStatsd(
+		metrics.StatsdWithAddress(addr),
+		metrics.StatsdWithFlushInterval(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer func() {
+		_ = s.Close()
+	}()
+
+	s.GetCounterVec("requests").WithValues().Incr(1)
+	assert.Equal(t, []string{"requests:1|c"}, read())
+}
+
+func TestStatsd_MaxPacketSize(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	s, err := metrics.NewStatsd(
+		metrics.StatsdWithAddress(addr),
+		metrics.StatsdWithFlushInterval(time.Hour),
+		metrics.StatsdWithMaxPacketSize(32),
+	)
+	require.NoError(t, err)
+
+	counter := s.GetCounterVec("requests_total").WithValues()
+	for i := 0; i < 5; i++ {
+		counter.Incr(1)
+	}
+
+	// buffer exceeds 32 bytes, so some packets must be sent before Close
+	assert.NotEmpty(t, read())
+	require.NoError(t, s.Close())
+}
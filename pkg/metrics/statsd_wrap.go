@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"strconv"
+)
+
+type statsdCounterVec struct {
+	*statsdVec
+}
+
+var _ StatCounterVec = (*statsdCounterVec)(nil)
+
+func (s *statsdCounterVec) WithValues(labelValues ...string) StatCounter {
+	name, tags := s.key(labelValues)
+	return &statsdCounter{
+		statsd: s.statsd,
+		name:   name,
+		tags:   tags,
+	}
+}
+
+type statsdCounter struct {
+	statsd *Statsd
+	name   string
+	tags   string
+}
+
+var _ StatCounter = (*statsdCounter)(nil)
+
+func (s *statsdCounter) Incr(count int64) {
+	s.statsd.write(s.name + ":" + strconv.FormatInt(count, 10) + "|c" + s.tags)
+}
+
+type statsdGaugeVec struct {
+	*statsdVec
+}
+
+var _ StatGaugeVec = (*statsdGaugeVec)(nil)
+
+func (s *statsdGaugeVec) WithValues(labelValues ...string) StatGauge {
+	name, tags := s.key(labelValues)
+	return &statsdGauge{
+		statsd: s.statsd,
+		name:   name,
+		tags:   tags,
+	}
+}
+
+type statsdGauge struct {
+	statsd *Statsd
+	name   string
+	tags   string
+}
+
+var _ StatGauge = (*statsdGauge)(nil)
+
+func (s *statsdGauge) Set(value int64) {
+	// In StatsD protocol, signed gauge value means relative change.
+	// To set negative value, the gauge must be reset to zero first.
+	if value < 0 {
+		s.statsd.write(s.name + ":0|g" + s.tags)
+	}
+
+	s.statsd.write(s.name + ":" + strconv.FormatInt(value, 10) + "|g" + s.tags)
+}
+
+func (s *statsdGauge) Incr(count int64) {
+	// The sign is always written, because the unsigned value sets the gauge instead of changing it.
+	delta := "+" + strconv.FormatInt(count, 10)
+	if count < 0 {
+		delta = "-" + strconv.FormatUint(uint64(-count), 10)
+	}
+
+	s.statsd.write(s.name + ":" + delta + "|g" + s.tags)
+}
+
+func (s *statsdGauge) Decr(count int64) {
+	s.Incr(-count)
+}
+
+type statsdTimerVec struct {
+	*statsdVec
+}
+
+var _ StatTimerVec = (*statsdTimerVec)(nil)
+
+func (s *statsdTimerVec) WithValues(labelValues ...string) StatTimer {
+	name, tags := s.key(labelValues)
+	return &statsdTimer{
+		statsd: s.statsd,
+		name:   name,
+		tags:   tags,
+	}
+}
+
+type statsdTimer struct {
+	statsd *Statsd
+	name   string
+	tags   string
+}
+
+var _ StatTimer = (*statsdTimer)(nil)
+
+// Timing receives delta in nanoseconds, then send it in milliseconds as the StatsD timer unit.
+func (s *statsdTimer) Timing(delta int64) {
+	ms := strconv.FormatFloat(float64(delta)/1e6, 'f', -1, 64)
+	s.statsd.write(s.name + ":" + ms + "|ms" + s.tags)
+}
@@ -2,11 +2,15 @@ package oteltracer
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
@@ -48,6 +52,29 @@ func WithOTLPGrpcEndpoint(endpoint string) ExporterOpt {
 	}
 }
 
+// WithJaegerEndpoint set the Jaeger collector endpoint for type JAEGER span exporter.
+// Jaeger natively accepts the OTLP HTTP protocol, so it is either host:port or URL of the Jaeger OTLP HTTP receiver.
+// Default to http://localhost:4318
+func WithJaegerEndpoint(endpoint string) ExporterOpt {
+	return func(option *ExporterOption) error {
+		option.jaegerEndpoint = endpoint
+		return nil
+	}
+}
+
+// WithMemoryExporter set the MemoryExporter used by type MEMORY span exporter,
+// so the caller can keep the reference to query the recent traces.
+func WithMemoryExporter(exporter *MemoryExporter) ExporterOpt {
+	return func(option *ExporterOption) error {
+		if exporter == nil {
+			return fmt.Errorf("cannot use nil memory exporter")
+		}
+
+		option.memoryExporter = exporter
+		return nil
+	}
+}
+
 // WithHttpRoundTripper useful when we want to capture request-response log send by OpenTelemetry library.
 // But please note to not add more tracing, you must only use this http.RoundTripper as logger only,
 // or your tracing may create unwanted span if you add more span inside this middleware.
@@ -62,17 +89,40 @@ type ExporterOption struct {
 	logger           *slog.Logger
 	otlpEndpoint     string
 	otlpGrpcEndpoint string
+	jaegerEndpoint   string
 	httpRoundTripper http.RoundTripper
+
+	otlpHeaders       map[string]string
+	otlpInsecure      bool
+	otlpTLSConfig     *tls.Config
+	otlpRootCAs       *x509.CertPool
+	otlpClientCerts   []tls.Certificate
+	otlpGzip          bool
+	otlpURLPathPrefix string
+	otlpTimeout       time.Duration
+	otlpRetry         *RetryConfig
+
+	filePath       string
+	fileMaxSize    int64
+	fileMaxBackups int
+
+	memoryExporter *MemoryExporter
 }
 
-// NewTracerExporter select the tracer span exporter based on name.
-// Default to noop exporter if no name or NOOP specified.
-func NewTracerExporter(name string, opts ...ExporterOpt) (trace.SpanExporter, error) {
+// newExporterOption returns the ExporterOption with default values, then applies the opts.
+// It is shared between the span, metric and log exporters, so all signals use the same collector endpoints.
+func newExporterOption(opts ...ExporterOpt) (*ExporterOption, error) {
 	cfg := &ExporterOption{
 		logger:           slog.Default(),
 		otlpEndpoint:     "localhost:4318",
 		otlpGrpcEndpoint: "localhost:4317",
+		jaegerEndpoint:   "http://localhost:4318",
 		httpRoundTripper: http.DefaultTransport,
+		otlpHeaders:      map[string]string{},
+		otlpInsecure:     true,
+		filePath:         "traces.jsonl",
+		fileMaxSize:      100 * 1024 * 1024,
+		fileMaxBackups:   5,
 	}
 
 	for _, opt := range opts {
@@ -82,11 +132,56 @@ func NewTracerExporter(name string, opts ...ExporterOpt) (trace.SpanExporter, er
 		}
 	}
 
-	httpClient := &http.Client{}
-	httpClient.Transport = cfg.httpRoundTripper
+	return cfg, nil
+}
+
+// NewTracerExporter select the tracer span exporter based on name.
+// The name can be comma-separated list, i.e. OTLP_GRPC,STDOUT, then the spans are sent into all of them.
+// Default to noop exporter if no name or NOOP specified.
+func NewTracerExporter(name string, opts ...ExporterOpt) (trace.SpanExporter, error) {
+	cfg, err := newExporterOption(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for _, n := range strings.Split(name, ",") {
+		n = strings.ToUpper(strings.TrimSpace(n))
+		if n == "" || n == "NOOP" || slices.Contains(names, n) {
+			continue
+		}
+
+		names = append(names, n)
+	}
+
+	if len(names) <= 0 {
+		return tracetest.NewNoopExporter(), nil
+	}
+
+	if len(names) == 1 {
+		return newTracerExporter(names[0], cfg)
+	}
+
+	exporters := make([]trace.SpanExporter, 0, len(names))
+	for _, n := range names {
+		exporter, err := newTracerExporter(n, cfg)
+		if err != nil {
+			// release the exporters created before, i.e. the opened file or connection
+			for _, e := range exporters {
+				_ = e.Shutdown(context.Background())
+			}
+
+			return nil, err
+		}
+
+		exporters = append(exporters, exporter)
+	}
+
+	return newMultiSpanExporter(exporters...), nil
+}
 
-	name = strings.TrimSpace(name)
-	name = strings.ToUpper(name)
+// newTracerExporter returns the span exporter of single name.
+func newTracerExporter(name string, cfg *ExporterOption) (trace.SpanExporter, error) {
 	switch name {
 	case "OTLP":
 		endpoint := strings.TrimSpace(cfg.otlpEndpoint)
@@ -94,13 +189,12 @@ func NewTracerExporter(name string, opts ...ExporterOpt) (trace.SpanExporter, er
 			return nil, fmt.Errorf("cannot use OpenTelemetry OTLP if OTEL_EXPORTER_OTLP_ENDPOINT is empty")
 		}
 
-		return otlptrace.New(
-			context.Background(),
-			otlptracehttp.NewClient(
-				otlptracehttp.WithInsecure(),
-				otlptracehttp.WithEndpoint(endpoint),
-			),
-		)
+		clientOpts, err := cfg.otlpTraceHTTPOptions(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return otlptrace.New(context.Background(), otlptracehttp.NewClient(clientOpts...))
 
 	case "OTLP_GRPC":
 		endpoint := strings.TrimSpace(cfg.otlpGrpcEndpoint)
@@ -108,13 +202,40 @@ func NewTracerExporter(name string, opts ...ExporterOpt) (trace.SpanExporter, er
 			return nil, fmt.Errorf("cannot use OpenTelemetry OTLP_GRPC if OTEL_EXPORTER_OTLP_GRPC_ENDPOINT is empty")
 		}
 
-		return otlptrace.New(
-			context.Background(),
-			otlptracegrpc.NewClient(
-				otlptracegrpc.WithInsecure(),
-				otlptracegrpc.WithEndpoint(endpoint),
-			),
-		)
+		clientOpts, err := cfg.otlpTraceGRPCOptions(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return otlptrace.New(context.Background(), otlptracegrpc.NewClient(clientOpts...))
+
+	case "JAEGER":
+		endpoint := strings.TrimSpace(cfg.jaegerEndpoint)
+		if endpoint == "" {
+			return nil, fmt.Errorf("cannot use Jaeger if OTEL_EXPORTER_JAEGER_ENDPOINT is empty")
+		}
+
+		// Jaeger Thrift collector path, which is removed in Jaeger v2 in favor of OTLP.
+		if strings.HasSuffix(strings.TrimRight(endpoint, "/"), "/api/traces") {
+			return nil, fmt.Errorf("OTEL_EXPORTER_JAEGER_ENDPOINT '%s' is the legacy Jaeger Thrift endpoint, use the Jaeger OTLP HTTP endpoint instead, i.e. http://localhost:4318", endpoint)
+		}
+
+		clientOpts, err := cfg.otlpTraceHTTPOptions(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return otlptrace.New(context.Background(), otlptracehttp.NewClient(clientOpts...))
+
+	case "FILE":
+		return otlptrace.New(context.Background(), newFileClient(cfg.filePath, cfg.fileMaxSize, cfg.fileMaxBackups))
+
+	case "MEMORY":
+		if cfg.memoryExporter != nil {
+			return cfg.memoryExporter, nil
+		}
+
+		return NewMemoryExporter()
 
 	case "STDOUT":
 		return stdouttrace.New(
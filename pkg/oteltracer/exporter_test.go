@@ -1,12 +1,16 @@
 package oteltracer_test
 
 import (
+	"context"
 	"log/slog"
+	"path/filepath"
 	"testing"
 
 	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 var tracerExporterText = &oteltracer.ExporterOption{}
@@ -66,6 +70,10 @@ func TestNewTracerExporter(t *testing.T) {
 			"OTLP_GRPC",
 			"STDOUT",
 			"NOOP",
+			"JAEGER",
+			"MEMORY",
+			"OTLP_GRPC,STDOUT",
+			"NOOP,",
 		}
 
 		for _, ty := range types {
@@ -94,4 +102,32 @@ func TestNewTracerExporter(t *testing.T) {
 		assert.Nil(t, spanExporter)
 		assert.Error(t, err)
 	})
+
+	t.Run("unknown type in list", func(t *testing.T) {
+		spanExporter, err := oteltracer.NewTracerExporter("STDOUT,unknown")
+		assert.Nil(t, spanExporter)
+		assert.Error(t, err)
+	})
+
+	t.Run("fan out into multiple types", func(t *testing.T) {
+		memoryExporter, err := oteltracer.NewMemoryExporter()
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "traces.jsonl")
+		spanExporter, err := oteltracer.NewTracerExporter(" memory , FILE, MEMORY",
+			oteltracer.WithMemoryExporter(memoryExporter),
+			oteltracer.WithFilePath(path),
+		)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		require.NoError(t, spanExporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: "span"}}.Snapshots()))
+		require.NoError(t, spanExporter.Shutdown(ctx))
+
+		// the duplicate name is only exported once
+		traces := memoryExporter.Traces()
+		require.Len(t, traces, 1)
+		assert.Equal(t, 1, traces[0].SpanCount)
+		assert.Equal(t, []string{"span"}, readSpanNames(t, path))
+	})
 }
@@ -0,0 +1,213 @@
+package oteltracer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// WithFilePath set the file path of the FILE span exporter. Default to traces.jsonl in the working directory.
+func WithFilePath(path string) ExporterOpt {
+	return func(option *ExporterOption) error {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return fmt.Errorf("file exporter path cannot be empty")
+		}
+
+		option.filePath = path
+		return nil
+	}
+}
+
+// WithFileMaxSize set the maximum size in bytes of the file before it is rotated. Default to 100 MiB.
+func WithFileMaxSize(size int64) ExporterOpt {
+	return func(option *ExporterOption) error {
+		if size <= 0 {
+			return fmt.Errorf("file exporter max size must be positive")
+		}
+
+		option.fileMaxSize = size
+		return nil
+	}
+}
+
+// WithFileMaxBackups set the number of rotated files to keep, the oldest is removed. Default to 5.
+// Zero means the rotated file is removed immediately.
+func WithFileMaxBackups(n int) ExporterOpt {
+	return func(option *ExporterOption) error {
+		if n < 0 {
+			return fmt.Errorf("file exporter max backups cannot be negative")
+		}
+
+		option.fileMaxBackups = n
+		return nil
+	}
+}
+
+// fileClient implements otlptrace.Client by writing each export as one line of OTLP-JSON
+// (ExportTraceServiceRequest encoded using protobuf JSON mapping), the same format as the OpenTelemetry Collector
+// file exporter, so the file can be replayed into the collector or analyzed offline.
+//
+// When the file size exceeds maxSize, it is renamed into path.1 (the older path.1 becomes path.2, and so on)
+// and the new file is created.
+type fileClient struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+var _ otlptrace.Client = (*fileClient)(nil)
+
+func newFileClient(path string, maxSize int64, maxBackups int) *fileClient {
+	return &fileClient{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+}
+
+func (c *fileClient) Start(_ context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.file != nil {
+		return nil
+	}
+
+	return c.openLocked()
+}
+
+func (c *fileClient) Stop(_ context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+func (c *fileClient) UploadTraces(_ context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	if len(protoSpans) <= 0 {
+		return nil
+	}
+
+	line, err := protojson.MarshalOptions{}.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: protoSpans})
+	if err != nil {
+		return fmt.Errorf("file exporter: cannot marshal spans: %w", err)
+	}
+
+	line = append(line, '\n')
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.file == nil {
+		return fmt.Errorf("file exporter: file %s is closed", c.path)
+	}
+
+	// when rotation fails the current file is reopened, so the spans are still written and the rotation is retried
+	// on the next upload.
+	var rotateErr error
+	if c.size > 0 && c.size+int64(len(line)) > c.maxSize {
+		rotateErr = c.rotateLocked()
+		if c.file == nil {
+			return rotateErr
+		}
+	}
+
+	n, err := c.file.Write(line)
+	c.size += int64(n)
+	if err != nil {
+		return errors.Join(rotateErr, fmt.Errorf("file exporter: cannot write spans: %w", err))
+	}
+
+	return rotateErr
+}
+
+func (c *fileClient) openLocked() error {
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("file exporter: cannot create directory %s: %w", dir, err)
+		}
+	}
+
+	file, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("file exporter: cannot open file %s: %w", c.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("file exporter: cannot stat file %s: %w", c.path, err)
+	}
+
+	c.file = file
+	c.size = info.Size()
+	return nil
+}
+
+// rotateLocked shifts the backup files, then moves the current file as the first backup and opens the new one.
+// If the rotation fails, the current file is reopened in append mode so the exporter keeps writing.
+func (c *fileClient) rotateLocked() error {
+	err := c.file.Close()
+	c.file = nil
+	if err != nil {
+		err = fmt.Errorf("file exporter: cannot close file %s: %w", c.path, err)
+	} else {
+		err = c.shiftBackups()
+	}
+
+	if openErr := c.openLocked(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+
+	return err
+}
+
+// shiftBackups moves the current file as the first backup, or removes it when no backup is kept.
+func (c *fileClient) shiftBackups() error {
+	if c.maxBackups <= 0 {
+		if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("file exporter: cannot remove file %s: %w", c.path, err)
+		}
+
+		return nil
+	}
+
+	// the oldest backup is overwritten by the rename below
+	for i := c.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(c.backupPath(i), c.backupPath(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("file exporter: cannot rotate file %s: %w", c.backupPath(i), err)
+		}
+	}
+
+	if err := os.Rename(c.path, c.backupPath(1)); err != nil {
+		return fmt.Errorf("file exporter: cannot rotate file %s: %w", c.path, err)
+	}
+
+	return nil
+}
+
+func (c *fileClient) backupPath(i int) string {
+	return c.path + "." + strconv.Itoa(i)
+}
@@ -0,0 +1,195 @@
+package oteltracer_test
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+)
+
+func TestWithFilePath(t *testing.T) {
+	opt := oteltracer.WithFilePath(" ")
+	assert.Error(t, opt(tracerExporterText))
+
+	opt = oteltracer.WithFilePath("traces.jsonl")
+	assert.NoError(t, opt(tracerExporterText))
+}
+
+func TestWithFileMaxSize(t *testing.T) {
+	opt := oteltracer.WithFileMaxSize(0)
+	assert.Error(t, opt(tracerExporterText))
+
+	opt = oteltracer.WithFileMaxSize(1024)
+	assert.NoError(t, opt(tracerExporterText))
+}
+
+func TestWithFileMaxBackups(t *testing.T) {
+	opt := oteltracer.WithFileMaxBackups(-1)
+	assert.Error(t, opt(tracerExporterText))
+
+	opt = oteltracer.WithFileMaxBackups(0)
+	assert.NoError(t, opt(tracerExporterText))
+}
+
+// readSpanNames reads the OTLP-JSON lines file and returns the span names in order.
+func readSpanNames(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+
+	names := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		req := &coltracepb.ExportTraceServiceRequest{}
+		require.NoError(t, protojson.Unmarshal(scanner.Bytes(), req))
+
+		for _, resourceSpans := range req.GetResourceSpans() {
+			for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+				for _, span := range scopeSpans.GetSpans() {
+					names = append(names, span.GetName())
+				}
+			}
+		}
+	}
+
+	require.NoError(t, scanner.Err())
+	return names
+}
+
+func TestNewTracerExporter_File(t *testing.T) {
+	t.Run("write otlp json lines", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "otel", "traces.jsonl")
+
+		spanExporter, err := oteltracer.NewTracerExporter("FILE", oteltracer.WithFilePath(path))
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		require.NoError(t, spanExporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: "first"}, {Name: "second"}}.Snapshots()))
+		require.NoError(t, spanExporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: "third"}}.Snapshots()))
+		require.NoError(t, spanExporter.Shutdown(ctx))
+
+		assert.Equal(t, []string{"first", "second", "third"}, readSpanNames(t, path))
+	})
+
+	t.Run("append to existing file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.jsonl")
+		ctx := context.Background()
+
+		for _, name := range []string{"first", "second"} {
+			spanExporter, err := oteltracer.NewTracerExporter("FILE", oteltracer.WithFilePath(path))
+			require.NoError(t, err)
+			require.NoError(t, spanExporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: name}}.Snapshots()))
+			require.NoError(t, spanExporter.Shutdown(ctx))
+		}
+
+		assert.Equal(t, []string{"first", "second"}, readSpanNames(t, path))
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+		// each export is one line bigger than the max size, so every export after the first rotates the file
+		spanExporter, err := oteltracer.NewTracerExporter("FILE",
+			oteltracer.WithFilePath(path),
+			oteltracer.WithFileMaxSize(1),
+			oteltracer.WithFileMaxBackups(2),
+		)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		for _, name := range []string{"first", "second", "third", "fourth"} {
+			require.NoError(t, spanExporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: name}}.Snapshots()))
+		}
+		require.NoError(t, spanExporter.Shutdown(ctx))
+
+		assert.Equal(t, []string{"fourth"}, readSpanNames(t, path))
+		assert.Equal(t, []string{"third"}, readSpanNames(t, path+".1"))
+		assert.Equal(t, []string{"second"}, readSpanNames(t, path+".2"))
+		assert.NoFileExists(t, path+".3")
+	})
+
+	t.Run("rotate without backup", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+		spanExporter, err := oteltracer.NewTracerExporter("FILE",
+			oteltracer.WithFilePath(path),
+			oteltracer.WithFileMaxSize(1),
+			oteltracer.WithFileMaxBackups(0),
+		)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		for _, name := range []string{"first", "second"} {
+			require.NoError(t, spanExporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: name}}.Snapshots()))
+		}
+		require.NoError(t, spanExporter.Shutdown(ctx))
+
+		assert.Equal(t, []string{"second"}, readSpanNames(t, path))
+		assert.NoFileExists(t, path+".1")
+	})
+
+	t.Run("rotate failure keeps writing", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+		// a non-empty directory at the backup path cannot be replaced by the rename
+		require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "locked"), 0o755))
+
+		spanExporter, err := oteltracer.NewTracerExporter("FILE",
+			oteltracer.WithFilePath(path),
+			oteltracer.WithFileMaxSize(1),
+			oteltracer.WithFileMaxBackups(1),
+		)
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		require.NoError(t, spanExporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: "first"}}.Snapshots()))
+		assert.Error(t, spanExporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: "second"}}.Snapshots()))
+
+		// once the backup path is free again, the next export rotates the file
+		require.NoError(t, os.RemoveAll(path+".1"))
+		require.NoError(t, spanExporter.ExportSpans(ctx, tracetest.SpanStubs{{Name: "third"}}.Snapshots()))
+		require.NoError(t, spanExporter.Shutdown(ctx))
+
+		assert.Equal(t, []string{"third"}, readSpanNames(t, path))
+		assert.Equal(t, []string{"first", "second"}, readSpanNames(t, path+".1"))
+	})
+
+	t.Run("cannot open file", func(t *testing.T) {
+		spanExporter, err := oteltracer.NewTracerExporter("FILE", oteltracer.WithFilePath(t.TempDir()))
+		assert.Nil(t, spanExporter)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewTracerExporter_Jaeger(t *testing.T) {
+	t.Run("empty endpoint", func(t *testing.T) {
+		spanExporter, err := oteltracer.NewTracerExporter("JAEGER", oteltracer.WithJaegerEndpoint(""))
+		assert.Nil(t, spanExporter)
+		assert.Error(t, err)
+	})
+
+	t.Run("legacy thrift endpoint", func(t *testing.T) {
+		spanExporter, err := oteltracer.NewTracerExporter("JAEGER", oteltracer.WithJaegerEndpoint("http://localhost:14268/api/traces"))
+		assert.Nil(t, spanExporter)
+		assert.ErrorContains(t, err, "legacy Jaeger Thrift endpoint")
+	})
+
+	t.Run("otlp endpoint", func(t *testing.T) {
+		spanExporter, err := oteltracer.NewTracerExporter("jaeger", oteltracer.WithJaegerEndpoint("http://localhost:14318"))
+		assert.NoError(t, err)
+		assert.NotNil(t, spanExporter)
+	})
+}
@@ -0,0 +1,73 @@
+package oteltracer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/sdk/log"
+)
+
+// NewLogExporter select the log record exporter based on name, using the same endpoints as NewTracerExporter.
+// There is no STDOUT log exporter, since the logs are already written into stdout by the slog handler.
+// Default to noop exporter if no name or NOOP specified.
+func NewLogExporter(name string, opts ...ExporterOpt) (log.Exporter, error) {
+	cfg, err := newExporterOption(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	name = strings.ToUpper(name)
+	switch name {
+	case "OTLP":
+		endpoint := strings.TrimSpace(cfg.otlpEndpoint)
+		if endpoint == "" {
+			return nil, fmt.Errorf("cannot use OpenTelemetry OTLP if OTEL_EXPORTER_OTLP_ENDPOINT is empty")
+		}
+
+		clientOpts, err := cfg.otlpLogHTTPOptions(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return otlploghttp.New(context.Background(), clientOpts...)
+
+	case "OTLP_GRPC":
+		endpoint := strings.TrimSpace(cfg.otlpGrpcEndpoint)
+		if endpoint == "" {
+			return nil, fmt.Errorf("cannot use OpenTelemetry OTLP_GRPC if OTEL_EXPORTER_OTLP_GRPC_ENDPOINT is empty")
+		}
+
+		clientOpts, err := cfg.otlpLogGRPCOptions(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return otlploggrpc.New(context.Background(), clientOpts...)
+
+	case "", "NOOP":
+		return &noopLogExporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown name='%s' for OpenTelemetry log exporter", name)
+	}
+}
+
+// noopLogExporter drops all log records.
+type noopLogExporter struct{}
+
+var _ log.Exporter = (*noopLogExporter)(nil)
+
+func (n *noopLogExporter) Export(_ context.Context, _ []log.Record) error {
+	return nil
+}
+
+func (n *noopLogExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func (n *noopLogExporter) ForceFlush(_ context.Context) error {
+	return nil
+}
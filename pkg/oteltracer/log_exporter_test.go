@@ -0,0 +1,51 @@
+package oteltracer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+)
+
+func TestNewLogExporter(t *testing.T) {
+	t.Run("error exporter", func(t *testing.T) {
+		logExporter, err := oteltracer.NewLogExporter("otlp", oteltracer.WithLogger(nil))
+		assert.Nil(t, logExporter)
+		assert.Error(t, err)
+	})
+
+	t.Run("available types", func(t *testing.T) {
+		types := []string{
+			"OTLP",
+			"OTLP_GRPC",
+			"NOOP",
+		}
+
+		for _, ty := range types {
+			t.Run(ty, func(t *testing.T) {
+				logExporter, err := oteltracer.NewLogExporter(ty)
+				assert.NotNil(t, logExporter)
+				assert.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("otlp without endpoint", func(t *testing.T) {
+		logExporter, err := oteltracer.NewLogExporter("otlp", oteltracer.WithOTLPEndpoint(""))
+		assert.Nil(t, logExporter)
+		assert.Error(t, err)
+	})
+
+	t.Run("otlp grpc without endpoint", func(t *testing.T) {
+		logExporter, err := oteltracer.NewLogExporter("otlp_grpc", oteltracer.WithOTLPGrpcEndpoint(""))
+		assert.Nil(t, logExporter)
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		logExporter, err := oteltracer.NewLogExporter("stdout")
+		assert.Nil(t, logExporter)
+		assert.Error(t, err)
+	})
+}
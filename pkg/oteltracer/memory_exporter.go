@@ -0,0 +1,283 @@
+package oteltracer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+type MemoryExporterOpt func(*MemoryExporter) error
+
+// MemoryWithMaxTraces set the number of the most recent traces to keep, the oldest trace is evicted. Default to 100.
+func MemoryWithMaxTraces(n int) MemoryExporterOpt {
+	return func(m *MemoryExporter) error {
+		if n <= 0 {
+			return fmt.Errorf("memory exporter max traces must be positive")
+		}
+
+		m.maxTraces = n
+		return nil
+	}
+}
+
+// MemoryWithMaxSpansPerTrace set the maximum spans kept per trace, the next spans of the same trace are dropped.
+// Default to 1000.
+func MemoryWithMaxSpansPerTrace(n int) MemoryExporterOpt {
+	return func(m *MemoryExporter) error {
+		if n <= 0 {
+			return fmt.Errorf("memory exporter max spans per trace must be positive")
+		}
+
+		m.maxSpansPerTrace = n
+		return nil
+	}
+}
+
+// MemorySpanEvent is the event recorded in the span, i.e. the exception.
+type MemorySpanEvent struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// MemorySpan is the span kept by MemoryExporter.
+type MemorySpan struct {
+	TraceID       string            `json:"traceId"`
+	SpanID        string            `json:"spanId"`
+	ParentSpanID  string            `json:"parentSpanId,omitempty"`
+	Name          string            `json:"name"`
+	Kind          string            `json:"kind"`
+	StartTime     time.Time         `json:"startTime"`
+	EndTime       time.Time         `json:"endTime"`
+	Duration      string            `json:"duration"`
+	StatusCode    string            `json:"statusCode"`
+	StatusMessage string            `json:"statusMessage,omitempty"`
+	Attributes    map[string]any    `json:"attributes,omitempty"`
+	Events        []MemorySpanEvent `json:"events,omitempty"`
+}
+
+// MemoryTraceSummary is the brief of the trace kept by MemoryExporter.
+type MemoryTraceSummary struct {
+	TraceID string `json:"traceId"`
+
+	// RootName is the name of the span without parent in this process, or the earliest span if none.
+	RootName  string    `json:"rootName"`
+	SpanCount int       `json:"spanCount"`
+	StartTime time.Time `json:"startTime"`
+	Duration  string    `json:"duration"`
+	HasError  bool      `json:"hasError"`
+}
+
+// memoryTrace is the spans of one trace in order of export.
+type memoryTrace struct {
+	spans []MemorySpan
+}
+
+// MemoryExporter is a span exporter which keeps the spans of the last N traces in memory,
+// so the recent traces can be inspected (i.e. from the debug endpoint) without any collector.
+type MemoryExporter struct {
+	maxTraces        int
+	maxSpansPerTrace int
+
+	lock   sync.RWMutex
+	traces map[oteltrace.TraceID]*memoryTrace
+
+	// ring is the trace ids in order of arrival, next is the position of the oldest trace to be evicted when full.
+	ring []oteltrace.TraceID
+	next int
+}
+
+var _ trace.SpanExporter = (*MemoryExporter)(nil)
+
+func NewMemoryExporter(opts ...MemoryExporterOpt) (*MemoryExporter, error) {
+	m := &MemoryExporter{
+		maxTraces:        100,
+		maxSpansPerTrace: 1000,
+	}
+
+	for _, opt := range opts {
+		err := opt(m)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.traces = make(map[oteltrace.TraceID]*memoryTrace, m.maxTraces)
+	m.ring = make([]oteltrace.TraceID, 0, m.maxTraces)
+	return m, nil
+}
+
+func (m *MemoryExporter) ExportSpans(_ context.Context, spans []trace.ReadOnlySpan) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, span := range spans {
+		traceID := span.SpanContext().TraceID()
+
+		t, exist := m.traces[traceID]
+		if !exist {
+			t = &memoryTrace{}
+			m.addTraceLocked(traceID, t)
+		}
+
+		if len(t.spans) >= m.maxSpansPerTrace {
+			continue
+		}
+
+		t.spans = append(t.spans, toMemorySpan(span))
+	}
+
+	return nil
+}
+
+// Shutdown does nothing, so the kept traces are still readable after the tracer provider is shut down.
+func (m *MemoryExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+// Traces returns the summary of the kept traces, the most recent first.
+func (m *MemoryExporter) Traces() []MemoryTraceSummary {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	out := make([]MemoryTraceSummary, 0, len(m.ring))
+
+	// iterate the ring buffer backward from the newest trace, which is right before the next position
+	for i := 1; i <= len(m.ring); i++ {
+		traceID := m.ring[(m.next-i+len(m.ring))%len(m.ring)]
+		out = append(out, summarizeTrace(traceID, m.traces[traceID].spans))
+	}
+
+	return out
+}
+
+// Trace returns the spans of the trace id in hex format, return false if the trace is not kept.
+func (m *MemoryExporter) Trace(traceID string) ([]MemorySpan, bool) {
+	id, err := oteltrace.TraceIDFromHex(traceID)
+	if err != nil {
+		return nil, false
+	}
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	t, exist := m.traces[id]
+	if !exist {
+		return nil, false
+	}
+
+	spans := make([]MemorySpan, len(t.spans))
+	copy(spans, t.spans)
+	return spans, true
+}
+
+// Reset removes all kept traces.
+func (m *MemoryExporter) Reset() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	clear(m.traces)
+	m.ring = m.ring[:0]
+	m.next = 0
+}
+
+// addTraceLocked adds the new trace, evicting the oldest one when the ring buffer is full.
+func (m *MemoryExporter) addTraceLocked(traceID oteltrace.TraceID, t *memoryTrace) {
+	m.traces[traceID] = t
+
+	if len(m.ring) < m.maxTraces {
+		m.ring = append(m.ring, traceID)
+		m.next = len(m.ring) % m.maxTraces
+		return
+	}
+
+	delete(m.traces, m.ring[m.next])
+	m.ring[m.next] = traceID
+	m.next = (m.next + 1) % m.maxTraces
+}
+
+func summarizeTrace(traceID oteltrace.TraceID, spans []MemorySpan) MemoryTraceSummary {
+	summary := MemoryTraceSummary{
+		TraceID:   traceID.String(),
+		SpanCount: len(spans),
+	}
+
+	var root *MemorySpan
+	var endTime time.Time
+	for i := range spans {
+		span := &spans[i]
+		if root == nil {
+			root = span
+		} else if isRoot, rootIsRoot := span.ParentSpanID == "", root.ParentSpanID == ""; (isRoot && !rootIsRoot) ||
+			(isRoot == rootIsRoot && span.StartTime.Before(root.StartTime)) {
+			root = span
+		}
+
+		if summary.StartTime.IsZero() || span.StartTime.Before(summary.StartTime) {
+			summary.StartTime = span.StartTime
+		}
+
+		if span.EndTime.After(endTime) {
+			endTime = span.EndTime
+		}
+
+		if span.StatusCode == codes.Error.String() {
+			summary.HasError = true
+		}
+	}
+
+	if root != nil {
+		summary.RootName = root.Name
+		summary.Duration = endTime.Sub(summary.StartTime).String()
+	}
+
+	return summary
+}
+
+func toMemorySpan(span trace.ReadOnlySpan) MemorySpan {
+	out := MemorySpan{
+		TraceID:       span.SpanContext().TraceID().String(),
+		SpanID:        span.SpanContext().SpanID().String(),
+		Name:          span.Name(),
+		Kind:          span.SpanKind().String(),
+		StartTime:     span.StartTime(),
+		EndTime:       span.EndTime(),
+		Duration:      span.EndTime().Sub(span.StartTime()).String(),
+		StatusCode:    span.Status().Code.String(),
+		StatusMessage: span.Status().Description,
+		Attributes:    attributesToMap(span.Attributes()),
+	}
+
+	if span.Parent().HasSpanID() {
+		out.ParentSpanID = span.Parent().SpanID().String()
+	}
+
+	for _, event := range span.Events() {
+		out.Events = append(out.Events, MemorySpanEvent{
+			Name:       event.Name,
+			Time:       event.Time,
+			Attributes: attributesToMap(event.Attributes),
+		})
+	}
+
+	return out
+}
+
+func attributesToMap(attrs []attribute.KeyValue) map[string]any {
+	if len(attrs) <= 0 {
+		return nil
+	}
+
+	out := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		out[string(attr.Key)] = attr.Value.AsInterface()
+	}
+
+	return out
+}
@@ -0,0 +1,143 @@
+package oteltracer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+)
+
+func TestNewMemoryExporter(t *testing.T) {
+	_, err := oteltracer.NewMemoryExporter(oteltracer.MemoryWithMaxTraces(0))
+	assert.Error(t, err)
+
+	_, err = oteltracer.NewMemoryExporter(oteltracer.MemoryWithMaxSpansPerTrace(0))
+	assert.Error(t, err)
+}
+
+func TestWithMemoryExporter(t *testing.T) {
+	opt := oteltracer.WithMemoryExporter(nil)
+	assert.Error(t, opt(tracerExporterText))
+}
+
+// startTrace records one trace with a root span and a child span using the memory exporter.
+func startTrace(provider *sdktrace.TracerProvider, name string, fail bool) string {
+	tracer := provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), name)
+	_, child := tracer.Start(ctx, name+"-child")
+	child.SetAttributes(attribute.String("db.system", "postgresql"))
+	if fail {
+		child.RecordError(errors.New("query failed"))
+		child.SetStatus(codes.Error, "query failed")
+	}
+
+	child.End()
+	root.End()
+	return root.SpanContext().TraceID().String()
+}
+
+func TestMemoryExporter(t *testing.T) {
+	t.Run("query trace", func(t *testing.T) {
+		memoryExporter, err := oteltracer.NewMemoryExporter()
+		require.NoError(t, err)
+
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(memoryExporter))
+		okID := startTrace(provider, "ok", false)
+		failID := startTrace(provider, "fail", true)
+		require.NoError(t, provider.Shutdown(context.Background()))
+
+		traces := memoryExporter.Traces()
+		require.Len(t, traces, 2)
+
+		assert.Equal(t, failID, traces[0].TraceID)
+		assert.Equal(t, "fail", traces[0].RootName)
+		assert.Equal(t, 2, traces[0].SpanCount)
+		assert.True(t, traces[0].HasError)
+
+		assert.Equal(t, okID, traces[1].TraceID)
+		assert.Equal(t, "ok", traces[1].RootName)
+		assert.False(t, traces[1].HasError)
+
+		spans, found := memoryExporter.Trace(failID)
+		require.True(t, found)
+		require.Len(t, spans, 2)
+
+		// the child span ends first
+		assert.Equal(t, "fail-child", spans[0].Name)
+		assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+		assert.Equal(t, "postgresql", spans[0].Attributes["db.system"])
+		assert.Equal(t, "Error", spans[0].StatusCode)
+		assert.Equal(t, "query failed", spans[0].StatusMessage)
+		require.Len(t, spans[0].Events, 1)
+		assert.Equal(t, "exception", spans[0].Events[0].Name)
+		assert.Empty(t, spans[1].ParentSpanID)
+	})
+
+	t.Run("trace not found", func(t *testing.T) {
+		memoryExporter, err := oteltracer.NewMemoryExporter()
+		require.NoError(t, err)
+
+		_, found := memoryExporter.Trace("not-hex")
+		assert.False(t, found)
+
+		_, found = memoryExporter.Trace("0102030405060708090a0b0c0d0e0f10")
+		assert.False(t, found)
+	})
+
+	t.Run("evict oldest trace", func(t *testing.T) {
+		memoryExporter, err := oteltracer.NewMemoryExporter(oteltracer.MemoryWithMaxTraces(2))
+		require.NoError(t, err)
+
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(memoryExporter))
+		ids := make([]string, 0)
+		for _, name := range []string{"first", "second", "third", "fourth"} {
+			ids = append(ids, startTrace(provider, name, false))
+		}
+		require.NoError(t, provider.Shutdown(context.Background()))
+
+		traces := memoryExporter.Traces()
+		require.Len(t, traces, 2)
+
+		_, found := memoryExporter.Trace(ids[1])
+		assert.False(t, found)
+
+		_, found = memoryExporter.Trace(ids[2])
+		assert.True(t, found)
+
+		_, found = memoryExporter.Trace(ids[3])
+		assert.True(t, found)
+	})
+
+	t.Run("limit spans per trace", func(t *testing.T) {
+		memoryExporter, err := oteltracer.NewMemoryExporter(oteltracer.MemoryWithMaxSpansPerTrace(1))
+		require.NoError(t, err)
+
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(memoryExporter))
+		id := startTrace(provider, "trace", false)
+		require.NoError(t, provider.Shutdown(context.Background()))
+
+		spans, found := memoryExporter.Trace(id)
+		require.True(t, found)
+		assert.Len(t, spans, 1)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		memoryExporter, err := oteltracer.NewMemoryExporter()
+		require.NoError(t, err)
+
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(memoryExporter))
+		startTrace(provider, "trace", false)
+		require.NoError(t, provider.Shutdown(context.Background()))
+
+		memoryExporter.Reset()
+		assert.Empty(t, memoryExporter.Traces())
+	})
+}
@@ -0,0 +1,91 @@
+package oteltracer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// NewMetricExporter select the metric exporter based on name, using the same endpoints as NewTracerExporter.
+// Default to noop exporter if no name or NOOP specified.
+func NewMetricExporter(name string, opts ...ExporterOpt) (metric.Exporter, error) {
+	cfg, err := newExporterOption(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	name = strings.ToUpper(name)
+	switch name {
+	case "OTLP":
+		endpoint := strings.TrimSpace(cfg.otlpEndpoint)
+		if endpoint == "" {
+			return nil, fmt.Errorf("cannot use OpenTelemetry OTLP if OTEL_EXPORTER_OTLP_ENDPOINT is empty")
+		}
+
+		clientOpts, err := cfg.otlpMetricHTTPOptions(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return otlpmetrichttp.New(context.Background(), clientOpts...)
+
+	case "OTLP_GRPC":
+		endpoint := strings.TrimSpace(cfg.otlpGrpcEndpoint)
+		if endpoint == "" {
+			return nil, fmt.Errorf("cannot use OpenTelemetry OTLP_GRPC if OTEL_EXPORTER_OTLP_GRPC_ENDPOINT is empty")
+		}
+
+		clientOpts, err := cfg.otlpMetricGRPCOptions(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return otlpmetricgrpc.New(context.Background(), clientOpts...)
+
+	case "STDOUT":
+		return stdoutmetric.New(
+			stdoutmetric.WithWriter(wrapToIO(cfg.logger)),
+			// Use human-readable output.
+			stdoutmetric.WithPrettyPrint(),
+			// Do not print timestamps for the demo.
+			stdoutmetric.WithoutTimestamps(),
+		)
+
+	case "", "NOOP":
+		return &noopMetricExporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown name='%s' for OpenTelemetry metric exporter", name)
+	}
+}
+
+// noopMetricExporter drops all metrics, since the metric SDK doesn't provide one like tracetest.NewNoopExporter.
+type noopMetricExporter struct{}
+
+var _ metric.Exporter = (*noopMetricExporter)(nil)
+
+func (n *noopMetricExporter) Temporality(kind metric.InstrumentKind) metricdata.Temporality {
+	return metric.DefaultTemporalitySelector(kind)
+}
+
+func (n *noopMetricExporter) Aggregation(kind metric.InstrumentKind) metric.Aggregation {
+	return metric.DefaultAggregationSelector(kind)
+}
+
+func (n *noopMetricExporter) Export(_ context.Context, _ *metricdata.ResourceMetrics) error {
+	return nil
+}
+
+func (n *noopMetricExporter) ForceFlush(_ context.Context) error {
+	return nil
+}
+
+func (n *noopMetricExporter) Shutdown(_ context.Context) error {
+	return nil
+}
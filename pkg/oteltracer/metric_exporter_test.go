@@ -0,0 +1,52 @@
+package oteltracer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+)
+
+func TestNewMetricExporter(t *testing.T) {
+	t.Run("error exporter", func(t *testing.T) {
+		metricExporter, err := oteltracer.NewMetricExporter("stdout", oteltracer.WithLogger(nil))
+		assert.Nil(t, metricExporter)
+		assert.Error(t, err)
+	})
+
+	t.Run("available types", func(t *testing.T) {
+		types := []string{
+			"OTLP",
+			"OTLP_GRPC",
+			"STDOUT",
+			"NOOP",
+		}
+
+		for _, ty := range types {
+			t.Run(ty, func(t *testing.T) {
+				metricExporter, err := oteltracer.NewMetricExporter(ty)
+				assert.NotNil(t, metricExporter)
+				assert.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("otlp without endpoint", func(t *testing.T) {
+		metricExporter, err := oteltracer.NewMetricExporter("otlp", oteltracer.WithOTLPEndpoint(""))
+		assert.Nil(t, metricExporter)
+		assert.Error(t, err)
+	})
+
+	t.Run("otlp grpc without endpoint", func(t *testing.T) {
+		metricExporter, err := oteltracer.NewMetricExporter("otlp_grpc", oteltracer.WithOTLPGrpcEndpoint(""))
+		assert.Nil(t, metricExporter)
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		metricExporter, err := oteltracer.NewMetricExporter("unknown")
+		assert.Nil(t, metricExporter)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,38 @@
+package oteltracer
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// multiSpanExporter fans out the spans into all exporters.
+// The failure of one exporter doesn't prevent the others to receive the spans.
+type multiSpanExporter struct {
+	exporters []trace.SpanExporter
+}
+
+var _ trace.SpanExporter = (*multiSpanExporter)(nil)
+
+func newMultiSpanExporter(exporters ...trace.SpanExporter) *multiSpanExporter {
+	return &multiSpanExporter{exporters: exporters}
+}
+
+func (m *multiSpanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	var err error
+	for _, exporter := range m.exporters {
+		err = errors.Join(err, exporter.ExportSpans(ctx, spans))
+	}
+
+	return err
+}
+
+func (m *multiSpanExporter) Shutdown(ctx context.Context) error {
+	var err error
+	for _, exporter := range m.exporters {
+		err = errors.Join(err, exporter.Shutdown(ctx))
+	}
+
+	return err
+}
@@ -0,0 +1,492 @@
+package oteltracer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // register gzip compressor for OTLP gRPC
+)
+
+// RetryConfig is the retry policy of OTLP exporters when the export failed, using exponential backoff.
+type RetryConfig struct {
+	// Enabled indicates whether to retry the failed export.
+	Enabled bool
+
+	// InitialInterval is the time to wait after the first failure before retrying.
+	InitialInterval time.Duration
+
+	// MaxInterval is the upper bound of the backoff interval.
+	MaxInterval time.Duration
+
+	// MaxElapsedTime is the maximum time spent to retry one export, after that the data is dropped.
+	MaxElapsedTime time.Duration
+}
+
+// WithOTLPHeaders add the headers (i.e. authorization token) to each export request of OTLP and OTLP_GRPC exporters.
+func WithOTLPHeaders(headers map[string]string) ExporterOpt {
+	return func(option *ExporterOption) error {
+		if option.otlpHeaders == nil {
+			option.otlpHeaders = map[string]string{}
+		}
+
+		for k, v := range headers {
+			k = strings.TrimSpace(k)
+			if k == "" {
+				return fmt.Errorf("otlp header name cannot be empty")
+			}
+
+			option.otlpHeaders[k] = v
+		}
+
+		return nil
+	}
+}
+
+// WithOTLPInsecure set whether to connect to the collector without TLS. Default to true.
+// The endpoint with http:// or https:// scheme takes precedence over this option.
+func WithOTLPInsecure(insecure bool) ExporterOpt {
+	return func(option *ExporterOption) error {
+		option.otlpInsecure = insecure
+		return nil
+	}
+}
+
+// WithOTLPTLSConfig set the base TLS config to connect to the collector, this also disables insecure connection.
+func WithOTLPTLSConfig(cfg *tls.Config) ExporterOpt {
+	return func(option *ExporterOption) error {
+		if cfg == nil {
+			return fmt.Errorf("cannot use nil tls config")
+		}
+
+		option.otlpTLSConfig = cfg.Clone()
+		option.otlpInsecure = false
+		return nil
+	}
+}
+
+// WithOTLPCertificate set the PEM encoded CA certificate file to verify the collector certificate,
+// this also disables insecure connection.
+func WithOTLPCertificate(caFile string) ExporterOpt {
+	return func(option *ExporterOption) error {
+		caFile = strings.TrimSpace(caFile)
+		if caFile == "" {
+			return fmt.Errorf("otlp certificate file cannot be empty")
+		}
+
+		b, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("cannot read otlp certificate file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no valid certificate found in otlp certificate file %s", caFile)
+		}
+
+		option.otlpRootCAs = pool
+		option.otlpInsecure = false
+		return nil
+	}
+}
+
+// WithOTLPClientCertificate set the PEM encoded client certificate and private key files for mTLS,
+// this also disables insecure connection.
+func WithOTLPClientCertificate(certFile, keyFile string) ExporterOpt {
+	return func(option *ExporterOption) error {
+		cert, err := tls.LoadX509KeyPair(strings.TrimSpace(certFile), strings.TrimSpace(keyFile))
+		if err != nil {
+			return fmt.Errorf("cannot load otlp client certificate: %w", err)
+		}
+
+		option.otlpClientCerts = []tls.Certificate{cert}
+		option.otlpInsecure = false
+		return nil
+	}
+}
+
+// WithOTLPCompression set the compression of export request: gzip or none. Default to none.
+func WithOTLPCompression(compression string) ExporterOpt {
+	return func(option *ExporterOption) error {
+		compression = strings.ToLower(strings.TrimSpace(compression))
+		switch compression {
+		case "", "none":
+			option.otlpGzip = false
+		case "gzip":
+			option.otlpGzip = true
+		default:
+			return fmt.Errorf("unknown otlp compression '%s', must be gzip or none", compression)
+		}
+
+		return nil
+	}
+}
+
+// WithOTLPURLPathPrefix set the path prefix of the OTLP HTTP collector, i.e. when the collector is behind a reverse proxy.
+// The signal path is appended into it, for example prefix /otlp sends the span into /otlp/v1/traces.
+func WithOTLPURLPathPrefix(prefix string) ExporterOpt {
+	return func(option *ExporterOption) error {
+		option.otlpURLPathPrefix = strings.TrimRight(strings.TrimSpace(prefix), "/")
+		return nil
+	}
+}
+
+// WithOTLPTimeout set the maximum time of one export request. Default to 10 seconds.
+func WithOTLPTimeout(timeout time.Duration) ExporterOpt {
+	return func(option *ExporterOption) error {
+		if timeout < 0 {
+			return fmt.Errorf("otlp timeout cannot be negative")
+		}
+
+		option.otlpTimeout = timeout
+		return nil
+	}
+}
+
+// WithOTLPRetry set the retry policy when the export failed. Default to the exporter library retry policy.
+func WithOTLPRetry(retry RetryConfig) ExporterOpt {
+	return func(option *ExporterOption) error {
+		if retry.Enabled && (retry.InitialInterval <= 0 || retry.MaxInterval <= 0 || retry.MaxElapsedTime <= 0) {
+			return fmt.Errorf("otlp retry intervals and max elapsed time must be positive")
+		}
+
+		option.otlpRetry = &retry
+		return nil
+	}
+}
+
+// WithOTLPFromEnv reads the standard OpenTelemetry environment variables of OTLP exporter:
+// OTEL_EXPORTER_OTLP_HEADERS, OTEL_EXPORTER_OTLP_INSECURE, OTEL_EXPORTER_OTLP_CERTIFICATE,
+// OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE, OTEL_EXPORTER_OTLP_CLIENT_KEY, OTEL_EXPORTER_OTLP_COMPRESSION
+// and OTEL_EXPORTER_OTLP_TIMEOUT (in milliseconds). Empty variable is ignored.
+//
+// Put this before the other options, so the explicit options take precedence over the environment variables.
+func WithOTLPFromEnv() ExporterOpt {
+	return func(option *ExporterOption) error {
+		var opts []ExporterOpt
+
+		if v := os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"); v != "" {
+			headers, err := parseOTLPHeaders(v)
+			if err != nil {
+				return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS: %w", err)
+			}
+
+			opts = append(opts, WithOTLPHeaders(headers))
+		}
+
+		if v := os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"); v != "" {
+			insecure, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_INSECURE: %w", err)
+			}
+
+			opts = append(opts, WithOTLPInsecure(insecure))
+		}
+
+		if v := os.Getenv("OTEL_EXPORTER_OTLP_CERTIFICATE"); v != "" {
+			opts = append(opts, WithOTLPCertificate(v))
+		}
+
+		certFile, keyFile := os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"), os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_KEY")
+		if certFile != "" || keyFile != "" {
+			opts = append(opts, WithOTLPClientCertificate(certFile, keyFile))
+		}
+
+		if v := os.Getenv("OTEL_EXPORTER_OTLP_COMPRESSION"); v != "" {
+			opts = append(opts, WithOTLPCompression(v))
+		}
+
+		if v := os.Getenv("OTEL_EXPORTER_OTLP_TIMEOUT"); v != "" {
+			ms, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_TIMEOUT, must be in milliseconds: %w", err)
+			}
+
+			opts = append(opts, WithOTLPTimeout(time.Duration(ms)*time.Millisecond))
+		}
+
+		for _, opt := range opts {
+			if err := opt(option); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// parseOTLPHeaders parses the headers in format key1=value1,key2=value2 with URL encoded value.
+func parseOTLPHeaders(s string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("header '%s' must be in format key=value", pair)
+		}
+
+		value, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("header '%s': %w", k, err)
+		}
+
+		headers[strings.TrimSpace(k)] = value
+	}
+
+	return headers, nil
+}
+
+// otlpEndpoint is the resolved collector address of OTLP exporter.
+type otlpEndpoint struct {
+	host     string
+	insecure bool
+	urlPath  string // base path without the signal path, empty means the default /v1/<signal>
+}
+
+// resolveOTLPEndpoint accepts both host:port and URL with http:// or https:// scheme as the endpoint.
+// The scheme decides the insecure connection, and the URL path is used as the path prefix.
+func (o *ExporterOption) resolveOTLPEndpoint(endpoint string) (otlpEndpoint, error) {
+	resolved := otlpEndpoint{
+		host:     endpoint,
+		insecure: o.otlpInsecure,
+		urlPath:  o.otlpURLPathPrefix,
+	}
+
+	if !strings.Contains(endpoint, "://") {
+		return resolved, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return resolved, fmt.Errorf("invalid otlp endpoint '%s': %w", endpoint, err)
+	}
+
+	switch u.Scheme {
+	case "http":
+		resolved.insecure = true
+	case "https":
+		resolved.insecure = false
+	default:
+		return resolved, fmt.Errorf("invalid otlp endpoint '%s': scheme must be http or https", endpoint)
+	}
+
+	resolved.host = u.Host
+	if path := strings.TrimRight(u.Path, "/"); path != "" {
+		resolved.urlPath = path
+	}
+
+	return resolved, nil
+}
+
+// tlsConfig returns the TLS config to connect to the collector.
+func (o *ExporterOption) tlsConfig() *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.otlpTLSConfig != nil {
+		cfg = o.otlpTLSConfig.Clone()
+	}
+
+	if o.otlpRootCAs != nil {
+		cfg.RootCAs = o.otlpRootCAs
+	}
+
+	if len(o.otlpClientCerts) > 0 {
+		cfg.Certificates = o.otlpClientCerts
+	}
+
+	return cfg
+}
+
+func (o *ExporterOption) otlpTraceHTTPOptions(endpoint string) ([]otlptracehttp.Option, error) {
+	e, err := o.resolveOTLPEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(e.host), otlptracehttp.WithHeaders(o.otlpHeaders)}
+	if e.insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	} else {
+		opts = append(opts, otlptracehttp.WithTLSClientConfig(o.tlsConfig()))
+	}
+
+	if e.urlPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(e.urlPath+"/v1/traces"))
+	}
+
+	if o.otlpGzip {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	}
+
+	if o.otlpTimeout > 0 {
+		opts = append(opts, otlptracehttp.WithTimeout(o.otlpTimeout))
+	}
+
+	if o.otlpRetry != nil {
+		opts = append(opts, otlptracehttp.WithRetry(otlptracehttp.RetryConfig(*o.otlpRetry)))
+	}
+
+	return opts, nil
+}
+
+func (o *ExporterOption) otlpTraceGRPCOptions(endpoint string) ([]otlptracegrpc.Option, error) {
+	e, err := o.resolveOTLPEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(e.host), otlptracegrpc.WithHeaders(o.otlpHeaders)}
+	if e.insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	} else {
+		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(o.tlsConfig())))
+	}
+
+	if o.otlpGzip {
+		opts = append(opts, otlptracegrpc.WithCompressor("gzip"))
+	}
+
+	if o.otlpTimeout > 0 {
+		opts = append(opts, otlptracegrpc.WithTimeout(o.otlpTimeout))
+	}
+
+	if o.otlpRetry != nil {
+		opts = append(opts, otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig(*o.otlpRetry)))
+	}
+
+	return opts, nil
+}
+
+func (o *ExporterOption) otlpMetricHTTPOptions(endpoint string) ([]otlpmetrichttp.Option, error) {
+	e, err := o.resolveOTLPEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(e.host), otlpmetrichttp.WithHeaders(o.otlpHeaders)}
+	if e.insecure {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	} else {
+		opts = append(opts, otlpmetrichttp.WithTLSClientConfig(o.tlsConfig()))
+	}
+
+	if e.urlPath != "" {
+		opts = append(opts, otlpmetrichttp.WithURLPath(e.urlPath+"/v1/metrics"))
+	}
+
+	if o.otlpGzip {
+		opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+	}
+
+	if o.otlpTimeout > 0 {
+		opts = append(opts, otlpmetrichttp.WithTimeout(o.otlpTimeout))
+	}
+
+	if o.otlpRetry != nil {
+		opts = append(opts, otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig(*o.otlpRetry)))
+	}
+
+	return opts, nil
+}
+
+func (o *ExporterOption) otlpMetricGRPCOptions(endpoint string) ([]otlpmetricgrpc.Option, error) {
+	e, err := o.resolveOTLPEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(e.host), otlpmetricgrpc.WithHeaders(o.otlpHeaders)}
+	if e.insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	} else {
+		opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(o.tlsConfig())))
+	}
+
+	if o.otlpGzip {
+		opts = append(opts, otlpmetricgrpc.WithCompressor("gzip"))
+	}
+
+	if o.otlpTimeout > 0 {
+		opts = append(opts, otlpmetricgrpc.WithTimeout(o.otlpTimeout))
+	}
+
+	if o.otlpRetry != nil {
+		opts = append(opts, otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig(*o.otlpRetry)))
+	}
+
+	return opts, nil
+}
+
+func (o *ExporterOption) otlpLogHTTPOptions(endpoint string) ([]otlploghttp.Option, error) {
+	e, err := o.resolveOTLPEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlploghttp.Option{otlploghttp.WithEndpoint(e.host), otlploghttp.WithHeaders(o.otlpHeaders)}
+	if e.insecure {
+		opts = append(opts, otlploghttp.WithInsecure())
+	} else {
+		opts = append(opts, otlploghttp.WithTLSClientConfig(o.tlsConfig()))
+	}
+
+	if e.urlPath != "" {
+		opts = append(opts, otlploghttp.WithURLPath(e.urlPath+"/v1/logs"))
+	}
+
+	if o.otlpGzip {
+		opts = append(opts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+	}
+
+	if o.otlpTimeout > 0 {
+		opts = append(opts, otlploghttp.WithTimeout(o.otlpTimeout))
+	}
+
+	if o.otlpRetry != nil {
+		opts = append(opts, otlploghttp.WithRetry(otlploghttp.RetryConfig(*o.otlpRetry)))
+	}
+
+	return opts, nil
+}
+
+func (o *ExporterOption) otlpLogGRPCOptions(endpoint string) ([]otlploggrpc.Option, error) {
+	e, err := o.resolveOTLPEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []otlploggrpc.Option{otlploggrpc.WithEndpoint(e.host), otlploggrpc.WithHeaders(o.otlpHeaders)}
+	if e.insecure {
+		opts = append(opts, otlploggrpc.WithInsecure())
+	} else {
+		opts = append(opts, otlploggrpc.WithTLSCredentials(credentials.NewTLS(o.tlsConfig())))
+	}
+
+	if o.otlpGzip {
+		opts = append(opts, otlploggrpc.WithCompressor("gzip"))
+	}
+
+	if o.otlpTimeout > 0 {
+		opts = append(opts, otlploggrpc.WithTimeout(o.otlpTimeout))
+	}
+
+	if o.otlpRetry != nil {
+		opts = append(opts, otlploggrpc.WithRetry(otlploggrpc.RetryConfig(*o.otlpRetry)))
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,297 @@
+package oteltracer_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+)
+
+func TestWithOTLPHeaders(t *testing.T) {
+	t.Run("empty name", func(t *testing.T) {
+		opt := oteltracer.WithOTLPHeaders(map[string]string{" ": "value"})
+		err := opt(tracerExporterText)
+		assert.Error(t, err)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		_, err := oteltracer.NewTracerExporter("OTLP", oteltracer.WithOTLPHeaders(map[string]string{"Authorization": "Bearer token"}))
+		assert.NoError(t, err)
+	})
+}
+
+func TestWithOTLPTLSConfig(t *testing.T) {
+	opt := oteltracer.WithOTLPTLSConfig(nil)
+	err := opt(tracerExporterText)
+	assert.Error(t, err)
+}
+
+func TestWithOTLPCertificate(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		opt := oteltracer.WithOTLPCertificate("")
+		err := opt(tracerExporterText)
+		assert.Error(t, err)
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		opt := oteltracer.WithOTLPCertificate(filepath.Join(t.TempDir(), "ca.pem"))
+		err := opt(tracerExporterText)
+		assert.Error(t, err)
+	})
+
+	t.Run("not pem", func(t *testing.T) {
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+
+		opt := oteltracer.WithOTLPCertificate(caFile)
+		err := opt(tracerExporterText)
+		assert.Error(t, err)
+	})
+}
+
+func TestWithOTLPClientCertificate(t *testing.T) {
+	opt := oteltracer.WithOTLPClientCertificate(filepath.Join(t.TempDir(), "cert.pem"), filepath.Join(t.TempDir(), "key.pem"))
+	err := opt(tracerExporterText)
+	assert.Error(t, err)
+}
+
+func TestWithOTLPCompression(t *testing.T) {
+	for _, compression := range []string{"", "none", "gzip", "GZIP"} {
+		opt := oteltracer.WithOTLPCompression(compression)
+		assert.NoError(t, opt(tracerExporterText), compression)
+	}
+
+	opt := oteltracer.WithOTLPCompression("zstd")
+	assert.Error(t, opt(tracerExporterText))
+}
+
+func TestWithOTLPTimeout(t *testing.T) {
+	opt := oteltracer.WithOTLPTimeout(-time.Second)
+	assert.Error(t, opt(tracerExporterText))
+
+	opt = oteltracer.WithOTLPTimeout(5 * time.Second)
+	assert.NoError(t, opt(tracerExporterText))
+}
+
+func TestWithOTLPRetry(t *testing.T) {
+	opt := oteltracer.WithOTLPRetry(oteltracer.RetryConfig{Enabled: true})
+	assert.Error(t, opt(tracerExporterText))
+
+	opt = oteltracer.WithOTLPRetry(oteltracer.RetryConfig{Enabled: false})
+	assert.NoError(t, opt(tracerExporterText))
+
+	opt = oteltracer.WithOTLPRetry(oteltracer.RetryConfig{
+		Enabled:         true,
+		InitialInterval: time.Second,
+		MaxInterval:     5 * time.Second,
+		MaxElapsedTime:  time.Minute,
+	})
+	assert.NoError(t, opt(tracerExporterText))
+}
+
+func TestWithOTLPFromEnv(t *testing.T) {
+	t.Run("invalid headers", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization")
+		assert.Error(t, oteltracer.WithOTLPFromEnv()(tracerExporterText))
+	})
+
+	t.Run("invalid insecure", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "maybe")
+		assert.Error(t, oteltracer.WithOTLPFromEnv()(tracerExporterText))
+	})
+
+	t.Run("invalid timeout", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "10s")
+		assert.Error(t, oteltracer.WithOTLPFromEnv()(tracerExporterText))
+	})
+
+	t.Run("invalid compression", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "zstd")
+		assert.Error(t, oteltracer.WithOTLPFromEnv()(tracerExporterText))
+	})
+
+	t.Run("client key without certificate", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_KEY", filepath.Join(t.TempDir(), "key.pem"))
+		assert.Error(t, oteltracer.WithOTLPFromEnv()(tracerExporterText))
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20token, X-Tenant=acme")
+		t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "true")
+		t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "gzip")
+		t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "5000")
+		assert.NoError(t, oteltracer.WithOTLPFromEnv()(tracerExporterText))
+	})
+}
+
+func TestNewTracerExporter_InvalidOTLPEndpoint(t *testing.T) {
+	for _, exporter := range []string{"OTLP", "OTLP_GRPC"} {
+		spanExporter, err := oteltracer.NewTracerExporter(exporter,
+			oteltracer.WithOTLPEndpoint("ftp://localhost:4318"),
+			oteltracer.WithOTLPGrpcEndpoint("ftp://localhost:4317"),
+		)
+		assert.Nil(t, spanExporter, exporter)
+		assert.Error(t, err, exporter)
+	}
+}
+
+// otlpRequest is the request received by the fake collector.
+type otlpRequest struct {
+	path            string
+	authorization   string
+	contentEncoding string
+	clientCerts     int
+}
+
+// newOTLPCollector starts the fake OTLP HTTP collector which requires client certificate,
+// and write its certificate as the CA file.
+func newOTLPCollector(t *testing.T) (server *httptest.Server, caFile string, requests chan otlpRequest) {
+	t.Helper()
+
+	requests = make(chan otlpRequest, 10)
+	server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- otlpRequest{
+			path:            r.URL.Path,
+			authorization:   r.Header.Get("Authorization"),
+			contentEncoding: r.Header.Get("Content-Encoding"),
+			clientCerts:     len(r.TLS.PeerCertificates),
+		}
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caFile = filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+	return server, caFile, requests
+}
+
+// writeClientCertificate generates self-signed client certificate and its private key as PEM files.
+func writeClientCertificate(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "otel-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestNewTracerExporter_OTLPWithMTLS(t *testing.T) {
+	server, caFile, requests := newOTLPCollector(t)
+	certFile, keyFile := writeClientCertificate(t)
+
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20secret")
+	t.Setenv("OTEL_EXPORTER_OTLP_CERTIFICATE", caFile)
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE", certFile)
+	t.Setenv("OTEL_EXPORTER_OTLP_CLIENT_KEY", keyFile)
+	t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "gzip")
+
+	spanExporter, err := oteltracer.NewTracerExporter("OTLP",
+		oteltracer.WithOTLPFromEnv(),
+		oteltracer.WithOTLPEndpoint(server.URL+"/otlp"),
+		oteltracer.WithOTLPTimeout(5*time.Second),
+		oteltracer.WithOTLPRetry(oteltracer.RetryConfig{Enabled: false}),
+	)
+	require.NoError(t, err)
+
+	err = spanExporter.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "span"}}.Snapshots())
+	require.NoError(t, err)
+	require.NoError(t, spanExporter.Shutdown(context.Background()))
+
+	select {
+	case req := <-requests:
+		assert.Equal(t, "/otlp/v1/traces", req.path)
+		assert.Equal(t, "Bearer secret", req.authorization)
+		assert.Equal(t, "gzip", req.contentEncoding)
+		assert.Equal(t, 1, req.clientCerts)
+	default:
+		t.Fatal("collector doesn't receive any export request")
+	}
+}
+
+func TestNewTracerExporter_OTLPWithoutClientCertificate(t *testing.T) {
+	server, caFile, _ := newOTLPCollector(t)
+
+	spanExporter, err := oteltracer.NewTracerExporter("OTLP",
+		oteltracer.WithOTLPCertificate(caFile),
+		oteltracer.WithOTLPEndpoint(strings.TrimPrefix(server.URL, "https://")),
+		oteltracer.WithOTLPRetry(oteltracer.RetryConfig{Enabled: false}),
+	)
+	require.NoError(t, err)
+
+	err = spanExporter.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "span"}}.Snapshots())
+	assert.ErrorContains(t, err, "certificate required")
+	assert.NoError(t, spanExporter.Shutdown(context.Background()))
+}
+
+func TestNewExporters_OTLPOptions(t *testing.T) {
+	_, caFile, _ := newOTLPCollector(t)
+	certFile, keyFile := writeClientCertificate(t)
+
+	opts := []oteltracer.ExporterOpt{
+		oteltracer.WithOTLPEndpoint("https://localhost:4318/otlp"),
+		oteltracer.WithOTLPGrpcEndpoint("localhost:4317"),
+		oteltracer.WithOTLPHeaders(map[string]string{"Authorization": "Bearer secret"}),
+		oteltracer.WithOTLPCertificate(caFile),
+		oteltracer.WithOTLPClientCertificate(certFile, keyFile),
+		oteltracer.WithOTLPCompression("gzip"),
+		oteltracer.WithOTLPTimeout(time.Second),
+	}
+
+	for _, exporter := range []string{"OTLP", "OTLP_GRPC"} {
+		spanExporter, err := oteltracer.NewTracerExporter(exporter, opts...)
+		assert.NoError(t, err, exporter)
+		assert.NotNil(t, spanExporter, exporter)
+
+		metricExporter, err := oteltracer.NewMetricExporter(exporter, opts...)
+		assert.NoError(t, err, exporter)
+		assert.NotNil(t, metricExporter, exporter)
+
+		logExporter, err := oteltracer.NewLogExporter(exporter, opts...)
+		assert.NoError(t, err, exporter)
+		assert.NotNil(t, logExporter, exporter)
+	}
+}
@@ -0,0 +1,185 @@
+package oteltracer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+)
+
+// Environment variables of Kubernetes downward API read by the resource builder,
+// see deployment/k8s/deployment.yaml for how they are populated.
+const (
+	EnvK8sPodName       = "K8S_POD_NAME"
+	EnvK8sPodUID        = "K8S_POD_UID"
+	EnvK8sNamespace     = "K8S_NAMESPACE"
+	EnvK8sNodeName      = "K8S_NODE_NAME"
+	EnvK8sContainerName = "K8S_CONTAINER_NAME"
+)
+
+// buildTimeKey is the time when the binary is built, there is no semantic convention for it yet.
+const buildTimeKey = attribute.Key("service.build.time")
+
+type ResourceOpt func(*ResourceOption) error
+
+// ResourceWithServiceName set the service.name, it is overridden by OTEL_SERVICE_NAME if set.
+func ResourceWithServiceName(name string) ResourceOpt {
+	return func(option *ResourceOption) error {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return fmt.Errorf("service name cannot be empty")
+		}
+
+		option.serviceName = name
+		return nil
+	}
+}
+
+// ResourceWithServiceVersion set the service.version, i.e. the build commit id. Empty version is ignored.
+func ResourceWithServiceVersion(version string) ResourceOpt {
+	return func(option *ResourceOption) error {
+		option.serviceVersion = strings.TrimSpace(version)
+		return nil
+	}
+}
+
+// ResourceWithBuildTime set the service.build.time in RFC3339 format. Zero time is ignored.
+func ResourceWithBuildTime(t time.Time) ResourceOpt {
+	return func(option *ResourceOption) error {
+		option.buildTime = t
+		return nil
+	}
+}
+
+// ResourceWithEnvironment set the deployment.environment.name, i.e. staging or production. Empty name is ignored.
+func ResourceWithEnvironment(env string) ResourceOpt {
+	return func(option *ResourceOption) error {
+		option.environment = strings.TrimSpace(env)
+		return nil
+	}
+}
+
+// ResourceWithAttributes adds the static attributes, it is overridden by the same key in OTEL_RESOURCE_ATTRIBUTES.
+func ResourceWithAttributes(attrs ...attribute.KeyValue) ResourceOpt {
+	return func(option *ResourceOption) error {
+		option.attributes = append(option.attributes, attrs...)
+		return nil
+	}
+}
+
+// ResourceWithDetectors adds the custom detectors, i.e. the cloud provider detector.
+func ResourceWithDetectors(detectors ...resource.Detector) ResourceOpt {
+	return func(option *ResourceOption) error {
+		for _, detector := range detectors {
+			if detector == nil {
+				return fmt.Errorf("cannot use nil resource detector")
+			}
+		}
+
+		option.detectors = append(option.detectors, detectors...)
+		return nil
+	}
+}
+
+type ResourceOption struct {
+	serviceName    string
+	serviceVersion string
+	buildTime      time.Time
+	environment    string
+	attributes     []attribute.KeyValue
+	detectors      []resource.Detector
+}
+
+// NewResource returns the resource describing this application, merged from (the later overrides the former):
+//
+//   - the host, OS, process, container and telemetry SDK detectors,
+//   - the Kubernetes pod name, pod uid, namespace, node and container name from the downward API env vars,
+//   - the service name, version, build time, environment and attributes from the options,
+//   - the custom detectors from ResourceWithDetectors,
+//   - OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME env vars.
+//
+// The process command line arguments are not detected, because they may contain credential.
+// When some detectors failed, it still returns the partially detected resource together with the error.
+func NewResource(ctx context.Context, opts ...ResourceOpt) (*resource.Resource, error) {
+	cfg := &ResourceOption{
+		serviceName: "unknown_service",
+	}
+
+	for _, opt := range opts {
+		err := opt(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attrs := []attribute.KeyValue{semconv.ServiceName(cfg.serviceName)}
+	if cfg.serviceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(cfg.serviceVersion))
+	}
+
+	if !cfg.buildTime.IsZero() {
+		attrs = append(attrs, buildTimeKey.String(cfg.buildTime.UTC().Format(time.RFC3339)))
+	}
+
+	if cfg.environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironmentName(cfg.environment))
+	}
+
+	attrs = append(attrs, cfg.attributes...)
+
+	resourceOpts := []resource.Option{
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithOS(),
+		resource.WithProcessPID(),
+		resource.WithProcessExecutableName(),
+		resource.WithProcessExecutablePath(),
+		resource.WithProcessOwner(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithProcessRuntimeDescription(),
+		resource.WithContainer(),
+		resource.WithDetectors(k8sDetector{}),
+		resource.WithAttributes(attrs...),
+		resource.WithDetectors(cfg.detectors...),
+		resource.WithFromEnv(),
+	}
+
+	return resource.New(ctx, resourceOpts...)
+}
+
+// k8sDetector reads the Kubernetes attributes from the env vars populated by the downward API.
+type k8sDetector struct{}
+
+var _ resource.Detector = k8sDetector{}
+
+func (k8sDetector) Detect(_ context.Context) (*resource.Resource, error) {
+	envAttrs := []struct {
+		env  string
+		attr func(string) attribute.KeyValue
+	}{
+		{env: EnvK8sPodName, attr: semconv.K8SPodName},
+		{env: EnvK8sPodUID, attr: semconv.K8SPodUID},
+		{env: EnvK8sNamespace, attr: semconv.K8SNamespaceName},
+		{env: EnvK8sNodeName, attr: semconv.K8SNodeName},
+		{env: EnvK8sContainerName, attr: semconv.K8SContainerName},
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(envAttrs))
+	for _, envAttr := range envAttrs {
+		if v := strings.TrimSpace(os.Getenv(envAttr.env)); v != "" {
+			attrs = append(attrs, envAttr.attr(v))
+		}
+	}
+
+	if len(attrs) <= 0 {
+		return resource.Empty(), nil
+	}
+
+	return resource.NewSchemaless(attrs...), nil
+}
@@ -0,0 +1,113 @@
+package oteltracer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+)
+
+// resourceValue returns the value of the attribute key as string, empty if not exist.
+func resourceValue(r *resource.Resource, key string) string {
+	v, ok := r.Set().Value(attribute.Key(key))
+	if !ok {
+		return ""
+	}
+
+	return v.Emit()
+}
+
+func TestNewResource(t *testing.T) {
+	t.Run("invalid option", func(t *testing.T) {
+		r, err := oteltracer.NewResource(context.Background(), oteltracer.ResourceWithServiceName(" "))
+		assert.Nil(t, r)
+		assert.Error(t, err)
+
+		r, err = oteltracer.NewResource(context.Background(), oteltracer.ResourceWithDetectors(nil))
+		assert.Nil(t, r)
+		assert.Error(t, err)
+	})
+
+	t.Run("from options and detectors", func(t *testing.T) {
+		t.Setenv("OTEL_SERVICE_NAME", "")
+		t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "")
+
+		buildTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		r, err := oteltracer.NewResource(context.Background(),
+			oteltracer.ResourceWithServiceName("myapp_server"),
+			oteltracer.ResourceWithServiceVersion("abc123"),
+			oteltracer.ResourceWithBuildTime(buildTime),
+			oteltracer.ResourceWithEnvironment("staging"),
+			oteltracer.ResourceWithAttributes(attribute.String("team", "payment")),
+			oteltracer.ResourceWithDetectors(resource.StringDetector("", "cloud.region", func() (string, error) {
+				return "ap-southeast-1", nil
+			})),
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, "myapp_server", resourceValue(r, "service.name"))
+		assert.Equal(t, "abc123", resourceValue(r, "service.version"))
+		assert.Equal(t, "2026-01-02T03:04:05Z", resourceValue(r, "service.build.time"))
+		assert.Equal(t, "staging", resourceValue(r, "deployment.environment.name"))
+		assert.Equal(t, "payment", resourceValue(r, "team"))
+		assert.Equal(t, "ap-southeast-1", resourceValue(r, "cloud.region"))
+		assert.NotEmpty(t, resourceValue(r, "host.name"))
+		assert.NotEmpty(t, resourceValue(r, "process.pid"))
+		assert.NotEmpty(t, resourceValue(r, "telemetry.sdk.version"))
+
+		// the command line may contain credential
+		assert.Empty(t, resourceValue(r, "process.command_args"))
+	})
+
+	t.Run("empty version and environment are ignored", func(t *testing.T) {
+		r, err := oteltracer.NewResource(context.Background(),
+			oteltracer.ResourceWithServiceVersion(""),
+			oteltracer.ResourceWithEnvironment(""),
+			oteltracer.ResourceWithBuildTime(time.Time{}),
+		)
+		require.NoError(t, err)
+
+		assert.Empty(t, resourceValue(r, "service.version"))
+		assert.Empty(t, resourceValue(r, "deployment.environment.name"))
+		assert.Empty(t, resourceValue(r, "service.build.time"))
+	})
+
+	t.Run("kubernetes downward api", func(t *testing.T) {
+		t.Setenv(oteltracer.EnvK8sPodName, "myapp-6d4cf56db6-x2k7p")
+		t.Setenv(oteltracer.EnvK8sPodUID, "0f5b3c2a-1d2e-4f3a-8b9c-0d1e2f3a4b5c")
+		t.Setenv(oteltracer.EnvK8sNamespace, "ys-ns")
+		t.Setenv(oteltracer.EnvK8sNodeName, "node-1")
+		t.Setenv(oteltracer.EnvK8sContainerName, "myapp-container")
+
+		r, err := oteltracer.NewResource(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, "myapp-6d4cf56db6-x2k7p", resourceValue(r, "k8s.pod.name"))
+		assert.Equal(t, "0f5b3c2a-1d2e-4f3a-8b9c-0d1e2f3a4b5c", resourceValue(r, "k8s.pod.uid"))
+		assert.Equal(t, "ys-ns", resourceValue(r, "k8s.namespace.name"))
+		assert.Equal(t, "node-1", resourceValue(r, "k8s.node.name"))
+		assert.Equal(t, "myapp-container", resourceValue(r, "k8s.container.name"))
+	})
+
+	t.Run("env overrides options", func(t *testing.T) {
+		t.Setenv("OTEL_SERVICE_NAME", "renamed")
+		t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment.name=production,team=checkout")
+
+		r, err := oteltracer.NewResource(context.Background(),
+			oteltracer.ResourceWithServiceName("myapp_server"),
+			oteltracer.ResourceWithEnvironment("staging"),
+			oteltracer.ResourceWithAttributes(attribute.String("team", "payment")),
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, "renamed", resourceValue(r, "service.name"))
+		assert.Equal(t, "production", resourceValue(r, "deployment.environment.name"))
+		assert.Equal(t, "checkout", resourceValue(r, "team"))
+	})
+}
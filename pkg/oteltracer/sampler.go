@@ -0,0 +1,212 @@
+package oteltracer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// NewSampler returns the trace.Sampler following the OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG semantic:
+// always_on, always_off, traceidratio, parentbased_always_on, parentbased_always_off and parentbased_traceidratio.
+// The arg is the sampling probability (0 to 1) for traceidratio samplers, default to 1.
+// Default to parentbased_always_on if no name specified.
+func NewSampler(name, arg string) (trace.Sampler, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "always_on":
+		return trace.AlwaysSample(), nil
+	case "always_off":
+		return trace.NeverSample(), nil
+	case "traceidratio":
+		ratio, err := parseSamplerRatio(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return trace.TraceIDRatioBased(ratio), nil
+	case "", "parentbased_always_on":
+		return trace.ParentBased(trace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return trace.ParentBased(trace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		ratio, err := parseSamplerRatio(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return trace.ParentBased(trace.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("unknown name='%s' for OpenTelemetry traces sampler", name)
+	}
+}
+
+func parseSamplerRatio(arg string) (float64, error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return 1, nil
+	}
+
+	ratio, err := strconv.ParseFloat(arg, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return 0, fmt.Errorf("invalid traces sampler arg '%s', must be a ratio between 0 and 1", arg)
+	}
+
+	return ratio, nil
+}
+
+type RuleSamplerOpt func(*RuleSampler) error
+
+// RuleSamplerWithRoute set the sampling ratio (0 to 1) for the entry span of the route.
+func RuleSamplerWithRoute(route string, ratio float64) RuleSamplerOpt {
+	return func(s *RuleSampler) error {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			return fmt.Errorf("route of sampler rule cannot be empty")
+		}
+
+		if ratio < 0 || ratio > 1 {
+			return fmt.Errorf("route %s: sampling ratio must be between 0 and 1, got %v", route, ratio)
+		}
+
+		s.routes[route] = trace.TraceIDRatioBased(ratio)
+		return nil
+	}
+}
+
+// RuleSamplerWithRouteResolver set the function to get the route of the span to be started.
+// Default to the http.route attribute of the span.
+func RuleSamplerWithRouteResolver(resolver func(p trace.SamplingParameters) string) RuleSamplerOpt {
+	return func(s *RuleSampler) error {
+		if resolver == nil {
+			return fmt.Errorf("route resolver cannot be nil")
+		}
+
+		s.routeResolver = resolver
+		return nil
+	}
+}
+
+// RuleSamplerWithKeepErrors records the span that is not sampled, so the span which ends with error status
+// can still be exported using the span processor from NewErrorSpanProcessor.
+func RuleSamplerWithKeepErrors(keep bool) RuleSamplerOpt {
+	return func(s *RuleSampler) error {
+		s.keepErrors = keep
+		return nil
+	}
+}
+
+// RuleSampler samples the entry span of the service (the span without parent, or with remote parent)
+// using the ratio of its route rule, so the noisy route (i.e. health check) can use lower ratio than the others.
+// Other spans, or the entry span without matching rule, are sampled by the default sampler.
+type RuleSampler struct {
+	defaultSampler trace.Sampler
+	routes         map[string]trace.Sampler
+	routeResolver  func(p trace.SamplingParameters) string
+	keepErrors     bool
+}
+
+var _ trace.Sampler = (*RuleSampler)(nil)
+
+// NewRuleSampler creates RuleSampler, the defaultSampler is usually from NewSampler.
+func NewRuleSampler(defaultSampler trace.Sampler, opts ...RuleSamplerOpt) (*RuleSampler, error) {
+	if defaultSampler == nil {
+		return nil, fmt.Errorf("default sampler cannot be nil")
+	}
+
+	s := &RuleSampler{
+		defaultSampler: defaultSampler,
+		routes:         map[string]trace.Sampler{},
+		routeResolver:  routeFromAttributes,
+	}
+
+	for _, opt := range opts {
+		err := opt(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *RuleSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	sampler := s.defaultSampler
+
+	if parent := oteltrace.SpanContextFromContext(p.ParentContext); !parent.IsValid() || parent.IsRemote() {
+		if routeSampler, ok := s.routes[s.routeResolver(p)]; ok {
+			sampler = routeSampler
+		}
+	}
+
+	result := sampler.ShouldSample(p)
+	if result.Decision == trace.Drop && s.keepErrors {
+		result.Decision = trace.RecordOnly
+	}
+
+	return result
+}
+
+func (s *RuleSampler) Description() string {
+	return fmt.Sprintf("RuleSampler{default:%s,routes:%d,keepErrors:%t}", s.defaultSampler.Description(), len(s.routes), s.keepErrors)
+}
+
+func routeFromAttributes(p trace.SamplingParameters) string {
+	for _, attr := range p.Attributes {
+		if attr.Key == semconv.HTTPRouteKey {
+			return attr.Value.AsString()
+		}
+	}
+
+	return ""
+}
+
+// NewErrorSpanProcessor wraps the span processor (i.e. trace.NewBatchSpanProcessor) to also export
+// the recorded span which is not sampled but ends with error status.
+// It must be used together with RuleSamplerWithKeepErrors, otherwise the not sampled span is never recorded.
+func NewErrorSpanProcessor(next trace.SpanProcessor) trace.SpanProcessor {
+	return &errorSpanProcessor{next: next}
+}
+
+type errorSpanProcessor struct {
+	next trace.SpanProcessor
+}
+
+func (e *errorSpanProcessor) OnStart(parent context.Context, s trace.ReadWriteSpan) {
+	e.next.OnStart(parent, s)
+}
+
+func (e *errorSpanProcessor) OnEnd(s trace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		if s.Status().Code != codes.Error {
+			return
+		}
+
+		s = sampledSpan{ReadOnlySpan: s}
+	}
+
+	e.next.OnEnd(s)
+}
+
+func (e *errorSpanProcessor) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
+
+func (e *errorSpanProcessor) ForceFlush(ctx context.Context) error {
+	return e.next.ForceFlush(ctx)
+}
+
+// sampledSpan marks the not sampled span as sampled, because the span processor only exports the sampled span.
+type sampledSpan struct {
+	trace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() oteltrace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
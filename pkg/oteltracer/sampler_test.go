@@ -0,0 +1,157 @@
+package oteltracer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.27.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+)
+
+func TestNewSampler(t *testing.T) {
+	tests := []struct {
+		name        string
+		arg         string
+		description string
+	}{
+		{"", "", trace.ParentBased(trace.AlwaysSample()).Description()},
+		{"always_on", "", trace.AlwaysSample().Description()},
+		{"ALWAYS_OFF", "", trace.NeverSample().Description()},
+		{"traceidratio", "", trace.TraceIDRatioBased(1).Description()},
+		{"traceidratio", "0.25", trace.TraceIDRatioBased(0.25).Description()},
+		{"parentbased_always_on", "", trace.ParentBased(trace.AlwaysSample()).Description()},
+		{"parentbased_always_off", "", trace.ParentBased(trace.NeverSample()).Description()},
+		{"parentbased_traceidratio", "0.5", trace.ParentBased(trace.TraceIDRatioBased(0.5)).Description()},
+	}
+
+	for _, tt := range tests {
+		sampler, err := oteltracer.NewSampler(tt.name, tt.arg)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.description, sampler.Description(), tt.name)
+	}
+
+	t.Run("unknown name", func(t *testing.T) {
+		sampler, err := oteltracer.NewSampler("jaeger_remote", "")
+		assert.Nil(t, sampler)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid arg", func(t *testing.T) {
+		for _, arg := range []string{"abc", "-0.1", "1.5"} {
+			sampler, err := oteltracer.NewSampler("traceidratio", arg)
+			assert.Nil(t, sampler, arg)
+			assert.Error(t, err, arg)
+		}
+	})
+}
+
+func TestNewRuleSampler(t *testing.T) {
+	t.Run("nil default sampler", func(t *testing.T) {
+		sampler, err := oteltracer.NewRuleSampler(nil)
+		assert.Nil(t, sampler)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		_, err := oteltracer.NewRuleSampler(trace.AlwaysSample(), oteltracer.RuleSamplerWithRoute("", 1))
+		assert.Error(t, err)
+
+		_, err = oteltracer.NewRuleSampler(trace.AlwaysSample(), oteltracer.RuleSamplerWithRoute("/ping", 2))
+		assert.Error(t, err)
+
+		_, err = oteltracer.NewRuleSampler(trace.AlwaysSample(), oteltracer.RuleSamplerWithRouteResolver(nil))
+		assert.Error(t, err)
+	})
+}
+
+func TestRuleSampler_ShouldSample(t *testing.T) {
+	newTracer := func(t *testing.T, opts ...oteltracer.RuleSamplerOpt) (oteltrace.Tracer, *tracetest.InMemoryExporter) {
+		t.Helper()
+
+		sampler, err := oteltracer.NewRuleSampler(trace.AlwaysSample(), opts...)
+		require.NoError(t, err)
+
+		exporter := tracetest.NewInMemoryExporter()
+		tp := trace.NewTracerProvider(
+			trace.WithSampler(sampler),
+			trace.WithSpanProcessor(oteltracer.NewErrorSpanProcessor(trace.NewSimpleSpanProcessor(exporter))),
+		)
+
+		return tp.Tracer("test"), exporter
+	}
+
+	t.Run("route rule", func(t *testing.T) {
+		tracer, exporter := newTracer(t, oteltracer.RuleSamplerWithRoute("/ping", 0))
+
+		_, span := tracer.Start(context.Background(), "ping", oteltrace.WithAttributes(semconv.HTTPRoute("/ping")))
+		assert.False(t, span.IsRecording())
+		span.End()
+
+		_, span = tracer.Start(context.Background(), "users", oteltrace.WithAttributes(semconv.HTTPRoute("/users")))
+		assert.True(t, span.SpanContext().IsSampled())
+		span.End()
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "users", spans[0].Name)
+	})
+
+	t.Run("rule only applies to entry span", func(t *testing.T) {
+		tracer, _ := newTracer(t, oteltracer.RuleSamplerWithRoute("/ping", 0))
+
+		ctx, parent := tracer.Start(context.Background(), "parent")
+		defer parent.End()
+
+		_, child := tracer.Start(ctx, "child", oteltrace.WithAttributes(semconv.HTTPRoute("/ping")))
+		defer child.End()
+		assert.True(t, child.SpanContext().IsSampled())
+	})
+
+	t.Run("custom route resolver", func(t *testing.T) {
+		type routeKey struct{}
+		tracer, _ := newTracer(t,
+			oteltracer.RuleSamplerWithRoute("/ping", 0),
+			oteltracer.RuleSamplerWithRouteResolver(func(p trace.SamplingParameters) string {
+				route, _ := p.ParentContext.Value(routeKey{}).(string)
+				return route
+			}),
+		)
+
+		_, span := tracer.Start(context.WithValue(context.Background(), routeKey{}, "/ping"), "ping")
+		defer span.End()
+		assert.False(t, span.IsRecording())
+	})
+
+	t.Run("keep error spans", func(t *testing.T) {
+		tracer, exporter := newTracer(t,
+			oteltracer.RuleSamplerWithRoute("/ping", 0),
+			oteltracer.RuleSamplerWithKeepErrors(true),
+		)
+
+		_, okSpan := tracer.Start(context.Background(), "ok", oteltrace.WithAttributes(semconv.HTTPRoute("/ping")))
+		assert.True(t, okSpan.IsRecording())
+		assert.False(t, okSpan.SpanContext().IsSampled())
+		okSpan.End()
+
+		ctx, errSpan := tracer.Start(context.Background(), "error", oteltrace.WithAttributes(semconv.HTTPRoute("/ping")))
+		_, childSpan := tracer.Start(ctx, "child error")
+		childSpan.SetStatus(codes.Error, "failed")
+		childSpan.End()
+
+		errSpan.SetStatus(codes.Error, "failed")
+		errSpan.End()
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		assert.Equal(t, "child error", spans[0].Name)
+		assert.Equal(t, "error", spans[1].Name)
+		assert.True(t, spans[1].SpanContext.IsSampled())
+	})
+}
@@ -0,0 +1,165 @@
+// Package profiler captures the runtime profiles of the running process into a single zip archive.
+package profiler
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profile is the name of the runtime profile.
+type Profile string
+
+const (
+	CPU       Profile = "cpu"
+	Heap      Profile = "heap"
+	Goroutine Profile = "goroutine"
+	Mutex     Profile = "mutex"
+	Block     Profile = "block"
+)
+
+// ErrCaptureInProgress is returned when another capture is running, because only one CPU profile can run at a time.
+var ErrCaptureInProgress = errors.New("another profile capture is in progress")
+
+// AllProfiles returns all supported profiles.
+func AllProfiles() []Profile {
+	return []Profile{CPU, Heap, Goroutine, Mutex, Block}
+}
+
+// ParseProfiles parses the comma-separated profile names, i.e: "cpu,heap". Empty string returns AllProfiles.
+func ParseProfiles(s string) ([]Profile, error) {
+	if strings.TrimSpace(s) == "" {
+		return AllProfiles(), nil
+	}
+
+	seen := make(map[Profile]struct{})
+	profiles := make([]Profile, 0)
+	for _, name := range strings.Split(s, ",") {
+		profile := Profile(strings.ToLower(strings.TrimSpace(name)))
+		switch profile {
+		case CPU, Heap, Goroutine, Mutex, Block:
+		case "":
+			continue
+		default:
+			return nil, fmt.Errorf("unknown profile '%s', must be one of cpu, heap, goroutine, mutex or block", name)
+		}
+
+		if _, exist := seen[profile]; exist {
+			continue
+		}
+
+		seen[profile] = struct{}{}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
+
+// SetRates enables the mutex and block profiles, both are disabled by default in Go runtime.
+// mutexFraction reports on average 1/mutexFraction of the mutex contention events, 0 disables it.
+// blockRate samples one blocking event per blockRate nanoseconds spent blocked, 0 disables it.
+func SetRates(mutexFraction, blockRate int) {
+	runtime.SetMutexProfileFraction(mutexFraction)
+	runtime.SetBlockProfileRate(blockRate)
+}
+
+// captureLock allows only one capture at a time.
+var captureLock sync.Mutex
+
+// Capture records the CPU profile during the duration, then takes the snapshot of the other profiles at the end,
+// and writes them into w as zip archive with one <profile>.pprof file each, readable by "go tool pprof".
+// The heap, goroutine, mutex and block profiles are cumulative since the process started (or since the rate is set).
+//
+// It stops early and returns the context error when ctx is done, i.e. the client disconnects.
+func Capture(ctx context.Context, w io.Writer, duration time.Duration, profiles ...Profile) error {
+	if duration <= 0 {
+		return fmt.Errorf("profile duration must be positive")
+	}
+
+	if len(profiles) <= 0 {
+		profiles = AllProfiles()
+	}
+
+	if !captureLock.TryLock() {
+		return ErrCaptureInProgress
+	}
+	defer captureLock.Unlock()
+
+	archive := zip.NewWriter(w)
+
+	cpuStarted := false
+	if slices.Contains(profiles, CPU) {
+		f, err := createFile(archive, CPU)
+		if err != nil {
+			return fmt.Errorf("cannot create cpu profile file: %w", err)
+		}
+
+		// It fails when the CPU profile is already started by the other, i.e. /debug/pprof/profile.
+		if err = pprof.StartCPUProfile(f); err != nil {
+			return fmt.Errorf("%w: %w", ErrCaptureInProgress, err)
+		}
+
+		cpuStarted = true
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	var ctxErr error
+	select {
+	case <-ctx.Done():
+		ctxErr = ctx.Err()
+	case <-timer.C:
+	}
+
+	if cpuStarted {
+		pprof.StopCPUProfile()
+	}
+
+	if ctxErr != nil {
+		return ctxErr
+	}
+
+	for _, profile := range profiles {
+		if profile == CPU {
+			continue
+		}
+
+		p := pprof.Lookup(string(profile))
+		if p == nil {
+			return fmt.Errorf("profile %s is not available", profile)
+		}
+
+		f, err := createFile(archive, profile)
+		if err != nil {
+			return fmt.Errorf("cannot create %s profile file: %w", profile, err)
+		}
+
+		if err = p.WriteTo(f, 0); err != nil {
+			return fmt.Errorf("cannot write %s profile: %w", profile, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("cannot write profile archive: %w", err)
+	}
+
+	return nil
+}
+
+// createFile adds <profile>.pprof into the archive without compression, because the profile is already gzipped.
+func createFile(archive *zip.Writer, profile Profile) (io.Writer, error) {
+	return archive.CreateHeader(&zip.FileHeader{
+		Name:     string(profile) + ".pprof",
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+}
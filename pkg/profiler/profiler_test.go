@@ -0,0 +1,121 @@
+package profiler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/profiler"
+)
+
+func TestParseProfiles(t *testing.T) {
+	profiles, err := profiler.ParseProfiles("")
+	assert.NoError(t, err)
+	assert.Equal(t, profiler.AllProfiles(), profiles)
+
+	profiles, err = profiler.ParseProfiles(" CPU, heap,cpu,")
+	assert.NoError(t, err)
+	assert.Equal(t, []profiler.Profile{profiler.CPU, profiler.Heap}, profiles)
+
+	_, err = profiler.ParseProfiles("cpu,threadcreate")
+	assert.Error(t, err)
+}
+
+func archiveFiles(t *testing.T, b []byte) map[string][]byte {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		files[f.Name] = content
+	}
+
+	return files
+}
+
+func TestCapture(t *testing.T) {
+	t.Run("all profiles", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := profiler.Capture(context.Background(), &buf, 100*time.Millisecond)
+		require.NoError(t, err)
+
+		files := archiveFiles(t, buf.Bytes())
+		assert.Len(t, files, 5)
+		for _, profile := range profiler.AllProfiles() {
+			content, exist := files[string(profile)+".pprof"]
+			assert.True(t, exist, profile)
+			assert.NotEmpty(t, content, profile)
+		}
+	})
+
+	t.Run("selected profiles", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := profiler.Capture(context.Background(), &buf, 10*time.Millisecond, profiler.Heap, profiler.Goroutine)
+		require.NoError(t, err)
+
+		files := archiveFiles(t, buf.Bytes())
+		assert.Len(t, files, 2)
+		assert.Contains(t, files, "heap.pprof")
+		assert.Contains(t, files, "goroutine.pprof")
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		err := profiler.Capture(context.Background(), io.Discard, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("concurrent capture", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			// retry when the probe below holds the lock first
+			for {
+				err := profiler.Capture(ctx, io.Discard, time.Minute, profiler.Heap)
+				if !errors.Is(err, profiler.ErrCaptureInProgress) {
+					done <- err
+					return
+				}
+			}
+		}()
+
+		// The probe uses canceled context, so it releases the lock immediately when it gets the lock first.
+		canceledCtx, cancelProbe := context.WithCancel(context.Background())
+		cancelProbe()
+
+		assert.Eventually(t, func() bool {
+			err := profiler.Capture(canceledCtx, io.Discard, time.Minute, profiler.Heap)
+			return errors.Is(err, profiler.ErrCaptureInProgress)
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := profiler.Capture(ctx, io.Discard, time.Minute)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+}
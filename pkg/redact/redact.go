@@ -0,0 +1,419 @@
+// Package redact masks the sensitive data (credentials, personal data, etc.) before it is written into the log.
+// The same Policy is meant to be shared by the incoming (server) and outgoing (client) HTTP logging middlewares,
+// so the secrets are masked consistently on both sides.
+package redact
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask is the default replacement of the sensitive value.
+const Mask = "[REDACTED]"
+
+var (
+	// DefaultHeaders is the header names which value is always masked by Default policy.
+	DefaultHeaders = []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"Cookie",
+		"Set-Cookie",
+		"X-Api-Key",
+		"X-Auth-Token",
+	}
+
+	// DefaultBodyKeys is the JSON key names which value is masked at any depth by Default policy.
+	DefaultBodyKeys = []string{
+		"password",
+		"secret",
+		"token",
+		"access_token",
+		"refresh_token",
+		"api_key",
+		"card_number",
+		"cvv",
+	}
+
+	// DefaultQueryParams is the query parameter names which value is masked by Default policy.
+	DefaultQueryParams = []string{
+		"token",
+		"access_token",
+		"refresh_token",
+		"api_key",
+		"password",
+	}
+)
+
+type Opt func(*Policy) error
+
+// WithHeaders masks the value of these header names. Header name is case-insensitive.
+func WithHeaders(names ...string) Opt {
+	return func(p *Policy) error {
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			p.headers[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+
+		return nil
+	}
+}
+
+// WithBodyKeys masks the value of JSON object key with these names at any depth.
+// The key is matched case-insensitively and ignoring "_" and "-", so "card_number" also matches "cardNumber".
+func WithBodyKeys(keys ...string) Opt {
+	return func(p *Policy) error {
+		for _, key := range keys {
+			key = normalizeKey(key)
+			if key == "" {
+				continue
+			}
+
+			p.bodyKeys[key] = struct{}{}
+		}
+
+		return nil
+	}
+}
+
+// WithBodyPaths masks the value at these JSON paths, separated by dot, i.e: "user.pin" or "$.items.*.cvv".
+// The "*" matches any object key or array element, and number matches the array index.
+// Unlike WithBodyKeys, the path is matched exactly (case-sensitive).
+func WithBodyPaths(paths ...string) Opt {
+	return func(p *Policy) error {
+		for _, path := range paths {
+			path = strings.TrimSpace(path)
+			path = strings.TrimPrefix(path, "$")
+			path = strings.TrimPrefix(path, ".")
+			if path == "" {
+				continue
+			}
+
+			segments := strings.Split(path, ".")
+			for _, segment := range segments {
+				if segment == "" {
+					return fmt.Errorf("invalid redact body path '%s': empty segment", path)
+				}
+			}
+
+			p.bodyPaths = append(p.bodyPaths, segments)
+		}
+
+		return nil
+	}
+}
+
+// WithQueryParams masks the value of these query parameter names. The name is case-insensitive.
+func WithQueryParams(names ...string) Opt {
+	return func(p *Policy) error {
+		for _, name := range names {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+
+			p.queryParams[name] = struct{}{}
+		}
+
+		return nil
+	}
+}
+
+// WithPatterns masks any part of the string value (header, query parameter, JSON string value or non-JSON body)
+// that matches these regular expressions, i.e: `\b\d{16}\b` for card number, or `(?i)bearer\s+\S+`.
+func WithPatterns(patterns ...string) Opt {
+	return func(p *Policy) error {
+		for _, pattern := range patterns {
+			if strings.TrimSpace(pattern) == "" {
+				continue
+			}
+
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid redact pattern '%s': %w", pattern, err)
+			}
+
+			p.patterns = append(p.patterns, re)
+		}
+
+		return nil
+	}
+}
+
+// WithMask replaces the default mask "[REDACTED]".
+func WithMask(mask string) Opt {
+	return func(p *Policy) error {
+		p.mask = mask
+		return nil
+	}
+}
+
+// Policy decides which part of HTTP request and response must be masked.
+// A nil *Policy is valid and masks nothing. The Policy is safe for concurrent use.
+type Policy struct {
+	mask        string
+	headers     map[string]struct{}
+	bodyKeys    map[string]struct{}
+	bodyPaths   [][]string
+	queryParams map[string]struct{}
+	patterns    []*regexp.Regexp
+}
+
+// New creates empty Policy, then applies the opts. Use Default to start with the default deny lists.
+func New(opts ...Opt) (*Policy, error) {
+	p := &Policy{
+		mask:        Mask,
+		headers:     make(map[string]struct{}),
+		bodyKeys:    make(map[string]struct{}),
+		bodyPaths:   make([][]string, 0),
+		queryParams: make(map[string]struct{}),
+		patterns:    make([]*regexp.Regexp, 0),
+	}
+
+	for _, opt := range opts {
+		err := opt(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Default returns Policy that masks DefaultHeaders, DefaultBodyKeys and DefaultQueryParams,
+// then applies the additional opts.
+func Default(opts ...Opt) (*Policy, error) {
+	defaults := []Opt{
+		WithHeaders(DefaultHeaders...),
+		WithBodyKeys(DefaultBodyKeys...),
+		WithQueryParams(DefaultQueryParams...),
+	}
+
+	return New(append(defaults, opts...)...)
+}
+
+// Header returns the copy of header with the sensitive values masked.
+func (p *Policy) Header(h http.Header) http.Header {
+	if p == nil || h == nil {
+		return h
+	}
+
+	out := make(http.Header, len(h))
+	for k, values := range h {
+		masked := make([]string, len(values))
+		for i, v := range values {
+			if _, deny := p.headers[http.CanonicalHeaderKey(k)]; deny {
+				masked[i] = p.mask
+				continue
+			}
+
+			masked[i] = p.String(v)
+		}
+
+		out[k] = masked
+	}
+
+	return out
+}
+
+// Query returns the copy of query parameters with the sensitive values masked.
+func (p *Policy) Query(values url.Values) url.Values {
+	if p == nil || values == nil {
+		return values
+	}
+
+	out := make(url.Values, len(values))
+	for k, vs := range values {
+		masked := make([]string, len(vs))
+		for i, v := range vs {
+			if _, deny := p.queryParams[strings.ToLower(k)]; deny {
+				masked[i] = p.mask
+				continue
+			}
+
+			masked[i] = p.String(v)
+		}
+
+		out[k] = masked
+	}
+
+	return out
+}
+
+// URL returns the string of URL with the sensitive query parameters and user password masked.
+func (p *Policy) URL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	if p == nil {
+		return u.String()
+	}
+
+	out := *u
+	if out.User != nil {
+		if _, hasPassword := out.User.Password(); hasPassword {
+			out.User = url.UserPassword(out.User.Username(), p.mask)
+		}
+	}
+
+	if out.RawQuery != "" {
+		out.RawQuery = p.Query(out.Query()).Encode()
+	}
+
+	return p.String(out.String())
+}
+
+// Body returns the copy of decoded JSON value (result of json.Unmarshal into any)
+// with the sensitive values masked. The form body (url.Values) is masked using both query parameter and body key names.
+// The raw string body (i.e. truncated JSON) is masked by looking for the `"key": value` pair of deny listed keys,
+// then using the patterns.
+func (p *Policy) Body(v any) any {
+	if p == nil {
+		return v
+	}
+
+	switch val := v.(type) {
+	case string:
+		return p.String(p.maskRawKeys(val))
+	case url.Values:
+		return p.maskForm(val)
+	}
+
+	v = p.maskKeys(v)
+	for _, path := range p.bodyPaths {
+		v = p.maskPath(v, path)
+	}
+
+	return v
+}
+
+// String masks any part of s that matches the patterns.
+func (p *Policy) String(s string) string {
+	if p == nil {
+		return s
+	}
+
+	for _, re := range p.patterns {
+		s = re.ReplaceAllLiteralString(s, p.mask)
+	}
+
+	return s
+}
+
+// maskKeys walks the value, copying the objects and arrays, masking the deny listed keys and string values.
+func (p *Policy) maskKeys(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, child := range val {
+			if _, deny := p.bodyKeys[normalizeKey(k)]; deny {
+				out[k] = p.mask
+				continue
+			}
+
+			out[k] = p.maskKeys(child)
+		}
+
+		return out
+
+	case []any:
+		out := make([]any, len(val))
+		for i, child := range val {
+			out[i] = p.maskKeys(child)
+		}
+
+		return out
+
+	case string:
+		return p.String(val)
+
+	default:
+		return v
+	}
+}
+
+// maskPath masks the value at the path. The value must already be copied by maskKeys.
+func (p *Policy) maskPath(v any, path []string) any {
+	if len(path) <= 0 {
+		return p.mask
+	}
+
+	segment, rest := path[0], path[1:]
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if segment == "*" || segment == k {
+				val[k] = p.maskPath(child, rest)
+			}
+		}
+
+		return val
+
+	case []any:
+		for i, child := range val {
+			if segment == "*" || segment == fmt.Sprint(i) {
+				val[i] = p.maskPath(child, rest)
+			}
+		}
+
+		return val
+
+	default:
+		// The path doesn't exist in this value.
+		return v
+	}
+}
+
+// rawJSONPair matches `"key": value` in JSON string, including the string value cut in the middle.
+var rawJSONPair = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]*)`)
+
+func (p *Policy) maskRawKeys(s string) string {
+	if len(p.bodyKeys) <= 0 {
+		return s
+	}
+
+	return rawJSONPair.ReplaceAllStringFunc(s, func(pair string) string {
+		m := rawJSONPair.FindStringSubmatch(pair)
+		if _, deny := p.bodyKeys[normalizeKey(m[1])]; !deny {
+			return pair
+		}
+
+		return `"` + m[1] + `"` + m[2] + `"` + p.mask + `"`
+	})
+}
+
+func (p *Policy) maskForm(values url.Values) url.Values {
+	out := make(url.Values, len(values))
+	for k, vs := range values {
+		_, denyQuery := p.queryParams[strings.ToLower(k)]
+		_, denyKey := p.bodyKeys[normalizeKey(k)]
+
+		masked := make([]string, len(vs))
+		for i, v := range vs {
+			if denyQuery || denyKey {
+				masked[i] = p.mask
+				continue
+			}
+
+			masked[i] = p.String(v)
+		}
+
+		out[k] = masked
+	}
+
+	return out
+}
+
+func normalizeKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	key = strings.ReplaceAll(key, "_", "")
+	key = strings.ReplaceAll(key, "-", "")
+	return key
+}
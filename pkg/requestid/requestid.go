@@ -0,0 +1,64 @@
+// Package requestid carries the request identifier through context.Context,
+// so it can be attached to logs, error responses and outgoing requests.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header used to accept, echo and forward the request ID.
+const Header = "X-Request-ID"
+
+// MaxLength is the maximum length of incoming request ID that is accepted, a longer one is replaced with the new one.
+const MaxLength = 128
+
+type ctxKey struct{}
+
+// New generates a new random request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// NewContext returns a copy of ctx with the request ID. Empty ID returns the ctx as is.
+func NewContext(ctx context.Context, id string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if id == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID in the ctx, or empty string if it doesn't exist.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Valid returns true if the incoming request ID is safe to be used as is:
+// not empty, at most MaxLength characters, and only contains letters, digits, and one of "-_.:+=/".
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '=', c == '/':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,45 @@
+package requestid_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/requestid"
+)
+
+func TestNew(t *testing.T) {
+	id := requestid.New()
+	assert.True(t, requestid.Valid(id))
+	assert.NotEqual(t, id, requestid.New())
+}
+
+func TestContext(t *testing.T) {
+	t.Run("nil context", func(t *testing.T) {
+		assert.Empty(t, requestid.FromContext(nil))
+
+		ctx := requestid.NewContext(nil, "abc")
+		assert.Equal(t, "abc", requestid.FromContext(ctx))
+	})
+
+	t.Run("empty id", func(t *testing.T) {
+		ctx := requestid.NewContext(context.Background(), "")
+		assert.Empty(t, requestid.FromContext(ctx))
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		ctx := requestid.NewContext(context.Background(), "abc")
+		assert.Equal(t, "abc", requestid.FromContext(ctx))
+	})
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, requestid.Valid("4bf92f3577b34da6a3ce929d0e0e4736"))
+	assert.True(t, requestid.Valid("Root=1-5759e988-bd862e3fe1be46a994272793"))
+	assert.False(t, requestid.Valid(""))
+	assert.False(t, requestid.Valid(strings.Repeat("a", requestid.MaxLength+1)))
+	assert.False(t, requestid.Valid("abc\ndef"))
+	assert.False(t, requestid.Valid("<script>"))
+}
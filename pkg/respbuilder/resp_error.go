@@ -1,10 +1,13 @@
 package respbuilder
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
 	"github.com/labstack/echo/v4"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/requestid"
 )
 
 type RespCodeErr int
@@ -12,12 +15,20 @@ type RespCodeErr int
 const (
 	ErrUnknown RespCodeErr = iota
 	ErrGeneral
+	ErrUnhealthy
+	ErrRequestTooLarge
+	ErrOverloaded
+	ErrTooManyRequests
 )
 
 // respMapErr must use prefix E to indicate the error
 var respMapErr = map[RespCodeErr]RespStructureErr{
-	ErrUnknown: {Code: "E", Status: "ErrUnknown"},
-	ErrGeneral: {Code: "E0", Status: "ErrorGeneral"},
+	ErrUnknown:         {Code: "E", Status: "ErrUnknown"},
+	ErrGeneral:         {Code: "E0", Status: "ErrorGeneral"},
+	ErrUnhealthy:       {Code: "E1", Status: "ErrorUnhealthy"},
+	ErrRequestTooLarge: {Code: "E2", Status: "ErrorRequestTooLarge"},
+	ErrOverloaded:      {Code: "E3", Status: "ErrorOverloaded"},
+	ErrTooManyRequests: {Code: "E4", Status: "ErrorTooManyRequests"},
 }
 
 // RespCodeErrStatus get RespStructureErr based on response code.
@@ -48,9 +59,13 @@ type RespError struct {
 
 // RespStructureErr to ensure that json marshalled version will not sort the keys
 type RespStructureErr struct {
-	Code   string     `json:"code"`
-	Status string     `json:"status"`
-	Error  *RespError `json:"error,omitempty"`
+	Code      string     `json:"code"`
+	Status    string     `json:"status"`
+	RequestID string     `json:"requestId,omitempty"`
+	Error     *RespError `json:"error,omitempty"`
+
+	// Data is optional detail of the error for the client, i.e. the health check report.
+	Data interface{} `json:"data,omitempty"`
 }
 
 // Error return RespStructureErr as contract when response is not success.
@@ -85,3 +100,17 @@ func Error(respCode RespCodeErr, err error, reasons ...string) RespStructureErr
 
 	return r
 }
+
+// ErrorCtx is the same as Error, and also includes the request ID from the context (see requestid.FromContext),
+// so the client can report the request ID to be traced in the logs.
+func ErrorCtx(ctx context.Context, respCode RespCodeErr, err error, reasons ...string) RespStructureErr {
+	r := Error(respCode, err, reasons...)
+	r.RequestID = requestid.FromContext(ctx)
+	return r
+}
+
+// WithData returns the copy of RespStructureErr with the error detail data.
+func (r RespStructureErr) WithData(data interface{}) RespStructureErr {
+	r.Data = data
+	return r
+}
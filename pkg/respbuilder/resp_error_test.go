@@ -1,6 +1,8 @@
 package respbuilder_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -8,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/yusufsyaifudin/go-project-structure/pkg/requestid"
 	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
 )
 
@@ -61,3 +64,35 @@ func TestError(t *testing.T) {
 		assert.Equal(t, respbuilder.RespCodeErrStatus(errCode).Status, resp.Status)
 	})
 }
+
+func TestErrorCtx(t *testing.T) {
+	t.Run("with request id", func(t *testing.T) {
+		ctx := requestid.NewContext(context.Background(), "req-123")
+		resp := respbuilder.ErrorCtx(ctx, respbuilder.ErrGeneral, fmt.Errorf("error content"), "error warning")
+		assert.Equal(t, "req-123", resp.RequestID)
+		assert.Equal(t, respbuilder.RespCodeErrStatus(respbuilder.ErrGeneral).Code, resp.Code)
+		assert.Equal(t, "error content", resp.Error.Message)
+		assert.Equal(t, []string{"error warning"}, resp.Error.Reasons)
+	})
+
+	t.Run("without request id", func(t *testing.T) {
+		resp := respbuilder.ErrorCtx(context.Background(), respbuilder.ErrGeneral, fmt.Errorf("error content"))
+		assert.Empty(t, resp.RequestID)
+
+		b, err := json.Marshal(resp)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(b), "requestId")
+		assert.NotContains(t, string(b), "data")
+	})
+}
+
+func TestRespStructureErr_WithData(t *testing.T) {
+	resp := respbuilder.Error(respbuilder.ErrUnhealthy, fmt.Errorf("unhealthy"))
+	withData := resp.WithData(map[string]string{"db": "failing"})
+	assert.Nil(t, resp.Data)
+
+	b, err := json.Marshal(withData)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"data":{"db":"failing"}`)
+	assert.Contains(t, string(b), `"code":"E1"`)
+}
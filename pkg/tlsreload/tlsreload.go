@@ -0,0 +1,368 @@
+// Package tlsreload builds the server tls.Config from certificate files,
+// and reloads them without restart when the files change, i.e. rotated by cert-manager.
+package tlsreload
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Opt func(*Reloader) error
+
+// WithCertificate set the PEM encoded certificate (may include the intermediate chain) and private key files.
+func WithCertificate(certFile, keyFile string) Opt {
+	return func(r *Reloader) error {
+		certFile, keyFile = strings.TrimSpace(certFile), strings.TrimSpace(keyFile)
+		if certFile == "" || keyFile == "" {
+			return fmt.Errorf("tls certificate and key file cannot be empty")
+		}
+
+		r.certFile = certFile
+		r.keyFile = keyFile
+		return nil
+	}
+}
+
+// WithClientCA set the PEM encoded CA bundle file to verify the client certificate (mTLS).
+// The client certificate is required unless changed using WithClientAuth.
+func WithClientCA(caFile string) Opt {
+	return func(r *Reloader) error {
+		caFile = strings.TrimSpace(caFile)
+		if caFile == "" {
+			return fmt.Errorf("tls client ca file cannot be empty")
+		}
+
+		r.clientCAFile = caFile
+		return nil
+	}
+}
+
+// WithClientAuth set the client certificate policy when the client CA is set.
+// Default to tls.RequireAndVerifyClientCert.
+func WithClientAuth(auth tls.ClientAuthType) Opt {
+	return func(r *Reloader) error {
+		switch auth {
+		case tls.RequireAndVerifyClientCert, tls.VerifyClientCertIfGiven:
+			r.clientAuth = auth
+			return nil
+		default:
+			return fmt.Errorf("tls client auth must be either require or verify if given, got %s", auth)
+		}
+	}
+}
+
+// WithMinVersion set the minimum TLS version, see ParseVersion. Default to TLS 1.2.
+func WithMinVersion(version uint16) Opt {
+	return func(r *Reloader) error {
+		if version < tls.VersionTLS12 || version > tls.VersionTLS13 {
+			return fmt.Errorf("tls min version must be TLS 1.2 or TLS 1.3")
+		}
+
+		r.minVersion = version
+		return nil
+	}
+}
+
+// WithCipherSuites set the allowed cipher suites of TLS 1.2, see ParseCipherSuites.
+// TLS 1.3 cipher suites are not configurable. Default to the Go secure cipher suites.
+func WithCipherSuites(suites []uint16) Opt {
+	return func(r *Reloader) error {
+		r.cipherSuites = suites
+		return nil
+	}
+}
+
+// WithNextProtos set the application protocols advertised in ALPN. Default to h2 and http/1.1.
+func WithNextProtos(protos ...string) Opt {
+	return func(r *Reloader) error {
+		r.nextProtos = protos
+		return nil
+	}
+}
+
+// WithReloadInterval set how often the files are checked for changes. Zero disables the automatic reload.
+// Default to 10 seconds.
+func WithReloadInterval(interval time.Duration) Opt {
+	return func(r *Reloader) error {
+		if interval < 0 {
+			return fmt.Errorf("tls reload interval cannot be negative")
+		}
+
+		r.interval = interval
+		return nil
+	}
+}
+
+// WithLogger set the logger to report the reload result.
+func WithLogger(logger *slog.Logger) Opt {
+	return func(r *Reloader) error {
+		if logger == nil {
+			return fmt.Errorf("cannot use nil logger")
+		}
+
+		r.logger = logger
+		return nil
+	}
+}
+
+// fileState is used to detect the file change, cert-manager replaces the file (symlink swap) on renewal.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader holds the current certificate and client CA pool.
+// It polls the files every reload interval and swaps them atomically when changed,
+// so the new handshake uses the new certificate while the established connections are not affected.
+// When the reload failed (i.e. the files are partially written), the previous certificate is kept.
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	clientAuth   tls.ClientAuthType
+	minVersion   uint16
+	cipherSuites []uint16
+	nextProtos   []string
+	interval     time.Duration
+	logger       *slog.Logger
+
+	// config is the tls.Config returned for each handshake, rebuilt on every reload.
+	config atomic.Pointer[tls.Config]
+
+	lock   sync.Mutex
+	states map[string]fileState
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+	wg        sync.WaitGroup
+}
+
+func New(opts ...Opt) (*Reloader, error) {
+	r := &Reloader{
+		clientAuth: tls.RequireAndVerifyClientCert,
+		minVersion: tls.VersionTLS12,
+		nextProtos: []string{"h2", "http/1.1"},
+		interval:   10 * time.Second,
+		logger:     slog.Default(),
+		states:     make(map[string]fileState),
+		closeChan:  make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		err := opt(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.certFile == "" {
+		return nil, fmt.Errorf("tls certificate is required")
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	if r.interval > 0 {
+		r.wg.Add(1)
+		go r.watch()
+	}
+
+	return r, nil
+}
+
+// TLSConfig returns the server tls.Config which always uses the latest loaded certificate and client CA.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:   r.minVersion,
+		CipherSuites: r.cipherSuites,
+		NextProtos:   r.nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.config.Load(), nil
+		},
+	}
+}
+
+// Reload loads the files and swaps the tls.Config, the current config is kept if error.
+func (r *Reloader) Reload() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	states := make(map[string]fileState)
+	for _, file := range r.files() {
+		state, err := statFile(file)
+		if err != nil {
+			return err
+		}
+
+		states[file] = state
+	}
+
+	// Remember the files state even if the reload failed, so it is retried only when the files change again,
+	// i.e. the key file is written after the certificate file.
+	r.states = states
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load tls certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   r.minVersion,
+		CipherSuites: r.cipherSuites,
+		NextProtos:   r.nextProtos,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if r.clientCAFile != "" {
+		b, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("cannot read tls client ca file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no valid certificate found in tls client ca file %s", r.clientCAFile)
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = r.clientAuth
+	}
+
+	r.config.Store(config)
+	return nil
+}
+
+// Close stops watching the files.
+func (r *Reloader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closeChan)
+	})
+
+	r.wg.Wait()
+	return nil
+}
+
+func (r *Reloader) watch() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.closeChan:
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+
+			if err := r.Reload(); err != nil {
+				r.logger.Error("cannot reload tls certificate, keep using the previous one", slog.Any("error", err))
+				continue
+			}
+
+			r.logger.Info("tls certificate reloaded", slog.String("cert_file", r.certFile))
+		}
+	}
+}
+
+// changed returns true if any file is modified since the last successful reload.
+func (r *Reloader) changed() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, file := range r.files() {
+		state, err := statFile(file)
+		if err != nil {
+			// the file may be in the middle of replacement, check again in the next tick
+			continue
+		}
+
+		if state != r.states[file] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+
+	return files
+}
+
+// statFile follows the symlink, because Kubernetes secret volume swaps the symlink of the data directory.
+func statFile(file string) (fileState, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileState{}, fmt.Errorf("cannot stat tls file: %w", err)
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// ParseVersion parses TLS version: 1.2 or 1.3.
+func ParseVersion(s string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "TLS") {
+	case "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown tls version '%s', must be 1.2 or 1.3", s)
+	}
+}
+
+// ParseCipherSuites parses the cipher suite names, i.e. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+// Only the secure cipher suites are allowed, see tls.CipherSuites. Empty names returns nil to use the Go default.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	available := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		available[suite.Name] = suite.ID
+	}
+
+	var suites []uint16
+	var errs error
+	for _, name := range names {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		id, ok := available[name]
+		if !ok {
+			errs = errors.Join(errs, fmt.Errorf("unknown or insecure tls cipher suite '%s'", name))
+			continue
+		}
+
+		suites = append(suites, id)
+	}
+
+	return suites, errs
+}
+
+// ParseClientAuth parses the client certificate policy: require (default) or optional.
+func ParseClientAuth(s string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "require":
+		return tls.RequireAndVerifyClientCert, nil
+	case "optional":
+		return tls.VerifyClientCertIfGiven, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("unknown tls client auth '%s', must be require or optional", s)
+	}
+}
@@ -0,0 +1,345 @@
+package tlsreload_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/tlsreload"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate signed by the parent, or self-signed CA when parent is nil.
+func newTestCert(t *testing.T, serial int64, commonName string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string, modTime time.Time) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, c.certPEM(), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	// Set the modification time explicitly, the file system time resolution may be too coarse within a test.
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+// serve starts TLS server which only completes the handshake, then returns its address.
+func serve(t *testing.T, config *tls.Config) string {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// handshake returns the serial number of the server certificate.
+// The client certificate is always sent, even when it is not signed by the CA requested by the server.
+func handshake(addr string, roots *x509.CertPool, clientCerts ...tls.Certificate) (*big.Int, error) {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		RootCAs:    roots,
+		ServerName: "localhost",
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if len(clientCerts) <= 0 {
+				return &tls.Certificate{}, nil
+			}
+
+			return &clientCerts[0], nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// TLS 1.3 client certificate is verified after the client handshake completes, read to get the server alert.
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = conn.Read(make([]byte, 1)); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber, nil
+}
+
+func TestNew(t *testing.T) {
+	t.Run("without certificate", func(t *testing.T) {
+		reloader, err := tlsreload.New()
+		assert.Nil(t, reloader)
+		assert.Error(t, err)
+	})
+
+	t.Run("file not exist", func(t *testing.T) {
+		dir := t.TempDir()
+		reloader, err := tlsreload.New(tlsreload.WithCertificate(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")))
+		assert.Nil(t, reloader)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid client ca", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+		newTestCert(t, 1, "server", nil).write(t, certFile, keyFile, time.Now())
+		require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+
+		reloader, err := tlsreload.New(tlsreload.WithCertificate(certFile, keyFile), tlsreload.WithClientCA(caFile))
+		assert.Nil(t, reloader)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid option", func(t *testing.T) {
+		reloader, err := tlsreload.New(tlsreload.WithMinVersion(tls.VersionTLS10))
+		assert.Nil(t, reloader)
+		assert.Error(t, err)
+	})
+}
+
+func TestReloader_HotReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	ca := newTestCert(t, 1, "ca", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	modTime := time.Now().Add(-time.Minute)
+	newTestCert(t, 100, "server", ca).write(t, certFile, keyFile, modTime)
+
+	reloader, err := tlsreload.New(
+		tlsreload.WithCertificate(certFile, keyFile),
+		tlsreload.WithReloadInterval(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, reloader.Close())
+	}()
+
+	addr := serve(t, reloader.TLSConfig())
+
+	serial, err := handshake(addr, roots)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, serial.Int64())
+
+	// rotate the certificate
+	newTestCert(t, 200, "server", ca).write(t, certFile, keyFile, modTime.Add(time.Second))
+
+	assert.Eventually(t, func() bool {
+		serial, err = handshake(addr, roots)
+		return err == nil && serial.Int64() == 200
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// the broken file keeps the previous certificate
+	require.NoError(t, os.WriteFile(certFile, []byte("partially written"), 0o600))
+	time.Sleep(50 * time.Millisecond)
+
+	serial, err = handshake(addr, roots)
+	require.NoError(t, err)
+	assert.EqualValues(t, 200, serial.Int64())
+	assert.Error(t, reloader.Reload())
+}
+
+func TestReloader_ClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+
+	ca := newTestCert(t, 1, "ca", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	newTestCert(t, 100, "server", ca).write(t, certFile, keyFile, time.Now())
+	require.NoError(t, os.WriteFile(caFile, ca.certPEM(), 0o600))
+
+	client := newTestCert(t, 300, "client", ca).tlsCertificate()
+	untrusted := newTestCert(t, 400, "untrusted", newTestCert(t, 2, "other-ca", nil)).tlsCertificate()
+
+	t.Run("require", func(t *testing.T) {
+		reloader, err := tlsreload.New(
+			tlsreload.WithCertificate(certFile, keyFile),
+			tlsreload.WithClientCA(caFile),
+		)
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, reloader.Close())
+		}()
+
+		addr := serve(t, reloader.TLSConfig())
+
+		_, err = handshake(addr, roots, client)
+		assert.NoError(t, err)
+
+		_, err = handshake(addr, roots)
+		assert.Error(t, err)
+
+		_, err = handshake(addr, roots, untrusted)
+		assert.Error(t, err)
+	})
+
+	t.Run("optional", func(t *testing.T) {
+		reloader, err := tlsreload.New(
+			tlsreload.WithCertificate(certFile, keyFile),
+			tlsreload.WithClientCA(caFile),
+			tlsreload.WithClientAuth(tls.VerifyClientCertIfGiven),
+			tlsreload.WithReloadInterval(0),
+		)
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, reloader.Close())
+		}()
+
+		addr := serve(t, reloader.TLSConfig())
+
+		_, err = handshake(addr, roots, client)
+		assert.NoError(t, err)
+
+		_, err = handshake(addr, roots)
+		assert.NoError(t, err)
+
+		_, err = handshake(addr, roots, untrusted)
+		assert.Error(t, err)
+	})
+}
+
+func TestReloader_MinVersion(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	ca := newTestCert(t, 1, "ca", nil)
+	newTestCert(t, 100, "server", ca).write(t, certFile, keyFile, time.Now())
+
+	reloader, err := tlsreload.New(
+		tlsreload.WithCertificate(certFile, keyFile),
+		tlsreload.WithMinVersion(tls.VersionTLS13),
+	)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, reloader.Close())
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	addr := serve(t, reloader.TLSConfig())
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: roots, ServerName: "localhost", MaxVersion: tls.VersionTLS12})
+	if err == nil {
+		_ = conn.Close()
+	}
+	assert.Error(t, err)
+}
+
+func TestParseVersion(t *testing.T) {
+	version, err := tlsreload.ParseVersion("1.2")
+	assert.NoError(t, err)
+	assert.EqualValues(t, tls.VersionTLS12, version)
+
+	version, err = tlsreload.ParseVersion("TLS1.3")
+	assert.NoError(t, err)
+	assert.EqualValues(t, tls.VersionTLS13, version)
+
+	_, err = tlsreload.ParseVersion("1.0")
+	assert.Error(t, err)
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	suites, err := tlsreload.ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", " "})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, suites)
+
+	suites, err = tlsreload.ParseCipherSuites(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, suites)
+
+	// insecure cipher suite is not allowed
+	_, err = tlsreload.ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	assert.Error(t, err)
+}
+
+func TestParseClientAuth(t *testing.T) {
+	auth, err := tlsreload.ParseClientAuth("")
+	assert.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, auth)
+
+	auth, err = tlsreload.ParseClientAuth("optional")
+	assert.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, auth)
+
+	_, err = tlsreload.ParseClientAuth("none")
+	assert.Error(t, err)
+}
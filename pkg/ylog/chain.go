@@ -0,0 +1,26 @@
+package ylog
+
+import (
+	"log/slog"
+)
+
+// Middleware wraps the next slog.Handler, i.e. NewLevelHandler or NewOTEL.
+type Middleware func(next slog.Handler) slog.Handler
+
+// Chain wraps the base handler (i.e. slog.JSONHandler) with the middlewares.
+// The first middleware is the outermost handler, which means it receives Enabled, WithAttrs and WithGroup first.
+//
+// For example, Chain(jsonHandler, LevelMiddleware(ctrl), OTELMiddleware(opts)) returns
+// LevelHandler -> OpenTelemetry -> slog.JSONHandler.
+func Chain(base slog.Handler, middlewares ...Middleware) slog.Handler {
+	handler := base
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] == nil {
+			continue
+		}
+
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
@@ -0,0 +1,172 @@
+package ylog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// ParseLevel parse level string such as DEBUG, INFO, WARN, ERROR (case-insensitive).
+// It also accepts offset such as INFO+2 as described in slog.Level.UnmarshalText.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(s)))
+	if err != nil {
+		return level, fmt.Errorf("invalid log level '%s': %w", s, err)
+	}
+
+	return level, nil
+}
+
+// LevelController holds the minimum log level that can be changed at runtime.
+// Beside the global level, it can hold the level override per logger group,
+// which is the name passed into slog.Logger.WithGroup. Nested groups are joined using dot, i.e: "db.query".
+type LevelController struct {
+	global *slog.LevelVar
+
+	lock   sync.RWMutex
+	groups map[string]slog.Level
+}
+
+var _ slog.Leveler = (*LevelController)(nil)
+
+// NewLevelController creates LevelController with the initial global level.
+func NewLevelController(level slog.Level) *LevelController {
+	global := &slog.LevelVar{}
+	global.Set(level)
+
+	return &LevelController{
+		global: global,
+		groups: make(map[string]slog.Level),
+	}
+}
+
+// Level returns the global level, it implements slog.Leveler.
+func (c *LevelController) Level() slog.Level {
+	return c.global.Level()
+}
+
+// SetLevel change the global level.
+func (c *LevelController) SetLevel(level slog.Level) {
+	c.global.Set(level)
+}
+
+// GroupLevel returns the level override for the group, return false if no override is set.
+func (c *LevelController) GroupLevel(group string) (slog.Level, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	level, exist := c.groups[group]
+	return level, exist
+}
+
+// SetGroupLevel set the level override for the group.
+// The override applies to the group and all nested groups unless the nested group has its own override.
+func (c *LevelController) SetGroupLevel(group string, level slog.Level) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.groups[group] = level
+}
+
+// UnsetGroupLevel removes the level override for the group, so it will follow the global level again.
+func (c *LevelController) UnsetGroupLevel(group string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.groups, group)
+}
+
+// GroupLevels returns copy of all group level overrides.
+func (c *LevelController) GroupLevels() map[string]slog.Level {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	out := make(map[string]slog.Level, len(c.groups))
+	for group, level := range c.groups {
+		out[group] = level
+	}
+
+	return out
+}
+
+// Enabled reports whether the level is enabled for the given group path.
+// The most specific group override wins, otherwise the global level is used.
+func (c *LevelController) Enabled(groups []string, level slog.Level) bool {
+	minLevel := c.global.Level()
+
+	c.lock.RLock()
+	if len(c.groups) > 0 {
+		for i := len(groups); i > 0; i-- {
+			if groupLevel, exist := c.groups[strings.Join(groups[:i], ".")]; exist {
+				minLevel = groupLevel
+				break
+			}
+		}
+	}
+	c.lock.RUnlock()
+
+	return level >= minLevel
+}
+
+// LevelHandler is slog.Handler that decides whether the record is enabled using LevelController.
+// It tracks the group names, so the level can be different for each logger group.
+type LevelHandler struct {
+	next   slog.Handler
+	ctrl   *LevelController
+	groups []string
+}
+
+var _ slog.Handler = (*LevelHandler)(nil)
+
+// NewLevelHandler wraps the parent handler. The level in the parent handler is not consulted anymore,
+// because the LevelController is the one who decides.
+func NewLevelHandler(parent slog.Handler, ctrl *LevelController) slog.Handler {
+	return &LevelHandler{
+		next:   parent,
+		ctrl:   ctrl,
+		groups: nil,
+	}
+}
+
+// LevelMiddleware returns Middleware that wraps the next handler using NewLevelHandler.
+func LevelMiddleware(ctrl *LevelController) Middleware {
+	return func(next slog.Handler) slog.Handler {
+		return NewLevelHandler(next, ctrl)
+	}
+}
+
+func (l *LevelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return l.ctrl.Enabled(l.groups, level)
+}
+
+func (l *LevelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return l.next.Handle(ctx, record)
+}
+
+func (l *LevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LevelHandler{
+		next:   l.next.WithAttrs(attrs),
+		ctrl:   l.ctrl,
+		groups: l.groups,
+	}
+}
+
+func (l *LevelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return l
+	}
+
+	// copy to prevent the sibling handler share the same backing array
+	groups := make([]string, 0, len(l.groups)+1)
+	groups = append(groups, l.groups...)
+	groups = append(groups, name)
+
+	return &LevelHandler{
+		next:   l.next.WithGroup(name),
+		ctrl:   l.ctrl,
+		groups: groups,
+	}
+}
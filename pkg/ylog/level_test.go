@@ -0,0 +1,97 @@
+package ylog_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/ylog"
+)
+
+func TestParseLevel(t *testing.T) {
+	testCases := []struct {
+		Input string
+		Level slog.Level
+		Err   bool
+	}{
+		{Input: "DEBUG", Level: slog.LevelDebug},
+		{Input: "info", Level: slog.LevelInfo},
+		{Input: " warn ", Level: slog.LevelWarn},
+		{Input: "ERROR", Level: slog.LevelError},
+		{Input: "INFO+2", Level: slog.LevelInfo + 2},
+		{Input: "verbose", Err: true},
+		{Input: "", Err: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Input, func(t *testing.T) {
+			level, err := ylog.ParseLevel(testCase.Input)
+			if testCase.Err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.Level, level)
+		})
+	}
+}
+
+func TestLevelController(t *testing.T) {
+	t.Run("global level", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		assert.Equal(t, slog.LevelInfo, ctrl.Level())
+		assert.False(t, ctrl.Enabled(nil, slog.LevelDebug))
+		assert.True(t, ctrl.Enabled(nil, slog.LevelInfo))
+
+		ctrl.SetLevel(slog.LevelDebug)
+		assert.True(t, ctrl.Enabled(nil, slog.LevelDebug))
+	})
+
+	t.Run("group level override", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		ctrl.SetGroupLevel("db", slog.LevelDebug)
+		ctrl.SetGroupLevel("db.query", slog.LevelError)
+
+		assert.True(t, ctrl.Enabled([]string{"db"}, slog.LevelDebug))
+		assert.True(t, ctrl.Enabled([]string{"db", "conn"}, slog.LevelDebug))
+		assert.False(t, ctrl.Enabled([]string{"db", "query"}, slog.LevelWarn))
+		assert.False(t, ctrl.Enabled([]string{"http"}, slog.LevelDebug))
+
+		level, exist := ctrl.GroupLevel("db")
+		assert.True(t, exist)
+		assert.Equal(t, slog.LevelDebug, level)
+		assert.Len(t, ctrl.GroupLevels(), 2)
+
+		ctrl.UnsetGroupLevel("db")
+		_, exist = ctrl.GroupLevel("db")
+		assert.False(t, exist)
+		assert.False(t, ctrl.Enabled([]string{"db"}, slog.LevelDebug))
+	})
+}
+
+func TestLevelHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctrl := ylog.NewLevelController(slog.LevelInfo)
+	logger := slog.New(ylog.NewLevelHandler(slog.NewJSONHandler(buf, nil), ctrl))
+	ctx := context.Background()
+
+	logger.DebugContext(ctx, "global debug")
+	assert.Empty(t, buf.String())
+
+	ctrl.SetGroupLevel("db", slog.LevelDebug)
+	dbLogger := logger.With(slog.String("foo", "bar")).WithGroup("db")
+
+	dbLogger.DebugContext(ctx, "db debug")
+	require.NotEmpty(t, buf.String())
+	assert.Contains(t, buf.String(), `"msg":"db debug"`)
+	buf.Reset()
+
+	// sibling group must not affected by override of another group
+	logger.WithGroup("http").DebugContext(ctx, "http debug")
+	assert.Empty(t, buf.String())
+}
@@ -2,82 +2,184 @@ package ylog
 
 import (
 	"context"
-	"go.opentelemetry.io/otel/trace"
 	"log/slog"
+
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// otelLoggerName is the instrumentation scope name of the emitted OpenTelemetry LogRecord.
+const otelLoggerName = "github.com/yusufsyaifudin/go-project-structure/pkg/ylog"
+
 type OpenTelemetryOption struct {
 	ContextExtractor func(context.Context) []slog.Attr
+
+	// LoggerProvider is optional. If set, each record is also emitted as OpenTelemetry LogRecord
+	// (i.e. exported by OTLP logs exporter), with the attributes and groups preserved.
+	// The trace context is taken from the context passed into the logger, i.e. slog.InfoContext(ctx, ...).
+	LoggerProvider log.LoggerProvider
+}
+
+// group is the group opened by WithGroup, with the attributes added after it was opened.
+type group struct {
+	name  string
+	attrs []slog.Attr
 }
 
+// OpenTelemetry is slog.Handler that adds trace_id, span_id, trace_flags and trace_sampled
+// from the span context, and the attributes from OpenTelemetryOption.ContextExtractor.
+//
+// These fields are always at the top level of the log, even when the logger is inside a group.
+// To do so, the groups are not passed into the next handler, but kept in this handler
+// and the record attributes are nested into the groups when the record is handled.
+// That means any handler that needs the group name (i.e. LevelHandler) must be placed before this handler.
 type OpenTelemetry struct {
 	next        slog.Handler
 	opts        *OpenTelemetryOption
+	otelLogger  log.Logger
 	enabledFunc func(ctx context.Context, level slog.Level, next func(context.Context, slog.Level) bool) bool
+
+	// attrs is the attributes added before any group opened. It is already passed into the next handler,
+	// but is kept to be emitted as OpenTelemetry LogRecord attributes.
+	attrs  []slog.Attr
+	groups []group
 }
 
 var _ slog.Handler = (*OpenTelemetry)(nil)
 
 func NewOTEL(parent slog.Handler, opts *OpenTelemetryOption) slog.Handler {
+	var otelLogger log.Logger
+	if opts != nil && opts.LoggerProvider != nil {
+		otelLogger = opts.LoggerProvider.Logger(otelLoggerName)
+	}
+
 	return &OpenTelemetry{
-		next: parent,
-		opts: opts,
+		next:       parent,
+		opts:       opts,
+		otelLogger: otelLogger,
 		enabledFunc: func(ctx context.Context, level slog.Level, next func(context.Context, slog.Level) bool) bool {
 			return next(ctx, level)
 		},
 	}
 }
 
+// OTELMiddleware returns Middleware that wraps the next handler using NewOTEL.
+func OTELMiddleware(opts *OpenTelemetryOption) Middleware {
+	return func(next slog.Handler) slog.Handler {
+		return NewOTEL(next, opts)
+	}
+}
+
 func (z *OpenTelemetry) Enabled(ctx context.Context, level slog.Level) bool {
 	return z.enabledFunc(ctx, level, z.next.Enabled)
 }
 
 func (z *OpenTelemetry) Handle(ctx context.Context, record slog.Record) error {
-	// From [OTEP0114](https://github.com/open-telemetry/oteps/pull/114)
-	// https://github.com/open-telemetry/opentelemetry-specification/blob/v1.18.0/specification/logs/README.md?plain=1#L474-L526
-	spanCtx := trace.SpanContextFromContext(ctx)
-	record.AddAttrs(
-		slog.String("trace_id", spanCtx.TraceID().String()),
-		slog.String("span_id", spanCtx.SpanID().String()),
-	)
-
+	ctxAttrs := make([]slog.Attr, 0)
 	if z.opts != nil && z.opts.ContextExtractor != nil {
 		for _, ctxAttr := range z.opts.ContextExtractor(ctx) {
 			switch ctxAttr.Key {
-			case "trace_id", "span_id":
+			case "trace_id", "span_id", "trace_flags", "trace_sampled":
 				continue
 			}
-			
-			record.AddAttrs(ctxAttr)
+
+			ctxAttrs = append(ctxAttrs, ctxAttr)
 		}
 	}
 
-	return z.next.Handle(ctx, record)
+	recordAttrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		recordAttrs = append(recordAttrs, attr)
+		return true
+	})
+
+	groupedAttrs := nestGroups(z.groups, recordAttrs)
+
+	// The trace id and span id is not added as attributes, since it is the part of OpenTelemetry LogRecord itself.
+	if z.otelLogger != nil {
+		emitOTEL(ctx, z.otelLogger, record, z.attrs, ctxAttrs, groupedAttrs)
+	}
+
+	// From [OTEP0114](https://github.com/open-telemetry/oteps/pull/114)
+	// https://github.com/open-telemetry/opentelemetry-specification/blob/v1.18.0/specification/logs/README.md?plain=1#L474-L526
+	spanCtx := trace.SpanContextFromContext(ctx)
+
+	// Record is rebuilt, so the trace fields are at the top level instead of inside the group.
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	out.AddAttrs(
+		slog.String("trace_id", spanCtx.TraceID().String()),
+		slog.String("span_id", spanCtx.SpanID().String()),
+		slog.String("trace_flags", spanCtx.TraceFlags().String()),
+		slog.Bool("trace_sampled", spanCtx.IsSampled()),
+	)
+	out.AddAttrs(ctxAttrs...)
+	out.AddAttrs(groupedAttrs...)
+
+	return z.next.Handle(ctx, out)
 }
 
 // WithAttrs is called when user call slog.With(attrs...)
 // or when slog.With(attrs..).WithGroup(name).With(attrs...)
 //
-// If user already call WithGroup, the appended valued will append to this group name child's.
-// We need to call AttributeFormatterFunc here before appended to the OpenTelemetry logger buffer.
+// If user already call WithGroup, the attributes are kept in this handler under the last group,
+// otherwise they are passed into the next handler.
 func (z *OpenTelemetry) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &OpenTelemetry{
-		next:        z.next.WithAttrs(attrs),
-		enabledFunc: z.enabledFunc,
+	if len(attrs) <= 0 {
+		return z
+	}
+
+	h := z.clone()
+	if len(h.groups) <= 0 {
+		h.next = z.next.WithAttrs(attrs)
+		h.attrs = append(h.attrs, attrs...)
+		return h
 	}
+
+	last := &h.groups[len(h.groups)-1]
+	last.attrs = append(last.attrs[:len(last.attrs):len(last.attrs)], attrs...)
+	return h
 }
 
 // WithGroup is called when user call slog.WithGroup(name).
-// This open a new namespae on OpenTelemetry
+// The group is kept in this handler, so the trace fields can be put at the top level.
 func (z *OpenTelemetry) WithGroup(name string) slog.Handler {
 	// https://cs.opensource.google/go/x/exp/+/46b07846:slog/handler.go;l=247
 	if name == "" {
 		return z
 	}
 
+	h := z.clone()
+	h.groups = append(h.groups, group{name: name})
+	return h
+}
+
+// clone copies the handler, including the options.
+// The slices are copied with full capacity, so appending into the clone never modifies the original handler.
+func (z *OpenTelemetry) clone() *OpenTelemetry {
 	return &OpenTelemetry{
-		next:        z.next.WithGroup(name),
+		next:        z.next,
 		opts:        z.opts,
+		otelLogger:  z.otelLogger,
 		enabledFunc: z.enabledFunc,
+		attrs:       z.attrs[:len(z.attrs):len(z.attrs)],
+		groups:      append(make([]group, 0, len(z.groups)+1), z.groups...),
 	}
 }
+
+// nestGroups puts the attributes into the groups, from the innermost group to the outermost group.
+// Like slog.JSONHandler, the group without any attribute is omitted.
+func nestGroups(groups []group, attrs []slog.Attr) []slog.Attr {
+	for i := len(groups) - 1; i >= 0; i-- {
+		groupAttrs := make([]slog.Attr, 0, len(groups[i].attrs)+len(attrs))
+		groupAttrs = append(groupAttrs, groups[i].attrs...)
+		groupAttrs = append(groupAttrs, attrs...)
+		if len(groupAttrs) <= 0 {
+			attrs = nil
+			continue
+		}
+
+		attrs = []slog.Attr{{Key: groups[i].name, Value: slog.GroupValue(groupAttrs...)}}
+	}
+
+	return attrs
+}
@@ -1 +1,224 @@
 package ylog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/ylog"
+)
+
+type ctxKey struct{}
+
+// newJSONLogger returns logger using ylog.OpenTelemetry on top of slog.JSONHandler, writing into the buffer.
+func newJSONLogger(opts *ylog.OpenTelemetryOption, middlewares ...ylog.Middleware) (*slog.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	jsonHandler := slog.NewJSONHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// remove time, so the output is deterministic
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	middlewares = append(middlewares, ylog.OTELMiddleware(opts))
+	return slog.New(ylog.Chain(jsonHandler, middlewares...)), buf
+}
+
+// decodeLines decodes each line of JSON log.
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	out := make([]map[string]any, 0)
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]any
+		require.NoError(t, dec.Decode(&line))
+		out = append(out, line)
+	}
+
+	return out
+}
+
+func sampledContext() (context.Context, trace.SpanContext) {
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x0a},
+		SpanID:     trace.SpanID{0x0b},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	return trace.ContextWithSpanContext(context.Background(), spanCtx), spanCtx
+}
+
+var requestIDExtractor = &ylog.OpenTelemetryOption{
+	ContextExtractor: func(ctx context.Context) []slog.Attr {
+		requestID, _ := ctx.Value(ctxKey{}).(string)
+		return []slog.Attr{
+			slog.String("request_id", requestID),
+			slog.String("trace_id", "must not override"),
+		}
+	},
+}
+
+func TestOpenTelemetry_Handle(t *testing.T) {
+	t.Run("without span", func(t *testing.T) {
+		logger, buf := newJSONLogger(nil)
+		logger.Info("hello", "a", 1)
+
+		lines := decodeLines(t, buf)
+		require.Len(t, lines, 1)
+		assert.Equal(t, map[string]any{
+			"level":         "INFO",
+			"msg":           "hello",
+			"trace_id":      "00000000000000000000000000000000",
+			"span_id":       "0000000000000000",
+			"trace_flags":   "00",
+			"trace_sampled": false,
+			"a":             float64(1),
+		}, lines[0])
+	})
+
+	t.Run("trace flags and sampled bit", func(t *testing.T) {
+		logger, buf := newJSONLogger(nil)
+
+		ctx, spanCtx := sampledContext()
+		logger.InfoContext(ctx, "sampled")
+
+		notSampled := trace.ContextWithSpanContext(context.Background(), spanCtx.WithTraceFlags(0))
+		logger.InfoContext(notSampled, "not sampled")
+
+		lines := decodeLines(t, buf)
+		require.Len(t, lines, 2)
+		assert.Equal(t, spanCtx.TraceID().String(), lines[0]["trace_id"])
+		assert.Equal(t, spanCtx.SpanID().String(), lines[0]["span_id"])
+		assert.Equal(t, "01", lines[0]["trace_flags"])
+		assert.Equal(t, true, lines[0]["trace_sampled"])
+		assert.Equal(t, "00", lines[1]["trace_flags"])
+		assert.Equal(t, false, lines[1]["trace_sampled"])
+	})
+
+	t.Run("trace fields are top level inside group", func(t *testing.T) {
+		logger, buf := newJSONLogger(requestIDExtractor)
+
+		ctx, spanCtx := sampledContext()
+		ctx = context.WithValue(ctx, ctxKey{}, "req-1")
+		logger.With("a", 1).WithGroup("g1").With("b", 2).WithGroup("g2").InfoContext(ctx, "grouped", "c", 3)
+
+		lines := decodeLines(t, buf)
+		require.Len(t, lines, 1)
+		assert.Equal(t, map[string]any{
+			"level":         "INFO",
+			"msg":           "grouped",
+			"a":             float64(1),
+			"trace_id":      spanCtx.TraceID().String(),
+			"span_id":       spanCtx.SpanID().String(),
+			"trace_flags":   "01",
+			"trace_sampled": true,
+			"request_id":    "req-1",
+			"g1": map[string]any{
+				"b": float64(2),
+				"g2": map[string]any{
+					"c": float64(3),
+				},
+			},
+		}, lines[0])
+	})
+
+	t.Run("empty group is omitted", func(t *testing.T) {
+		logger, buf := newJSONLogger(nil)
+		logger.WithGroup("g1").With("a", 1).WithGroup("g2").Info("msg")
+		logger.WithGroup("g1").Info("msg")
+
+		lines := decodeLines(t, buf)
+		require.Len(t, lines, 2)
+		assert.Equal(t, map[string]any{"a": float64(1)}, lines[0]["g1"])
+		assert.NotContains(t, lines[1], "g1")
+	})
+
+	t.Run("empty group name is ignored", func(t *testing.T) {
+		logger, buf := newJSONLogger(nil)
+		logger.WithGroup("").Info("msg", "a", 1)
+
+		lines := decodeLines(t, buf)
+		require.Len(t, lines, 1)
+		assert.Equal(t, float64(1), lines[0]["a"])
+	})
+}
+
+func TestOpenTelemetry_OptionsPreserved(t *testing.T) {
+	t.Run("with attrs", func(t *testing.T) {
+		logger, buf := newJSONLogger(requestIDExtractor)
+
+		ctx := context.WithValue(context.Background(), ctxKey{}, "req-1")
+		logger.With("a", 1).InfoContext(ctx, "msg")
+
+		lines := decodeLines(t, buf)
+		require.Len(t, lines, 1)
+		assert.Equal(t, "req-1", lines[0]["request_id"])
+		assert.Equal(t, float64(1), lines[0]["a"])
+		assert.Equal(t, "00000000000000000000000000000000", lines[0]["trace_id"])
+	})
+
+	t.Run("with group then attrs", func(t *testing.T) {
+		logger, buf := newJSONLogger(requestIDExtractor)
+
+		ctx := context.WithValue(context.Background(), ctxKey{}, "req-2")
+		logger.WithGroup("g").With("a", 1).InfoContext(ctx, "msg")
+
+		lines := decodeLines(t, buf)
+		require.Len(t, lines, 1)
+		assert.Equal(t, "req-2", lines[0]["request_id"])
+		assert.Equal(t, map[string]any{"a": float64(1)}, lines[0]["g"])
+	})
+}
+
+func TestOpenTelemetry_SiblingLoggers(t *testing.T) {
+	logger, buf := newJSONLogger(nil)
+
+	parent := logger.WithGroup("g").With("a", 1)
+	child1 := parent.With("b", 1)
+	child2 := parent.With("c", 2)
+
+	child1.Info("child1")
+	child2.Info("child2")
+	parent.Info("parent")
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 3)
+	assert.Equal(t, map[string]any{"a": float64(1), "b": float64(1)}, lines[0]["g"])
+	assert.Equal(t, map[string]any{"a": float64(1), "c": float64(2)}, lines[1]["g"])
+	assert.Equal(t, map[string]any{"a": float64(1)}, lines[2]["g"])
+}
+
+func TestChain(t *testing.T) {
+	t.Run("nil middleware is skipped", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		handler := ylog.Chain(slog.NewJSONHandler(buf, nil), nil)
+		slog.New(handler).Info("msg")
+		assert.Contains(t, buf.String(), `"msg":"msg"`)
+	})
+
+	t.Run("level handler sees the group", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		ctrl.SetGroupLevel("db", slog.LevelDebug)
+
+		logger, buf := newJSONLogger(nil, ylog.LevelMiddleware(ctrl))
+		logger.Debug("dropped")
+		logger.WithGroup("db").Debug("kept", "query", "SELECT 1")
+
+		lines := decodeLines(t, buf)
+		require.Len(t, lines, 1)
+		assert.Equal(t, "kept", lines[0]["msg"])
+		assert.Equal(t, map[string]any{"query": "SELECT 1"}, lines[0]["db"])
+		assert.Contains(t, lines[0], "trace_id")
+	})
+}
@@ -0,0 +1,114 @@
+package ylog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// emitOTEL converts the slog.Record into OpenTelemetry LogRecord and emits it.
+// The attributes are in the same order and structure as the record passed into the next slog.Handler:
+// handler attributes, context attributes, then the record attributes nested in the groups.
+func emitOTEL(ctx context.Context, logger log.Logger, record slog.Record, handlerAttrs, ctxAttrs, groupedAttrs []slog.Attr) {
+	var logRecord log.Record
+	logRecord.SetTimestamp(record.Time)
+	logRecord.SetObservedTimestamp(time.Now())
+	logRecord.SetSeverity(otelSeverity(record.Level))
+	logRecord.SetSeverityText(record.Level.String())
+	logRecord.SetBody(log.StringValue(record.Message))
+	logRecord.AddAttributes(otelKeyValues(handlerAttrs)...)
+	logRecord.AddAttributes(otelKeyValues(ctxAttrs)...)
+	logRecord.AddAttributes(otelKeyValues(groupedAttrs)...)
+
+	logger.Emit(ctx, logRecord)
+}
+
+// otelSeverity maps slog.Level into OpenTelemetry severity number.
+// slog.LevelDebug, slog.LevelInfo, slog.LevelWarn and slog.LevelError are mapped into
+// log.SeverityDebug, log.SeverityInfo, log.SeverityWarn and log.SeverityError respectively,
+// and the level between them is mapped into DEBUG2, INFO3, etc.
+func otelSeverity(level slog.Level) log.Severity {
+	// slog.LevelDebug is -4 and log.SeverityDebug is 5, the distance is 9 for every pair.
+	severity := int(level) + int(log.SeverityInfo)
+	switch {
+	case severity < int(log.SeverityTrace1):
+		return log.SeverityTrace1
+	case severity > int(log.SeverityFatal4):
+		return log.SeverityFatal4
+	default:
+		return log.Severity(severity)
+	}
+}
+
+func otelKeyValues(attrs []slog.Attr) []log.KeyValue {
+	kvs := make([]log.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		attr.Value = attr.Value.Resolve()
+
+		// Ignore empty attribute like slog.JSONHandler does.
+		if attr.Equal(slog.Attr{}) {
+			continue
+		}
+
+		if attr.Value.Kind() == slog.KindGroup {
+			groupAttrs := otelKeyValues(attr.Value.Group())
+			if len(groupAttrs) <= 0 {
+				continue
+			}
+
+			// Inline the group attributes when the key is empty, like slog.JSONHandler does.
+			if attr.Key == "" {
+				kvs = append(kvs, groupAttrs...)
+				continue
+			}
+
+			kvs = append(kvs, log.Map(attr.Key, groupAttrs...))
+			continue
+		}
+
+		kvs = append(kvs, log.KeyValue{Key: attr.Key, Value: otelValue(attr.Value)})
+	}
+
+	return kvs
+}
+
+func otelValue(v slog.Value) log.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		return log.StringValue(v.String())
+	case slog.KindInt64:
+		return log.Int64Value(v.Int64())
+	case slog.KindUint64:
+		u := v.Uint64()
+		if u > math.MaxInt64 {
+			return log.StringValue(fmt.Sprint(u))
+		}
+
+		return log.Int64Value(int64(u))
+	case slog.KindFloat64:
+		return log.Float64Value(v.Float64())
+	case slog.KindBool:
+		return log.BoolValue(v.Bool())
+	case slog.KindDuration:
+		return log.Int64Value(v.Duration().Nanoseconds())
+	case slog.KindTime:
+		return log.StringValue(v.Time().Format(time.RFC3339Nano))
+	case slog.KindGroup:
+		return log.MapValue(otelKeyValues(v.Group())...)
+	default:
+		switch val := v.Any().(type) {
+		case []byte:
+			return log.BytesValue(val)
+		case error:
+			return log.StringValue(val.Error())
+		case fmt.Stringer:
+			return log.StringValue(val.String())
+		default:
+			return log.StringValue(fmt.Sprintf("%+v", val))
+		}
+	}
+}
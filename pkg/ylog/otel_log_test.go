@@ -0,0 +1,197 @@
+package ylog_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/ylog"
+)
+
+// memLogExporter keeps all exported log records in memory.
+type memLogExporter struct {
+	lock    sync.Mutex
+	records []sdklog.Record
+}
+
+var _ sdklog.Exporter = (*memLogExporter)(nil)
+
+func (m *memLogExporter) Export(_ context.Context, records []sdklog.Record) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, record := range records {
+		m.records = append(m.records, record.Clone())
+	}
+
+	return nil
+}
+
+func (m *memLogExporter) Shutdown(_ context.Context) error   { return nil }
+func (m *memLogExporter) ForceFlush(_ context.Context) error { return nil }
+
+func (m *memLogExporter) Records() []sdklog.Record {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.records
+}
+
+func newOTELLogger(t *testing.T, opts *ylog.OpenTelemetryOption) (*slog.Logger, *memLogExporter) {
+	t.Helper()
+
+	exporter := &memLogExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+
+	if opts == nil {
+		opts = &ylog.OpenTelemetryOption{}
+	}
+	opts.LoggerProvider = provider
+
+	handler := slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return slog.New(ylog.NewOTEL(handler, opts)), exporter
+}
+
+// logValue converts log.Value into plain Go value, so it can be compared easily.
+func logValue(v log.Value) any {
+	switch v.Kind() {
+	case log.KindString:
+		return v.AsString()
+	case log.KindInt64:
+		return v.AsInt64()
+	case log.KindFloat64:
+		return v.AsFloat64()
+	case log.KindBool:
+		return v.AsBool()
+	case log.KindBytes:
+		return v.AsBytes()
+	case log.KindMap:
+		return logAttributes(v.AsMap())
+	default:
+		return nil
+	}
+}
+
+func logAttributes(kvs []log.KeyValue) map[string]any {
+	out := make(map[string]any)
+	for _, kv := range kvs {
+		out[kv.Key] = logValue(kv.Value)
+	}
+
+	return out
+}
+
+func recordAttributes(record sdklog.Record) map[string]any {
+	kvs := make([]log.KeyValue, 0)
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		kvs = append(kvs, kv)
+		return true
+	})
+
+	return logAttributes(kvs)
+}
+
+func TestOpenTelemetry_LoggerProvider(t *testing.T) {
+	t.Run("severity", func(t *testing.T) {
+		logger, exporter := newOTELLogger(t, nil)
+
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Log(context.Background(), slog.LevelInfo+2, "info+2")
+		logger.Warn("warn")
+		logger.Error("error")
+		logger.Log(context.Background(), slog.LevelError+100, "too high")
+
+		records := exporter.Records()
+		require.Len(t, records, 6)
+
+		expected := []log.Severity{
+			log.SeverityDebug,
+			log.SeverityInfo,
+			log.SeverityInfo3,
+			log.SeverityWarn,
+			log.SeverityError,
+			log.SeverityFatal4,
+		}
+		for i, severity := range expected {
+			assert.Equal(t, severity, records[i].Severity())
+		}
+
+		assert.Equal(t, "info", records[1].Body().AsString())
+		assert.Equal(t, "INFO+2", records[2].SeverityText())
+	})
+
+	t.Run("attributes and groups are preserved", func(t *testing.T) {
+		logger, exporter := newOTELLogger(t, nil)
+
+		logger.With("a", 1).WithGroup("g").With("b", "two").WithGroup("empty").Info("msg",
+			"c", true,
+			slog.Group("nested", "d", 1.5),
+			"dur", time.Second,
+			"err", errors.New("oops"),
+		)
+
+		records := exporter.Records()
+		require.Len(t, records, 1)
+		assert.Equal(t, map[string]any{
+			"a": int64(1),
+			"g": map[string]any{
+				"b": "two",
+				"empty": map[string]any{
+					"c":      true,
+					"nested": map[string]any{"d": 1.5},
+					"dur":    time.Second.Nanoseconds(),
+					"err":    "oops",
+				},
+			},
+		}, recordAttributes(records[0]))
+	})
+
+	t.Run("empty group is omitted", func(t *testing.T) {
+		logger, exporter := newOTELLogger(t, nil)
+
+		logger.With("a", 1).WithGroup("g").Info("msg")
+
+		records := exporter.Records()
+		require.Len(t, records, 1)
+		assert.Equal(t, map[string]any{"a": int64(1)}, recordAttributes(records[0]))
+	})
+
+	t.Run("trace context and context attributes", func(t *testing.T) {
+		logger, exporter := newOTELLogger(t, &ylog.OpenTelemetryOption{
+			ContextExtractor: func(ctx context.Context) []slog.Attr {
+				return []slog.Attr{slog.String("request_id", "abc"), slog.String("trace_id", "ignored")}
+			},
+		})
+
+		spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{2},
+			TraceFlags: trace.FlagsSampled,
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+		logger.WithGroup("g").InfoContext(ctx, "msg", "a", 1)
+
+		records := exporter.Records()
+		require.Len(t, records, 1)
+		assert.Equal(t, spanCtx.TraceID(), records[0].TraceID())
+		assert.Equal(t, spanCtx.SpanID(), records[0].SpanID())
+		assert.Equal(t, map[string]any{
+			"request_id": "abc",
+			"g":          map[string]any{"a": int64(1)},
+		}, recordAttributes(records[0]))
+	})
+}
@@ -0,0 +1,318 @@
+package handleradmin
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/ylog"
+	"github.com/yusufsyaifudin/go-project-structure/transport/restapi"
+)
+
+type Opt func(*AdminHandler) error
+
+// WithToken set the bearer token to authenticate the admin endpoints.
+// If token is empty, none of the admin endpoints will be registered.
+func WithToken(token string) Opt {
+	return func(handler *AdminHandler) error {
+		handler.token = strings.TrimSpace(token)
+		return nil
+	}
+}
+
+// WithLevelController set the log level controller to read and change at runtime.
+func WithLevelController(ctrl *ylog.LevelController) Opt {
+	return func(handler *AdminHandler) error {
+		if ctrl == nil {
+			return fmt.Errorf("cannot use nil log level controller")
+		}
+
+		handler.levelController = ctrl
+		return nil
+	}
+}
+
+// WithTraceBuffer set the in-memory span exporter to inspect the recent traces.
+func WithTraceBuffer(buffer *oteltracer.MemoryExporter) Opt {
+	return func(handler *AdminHandler) error {
+		if buffer == nil {
+			return fmt.Errorf("cannot use nil trace buffer")
+		}
+
+		handler.traceBuffer = buffer
+		return nil
+	}
+}
+
+// WithProfiling enables the net/http/pprof endpoints under /debug/pprof and the profile capture endpoint
+// /debug/profile/capture, the capture duration is limited to maxDuration.
+func WithProfiling(maxDuration time.Duration) Opt {
+	return func(handler *AdminHandler) error {
+		if maxDuration <= 0 {
+			return fmt.Errorf("profile max duration must be positive")
+		}
+
+		handler.profileMaxDuration = maxDuration
+		return nil
+	}
+}
+
+type AdminHandler struct {
+	token              string
+	levelController    *ylog.LevelController
+	traceBuffer        *oteltracer.MemoryExporter
+	profileMaxDuration time.Duration
+
+	// revertLock guard revertTimers, the key is the group name (empty string for global level).
+	revertLock   sync.Mutex
+	revertTimers map[string]*pendingRevert
+}
+
+// pendingRevert is the scheduled revert of timed level override.
+// revert restores the level before the first override, even if the override is extended several times.
+type pendingRevert struct {
+	timer  *time.Timer
+	revert func()
+}
+
+// Ensure AdminHandler implements restapi.EchoRouter to successfully register endpoint to Echo framework.
+var _ restapi.EchoRouter = (*AdminHandler)(nil)
+
+func New(opts ...Opt) (*AdminHandler, error) {
+	adminHandler := &AdminHandler{
+		revertTimers: make(map[string]*pendingRevert),
+	}
+
+	for _, opt := range opts {
+		err := opt(adminHandler)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return adminHandler, nil
+}
+
+func (a *AdminHandler) Router(e *echo.Echo) {
+	if a.token == "" {
+		slog.Warn("admin endpoints are disabled because the admin token is empty")
+		return
+	}
+
+	g := e.Group("/admin", a.authenticate)
+
+	if a.levelController != nil {
+		g.GET("/log-level", a.GetLogLevel)
+		g.PUT("/log-level", a.SetLogLevel)
+		g.DELETE("/log-level", a.UnsetLogLevel)
+	}
+
+	if a.traceBuffer != nil {
+		g.GET("/traces", a.ListTraces)
+		g.GET("/traces/:traceId", a.GetTrace)
+	}
+
+	if a.profileMaxDuration > 0 {
+		a.profileRouter(e.Group("/debug", a.authenticate))
+	}
+}
+
+// authenticate only allows request with header "Authorization: Bearer <token>".
+func (a *AdminHandler) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, found := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			err := fmt.Errorf("invalid admin token")
+			return c.JSON(http.StatusUnauthorized, respbuilder.ErrorCtx(c.Request().Context(), respbuilder.ErrGeneral, err))
+		}
+
+		return next(c)
+	}
+}
+
+type LogLevelResp struct {
+	Level  string            `json:"level"`
+	Groups map[string]string `json:"groups,omitempty"`
+}
+
+// GetLogLevel returns the current global log level and the level override per logger group.
+func (a *AdminHandler) GetLogLevel(c echo.Context) error {
+	return c.JSON(http.StatusOK, respbuilder.Ok(respbuilder.Success, a.logLevelResp()))
+}
+
+type SetLogLevelReq struct {
+	// Level is one of DEBUG, INFO, WARN, ERROR.
+	Level string `json:"level"`
+
+	// Group is the logger group name to override, empty means the global level.
+	Group string `json:"group,omitempty"`
+
+	// Duration is optional, if set the level will be reverted to the previous value after this duration.
+	// For example "5m" to enable debug log for five minutes.
+	Duration string `json:"duration,omitempty"`
+}
+
+// SetLogLevel change the global log level or the level of the logger group.
+func (a *AdminHandler) SetLogLevel(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req SetLogLevelReq
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	level, err := ylog.ParseLevel(req.Level)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, respbuilder.ErrorCtx(c.Request().Context(), respbuilder.ErrGeneral, err))
+	}
+
+	var revertAfter time.Duration
+	if req.Duration != "" {
+		revertAfter, err = time.ParseDuration(req.Duration)
+		if err != nil || revertAfter <= 0 {
+			err = fmt.Errorf("invalid duration '%s', must be positive duration such as 5m", req.Duration)
+			return c.JSON(http.StatusBadRequest, respbuilder.ErrorCtx(c.Request().Context(), respbuilder.ErrGeneral, err))
+		}
+	}
+
+	group := strings.TrimSpace(req.Group)
+	a.setLevel(group, level, revertAfter)
+
+	slog.WarnContext(ctx, "log level changed",
+		slog.String("group", group),
+		slog.String("level", level.String()),
+		slog.String("revert_after", revertAfter.String()),
+	)
+
+	return c.JSON(http.StatusOK, respbuilder.Ok(respbuilder.Success, a.logLevelResp()))
+}
+
+// UnsetLogLevel removes the level override of the logger group passed in query param "group".
+func (a *AdminHandler) UnsetLogLevel(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	group := strings.TrimSpace(c.QueryParam("group"))
+	if group == "" {
+		err := fmt.Errorf("query param group is required")
+		return c.JSON(http.StatusBadRequest, respbuilder.ErrorCtx(c.Request().Context(), respbuilder.ErrGeneral, err))
+	}
+
+	a.cancelRevert(group)
+	a.levelController.UnsetGroupLevel(group)
+
+	slog.WarnContext(ctx, "log level override removed", slog.String("group", group))
+
+	return c.JSON(http.StatusOK, respbuilder.Ok(respbuilder.Success, a.logLevelResp()))
+}
+
+// setLevel change the level of the group (empty string for global level).
+// If revertAfter is positive, the level is reverted after that duration to the level before the override.
+// When a timed override is already pending for the group, the original level is kept and only the deadline moves,
+// so extending the override never makes the temporary level permanent.
+func (a *AdminHandler) setLevel(group string, level slog.Level, revertAfter time.Duration) {
+	a.revertLock.Lock()
+	defer a.revertLock.Unlock()
+
+	var revert func()
+	if pending, exist := a.revertTimers[group]; exist {
+		pending.timer.Stop()
+		delete(a.revertTimers, group)
+		revert = pending.revert
+	} else {
+		revert = a.restoreLevelFunc(group)
+	}
+
+	if group == "" {
+		a.levelController.SetLevel(level)
+	} else {
+		a.levelController.SetGroupLevel(group, level)
+	}
+
+	if revertAfter <= 0 {
+		return
+	}
+
+	pending := &pendingRevert{revert: revert}
+	pending.timer = time.AfterFunc(revertAfter, func() {
+		a.revertLock.Lock()
+		defer a.revertLock.Unlock()
+
+		// skip if this timer is already replaced by the newer request
+		if a.revertTimers[group] != pending {
+			return
+		}
+
+		delete(a.revertTimers, group)
+		pending.revert()
+		slog.Warn("log level reverted", slog.String("group", group))
+	})
+
+	a.revertTimers[group] = pending
+}
+
+// restoreLevelFunc returns function that restores the current level of the group.
+func (a *AdminHandler) restoreLevelFunc(group string) func() {
+	if group == "" {
+		prevLevel := a.levelController.Level()
+		return func() { a.levelController.SetLevel(prevLevel) }
+	}
+
+	prevLevel, prevExist := a.levelController.GroupLevel(group)
+	return func() {
+		if prevExist {
+			a.levelController.SetGroupLevel(group, prevLevel)
+			return
+		}
+
+		a.levelController.UnsetGroupLevel(group)
+	}
+}
+
+// cancelRevert stops the pending revert of the group, if any.
+func (a *AdminHandler) cancelRevert(group string) {
+	a.revertLock.Lock()
+	defer a.revertLock.Unlock()
+
+	if pending, exist := a.revertTimers[group]; exist {
+		pending.timer.Stop()
+		delete(a.revertTimers, group)
+	}
+}
+
+func (a *AdminHandler) logLevelResp() LogLevelResp {
+	resp := LogLevelResp{
+		Level:  a.levelController.Level().String(),
+		Groups: make(map[string]string),
+	}
+
+	for group, level := range a.levelController.GroupLevels() {
+		resp.Groups[group] = level.String()
+	}
+
+	return resp
+}
+
+// ListTraces returns the summary of the recent traces kept in the trace buffer, the most recent first.
+func (a *AdminHandler) ListTraces(c echo.Context) error {
+	return c.JSON(http.StatusOK, respbuilder.Ok(respbuilder.Success, a.traceBuffer.Traces()))
+}
+
+// GetTrace returns all spans of the trace kept in the trace buffer.
+func (a *AdminHandler) GetTrace(c echo.Context) error {
+	traceID := c.Param("traceId")
+	spans, found := a.traceBuffer.Trace(traceID)
+	if !found {
+		err := fmt.Errorf("trace '%s' is not found in the buffer", traceID)
+		return c.JSON(http.StatusNotFound, respbuilder.ErrorCtx(c.Request().Context(), respbuilder.ErrGeneral, err))
+	}
+
+	return c.JSON(http.StatusOK, respbuilder.Ok(respbuilder.Success, spans))
+}
@@ -0,0 +1,270 @@
+package handleradmin_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/oteltracer"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/ylog"
+	"github.com/yusufsyaifudin/go-project-structure/transport/restapi/handleradmin"
+)
+
+func newAdminServer(t *testing.T, token string, ctrl *ylog.LevelController) *echo.Echo {
+	t.Helper()
+
+	h, err := handleradmin.New(
+		handleradmin.WithToken(token),
+		handleradmin.WithLevelController(ctrl),
+	)
+	require.NoError(t, err)
+
+	e := echo.New()
+	h.Router(e)
+	return e
+}
+
+func doRequest(e *echo.Echo, method, target, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWithLevelController(t *testing.T) {
+	_, err := handleradmin.New(handleradmin.WithLevelController(nil))
+	assert.Error(t, err)
+}
+
+func TestAdminHandler_LogLevel(t *testing.T) {
+	t.Run("disabled without token", func(t *testing.T) {
+		e := newAdminServer(t, "", ylog.NewLevelController(slog.LevelInfo))
+		rec := doRequest(e, http.MethodGet, "/admin/log-level", "", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		e := newAdminServer(t, "secret", ylog.NewLevelController(slog.LevelInfo))
+		rec := doRequest(e, http.MethodGet, "/admin/log-level", "wrong", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("get level", func(t *testing.T) {
+		e := newAdminServer(t, "secret", ylog.NewLevelController(slog.LevelInfo))
+		rec := doRequest(e, http.MethodGet, "/admin/log-level", "secret", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"level":"INFO"`)
+	})
+
+	t.Run("set global level", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		e := newAdminServer(t, "secret", ctrl)
+		rec := doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"debug"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, slog.LevelDebug, ctrl.Level())
+	})
+
+	t.Run("set invalid level", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		e := newAdminServer(t, "secret", ctrl)
+		rec := doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"verbose"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, slog.LevelInfo, ctrl.Level())
+	})
+
+	t.Run("set group level then remove", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		e := newAdminServer(t, "secret", ctrl)
+		rec := doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"debug","group":"db"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"db":"DEBUG"`)
+
+		rec = doRequest(e, http.MethodDelete, "/admin/log-level?group=db", "secret", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		_, exist := ctrl.GroupLevel("db")
+		assert.False(t, exist)
+	})
+
+	t.Run("revert after duration", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		e := newAdminServer(t, "secret", ctrl)
+		rec := doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"debug","duration":"10ms"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, slog.LevelDebug, ctrl.Level())
+
+		assert.Eventually(t, func() bool {
+			return ctrl.Level() == slog.LevelInfo
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("extend timed override keeps original level", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		e := newAdminServer(t, "secret", ctrl)
+		rec := doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"debug","duration":"1h"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"debug","duration":"10ms"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, slog.LevelDebug, ctrl.Level())
+
+		assert.Eventually(t, func() bool {
+			return ctrl.Level() == slog.LevelInfo
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("extend timed group override keeps original level", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		ctrl.SetGroupLevel("db", slog.LevelWarn)
+		e := newAdminServer(t, "secret", ctrl)
+		rec := doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"debug","group":"db","duration":"1h"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"error","group":"db","duration":"10ms"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"db":"ERROR"`)
+
+		assert.Eventually(t, func() bool {
+			level, exist := ctrl.GroupLevel("db")
+			return exist && level == slog.LevelWarn
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("extend timed override of new group removes it", func(t *testing.T) {
+		ctrl := ylog.NewLevelController(slog.LevelInfo)
+		e := newAdminServer(t, "secret", ctrl)
+		rec := doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"debug","group":"cache","duration":"1h"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = doRequest(e, http.MethodPut, "/admin/log-level", "secret", `{"level":"debug","group":"cache","duration":"10ms"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		assert.Eventually(t, func() bool {
+			_, exist := ctrl.GroupLevel("cache")
+			return !exist
+		}, time.Second, 5*time.Millisecond)
+	})
+}
+
+func TestWithTraceBuffer(t *testing.T) {
+	_, err := handleradmin.New(handleradmin.WithTraceBuffer(nil))
+	assert.Error(t, err)
+}
+
+func TestAdminHandler_Traces(t *testing.T) {
+	traceBuffer, err := oteltracer.NewMemoryExporter()
+	require.NoError(t, err)
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(traceBuffer))
+	_, span := provider.Tracer("test").Start(context.Background(), "GET /users/:id")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	traceID := span.SpanContext().TraceID().String()
+
+	h, err := handleradmin.New(
+		handleradmin.WithToken("secret"),
+		handleradmin.WithTraceBuffer(traceBuffer),
+	)
+	require.NoError(t, err)
+
+	e := echo.New()
+	h.Router(e)
+
+	t.Run("invalid token", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/admin/traces", "wrong", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("list traces", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/admin/traces", "secret", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"traceId":"`+traceID+`"`)
+		assert.Contains(t, rec.Body.String(), `"rootName":"GET /users/:id"`)
+	})
+
+	t.Run("get trace", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/admin/traces/"+traceID, "secret", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"spanId":"`+span.SpanContext().SpanID().String()+`"`)
+	})
+
+	t.Run("trace not found", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/admin/traces/0102030405060708090a0b0c0d0e0f10", "secret", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestWithProfiling(t *testing.T) {
+	_, err := handleradmin.New(handleradmin.WithProfiling(0))
+	assert.Error(t, err)
+}
+
+func TestAdminHandler_Profile(t *testing.T) {
+	h, err := handleradmin.New(
+		handleradmin.WithToken("secret"),
+		handleradmin.WithProfiling(time.Second),
+	)
+	require.NoError(t, err)
+
+	e := echo.New()
+	h.Router(e)
+
+	t.Run("invalid token", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/debug/pprof", "wrong", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = doRequest(e, http.MethodGet, "/debug/profile/capture", "", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("pprof index", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/debug/pprof", "secret", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "goroutine")
+	})
+
+	t.Run("pprof named profile", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/debug/pprof/goroutine?debug=1", "secret", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "goroutine profile:")
+	})
+
+	t.Run("capture", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/debug/profile/capture?duration=50ms&profiles=cpu,heap", "secret", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/zip", rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "attachment; filename=\"profile-")
+
+		reader, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		require.NoError(t, err)
+		require.Len(t, reader.File, 2)
+		assert.Equal(t, "cpu.pprof", reader.File[0].Name)
+		assert.Equal(t, "heap.pprof", reader.File[1].Name)
+	})
+
+	t.Run("duration exceeds max", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/debug/profile/capture?duration=1m", "secret", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		rec := doRequest(e, http.MethodGet, "/debug/profile/capture?profiles=threadcreate", "secret", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -0,0 +1,76 @@
+package handleradmin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/profiler"
+	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
+)
+
+// profileRouter registers the profiling endpoints into the group which is already authenticated.
+func (a *AdminHandler) profileRouter(g *echo.Group) {
+	// pprof.Index also serves the named profiles, i.e. /debug/pprof/heap and /debug/pprof/goroutine?debug=2.
+	g.GET("/pprof", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+	g.GET("/pprof/cmdline", echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	g.GET("/pprof/profile", echo.WrapHandler(http.HandlerFunc(pprof.Profile)))
+	g.GET("/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	g.POST("/pprof/symbol", echo.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	g.GET("/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+	g.GET("/pprof/:name", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+
+	g.GET("/profile/capture", a.CaptureProfile)
+}
+
+// CaptureProfile records the profiles passed in query param "profiles" (comma-separated of cpu, heap, goroutine,
+// mutex and block, default to all) for the "duration" (default to 10s), then returns them as a zip archive.
+func (a *AdminHandler) CaptureProfile(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	duration := 10 * time.Second
+	if s := c.QueryParam("duration"); s != "" {
+		var err error
+		duration, err = time.ParseDuration(s)
+		if err != nil || duration <= 0 || duration > a.profileMaxDuration {
+			err = fmt.Errorf("invalid duration '%s', must be positive duration up to %s", s, a.profileMaxDuration)
+			return c.JSON(http.StatusBadRequest, respbuilder.ErrorCtx(ctx, respbuilder.ErrGeneral, err))
+		}
+	}
+
+	duration = min(duration, a.profileMaxDuration)
+
+	profiles, err := profiler.ParseProfiles(c.QueryParam("profiles"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, respbuilder.ErrorCtx(ctx, respbuilder.ErrGeneral, err))
+	}
+
+	// Extend the server write timeout for this response, the capture may take longer than it.
+	_ = http.NewResponseController(c.Response().Writer).SetWriteDeadline(time.Now().Add(duration + time.Minute))
+
+	slog.WarnContext(ctx, "capturing profiles",
+		slog.Any("profiles", profiles),
+		slog.String("duration", duration.String()),
+	)
+
+	// The archive is buffered, so the error can still be responded as JSON.
+	var buf bytes.Buffer
+	err = profiler.Capture(ctx, &buf, duration, profiles...)
+	if errors.Is(err, profiler.ErrCaptureInProgress) {
+		return c.JSON(http.StatusConflict, respbuilder.ErrorCtx(ctx, respbuilder.ErrGeneral, err))
+	}
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, respbuilder.ErrorCtx(ctx, respbuilder.ErrGeneral, err))
+	}
+
+	filename := fmt.Sprintf("profile-%s.zip", time.Now().UTC().Format("20060102T150405Z"))
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, "application/zip", buf.Bytes())
+}
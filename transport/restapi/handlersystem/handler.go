@@ -1,6 +1,7 @@
 package handlersystem
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
 	"runtime"
@@ -9,6 +10,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/yusufsyaifudin/go-project-structure/pkg/health"
 	"github.com/yusufsyaifudin/go-project-structure/pkg/respbuilder"
 	"github.com/yusufsyaifudin/go-project-structure/transport/restapi"
 )
@@ -40,10 +42,27 @@ func WithStartupTime(t time.Time) Opt {
 	}
 }
 
+// WithHealth set the health checks registry used by the /healthz/* and /ready endpoints.
+// The caller must call health.Health.MarkStarted when the initialization is done.
+func WithHealth(h *health.Health) Opt {
+	return func(handler *SystemHandler) error {
+		if h == nil {
+			return fmt.Errorf("cannot use nil health")
+		}
+
+		handler.health = h
+		return nil
+	}
+}
+
 type SystemHandler struct {
 	buildCommitID string
 	buildTime     time.Time
 	startupTime   time.Time
+
+	// health readiness is flipped when the server is shutting down,
+	// so the load balancer stops sending new traffic before the connections are drained.
+	health *health.Health
 }
 
 // Ensure SystemHandler implements restapi.EchoRouter to successfully register endpoint to Echo framework.
@@ -61,12 +80,29 @@ func New(opts ...Opt) (*SystemHandler, error) {
 		}
 	}
 
+	// without any registered check, the application is considered started once the handler is created
+	if systemHandler.health == nil {
+		systemHandler.health = health.New()
+		systemHandler.health.MarkStarted()
+	}
+
 	return systemHandler, nil
 }
 
 func (s *SystemHandler) Router(e *echo.Echo) {
 	e.GET("/ping", s.Ping)
+	e.GET("/ready", s.Ready)
 	e.GET("/system-info", s.SystemInfo)
+
+	e.GET("/healthz/live", s.Healthz(health.Liveness))
+	e.GET("/healthz/ready", s.Healthz(health.Readiness))
+	e.GET("/healthz/startup", s.Healthz(health.Startup))
+}
+
+// SetReady changes the readiness state returned by the /ready and /healthz/ready endpoints.
+// Call SetReady(false) at the beginning of shutdown sequence.
+func (s *SystemHandler) SetReady(ready bool) {
+	s.health.SetReady(ready)
 }
 
 type PingResp struct {
@@ -117,6 +153,53 @@ func (s *SystemHandler) Ping(c echo.Context) error {
 	}))
 }
 
+// Ready returns the same response as Ping, but it will return 503 Service Unavailable
+// when the readiness probe is failing, i.e. the server is shutting down.
+// Prefer /healthz/ready which returns the detail of each check.
+func (s *SystemHandler) Ready(c echo.Context) error {
+	report := s.health.Check(c.Request().Context(), health.Readiness)
+	if !report.Healthy() {
+		err := fmt.Errorf("server is not ready")
+		return c.JSON(http.StatusServiceUnavailable, respbuilder.ErrorCtx(c.Request().Context(), respbuilder.ErrGeneral, err, failingReasons(report)...))
+	}
+
+	return s.Ping(c)
+}
+
+// Healthz returns the handler of the probe, responding the report with 200 OK when the probe passes
+// (including the degraded status), or 503 Service Unavailable when it fails.
+// Use /healthz/live, /healthz/ready and /healthz/startup as Kubernetes liveness, readiness and startup probes.
+func (s *SystemHandler) Healthz(probe health.Probe) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		report := s.health.Check(ctx, probe)
+		if !report.Healthy() {
+			err := fmt.Errorf("%s probe is failing", probe)
+			resp := respbuilder.ErrorCtx(ctx, respbuilder.ErrUnhealthy, err, failingReasons(report)...).WithData(report)
+			return c.JSON(http.StatusServiceUnavailable, resp)
+		}
+
+		return c.JSON(http.StatusOK, respbuilder.Ok(respbuilder.Success, report))
+	}
+}
+
+// failingReasons lists why the probe is failing: the report reason and the error of the failing critical checks.
+func failingReasons(report health.Report) []string {
+	reasons := make([]string, 0)
+	if report.Reason != "" {
+		reasons = append(reasons, report.Reason)
+	}
+
+	for _, check := range report.Checks {
+		if check.Critical && check.Status == health.StatusFailing {
+			reasons = append(reasons, check.Name+": "+check.Error)
+		}
+	}
+
+	return reasons
+}
+
 type SystemInfoRespBySize struct {
 	Size    uint32 `json:"size,omitempty"`
 	Mallocs uint64 `json:"mallocs,omitempty"`
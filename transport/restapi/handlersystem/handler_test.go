@@ -0,0 +1,97 @@
+package handlersystem_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/pkg/health"
+	"github.com/yusufsyaifudin/go-project-structure/transport/restapi/handlersystem"
+)
+
+func newSystemServer(t *testing.T, opts ...handlersystem.Opt) (*handlersystem.SystemHandler, *echo.Echo) {
+	t.Helper()
+
+	h, err := handlersystem.New(opts...)
+	require.NoError(t, err)
+
+	e := echo.New()
+	h.Router(e)
+	return h, e
+}
+
+func doRequest(e *echo.Echo, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestWithHealth(t *testing.T) {
+	_, err := handlersystem.New(handlersystem.WithHealth(nil))
+	assert.Error(t, err)
+}
+
+func TestSystemHandler_Healthz(t *testing.T) {
+	t.Run("default health is started", func(t *testing.T) {
+		_, e := newSystemServer(t)
+
+		for _, target := range []string{"/healthz/live", "/healthz/ready", "/healthz/startup", "/ready"} {
+			rec := doRequest(e, target)
+			assert.Equal(t, http.StatusOK, rec.Code, target)
+		}
+	})
+
+	t.Run("not started", func(t *testing.T) {
+		_, e := newSystemServer(t, handlersystem.WithHealth(health.New()))
+
+		rec := doRequest(e, "/healthz/startup")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"E1"`)
+		assert.Contains(t, rec.Body.String(), `"reasons":["application is starting"]`)
+
+		rec = doRequest(e, "/healthz/live")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("failing check", func(t *testing.T) {
+		h := health.New()
+		h.MarkStarted()
+		require.NoError(t, h.Register("db", func(ctx context.Context) error {
+			return errors.New("connection refused")
+		}))
+		require.NoError(t, h.Register("cache", func(ctx context.Context) error {
+			return nil
+		}))
+
+		_, e := newSystemServer(t, handlersystem.WithHealth(h))
+
+		rec := doRequest(e, "/healthz/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"reasons":["db: connection refused"]`)
+		assert.Contains(t, rec.Body.String(), `"name":"cache","status":"ok"`)
+
+		rec = doRequest(e, "/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		handler, e := newSystemServer(t)
+		handler.SetReady(false)
+
+		rec := doRequest(e, "/healthz/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "application is shutting down")
+
+		rec = doRequest(e, "/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+		rec = doRequest(e, "/healthz/live")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"reflect"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -16,6 +17,9 @@ import (
 
 type HTTPConfig func(*HTTP) error
 
+// notFoundHandlerPtr is used to detect unmatched route, since function cannot be compared directly.
+var notFoundHandlerPtr = reflect.ValueOf(echo.NotFoundHandler).Pointer()
+
 // WithBuildCommitID add build commit id info
 func WithBuildCommitID(hash string) HTTPConfig {
 	return func(h *HTTP) error {
@@ -67,6 +71,9 @@ type HTTP struct {
 	handlers      []EchoRouter
 
 	echo *echo.Echo
+
+	// routes is the set of registered route templates, used to verify the result of MatchRoute.
+	routes map[string]struct{}
 }
 
 var _ http.Handler = (*HTTP)(nil)
@@ -85,6 +92,7 @@ func NewHTTP(configs ...HTTPConfig) (*HTTP, error) {
 		startupTime:   time.Now(),
 		handlers:      make([]EchoRouter, 0),
 		echo:          e,
+		routes:        make(map[string]struct{}),
 	}
 
 	for _, cfg := range configs {
@@ -107,6 +115,10 @@ func NewHTTP(configs ...HTTPConfig) (*HTTP, error) {
 		handler.Router(e)
 	}
 
+	for _, route := range e.Routes() {
+		h.routes[route.Path] = struct{}{}
+	}
+
 	return h, nil
 }
 
@@ -114,6 +126,33 @@ func (h *HTTP) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	h.echo.ServeHTTP(writer, request)
 }
 
+// MatchRoute returns the registered route template (i.e. /users/:id) that matches the request.
+// It returns empty string when no route matched, so the caller can group them as one unmatched route.
+// Request with registered path but different method (405 Method Not Allowed) still returns the route template.
+func (h *HTTP) MatchRoute(req *http.Request) string {
+	if req == nil || req.URL == nil {
+		return ""
+	}
+
+	c := h.echo.AcquireContext()
+	defer h.echo.ReleaseContext(c)
+
+	c.Reset(req, nil)
+	h.echo.Router().Find(req.Method, echo.GetPath(req), c)
+
+	// Echo still set the path of the closest node when nothing matched, but the handler is NotFoundHandler.
+	if reflect.ValueOf(c.Handler()).Pointer() == notFoundHandlerPtr {
+		return ""
+	}
+
+	route := c.Path()
+	if _, registered := h.routes[route]; !registered {
+		return ""
+	}
+
+	return route
+}
+
 func (h *HTTP) httpErrorHandler(err error, eCtx echo.Context) {
 	ctx := eCtx.Request().Context()
 
@@ -134,7 +173,17 @@ func (h *HTTP) httpErrorHandler(err error, eCtx echo.Context) {
 		httpStatus = http.StatusInternalServerError
 	}
 
-	_err := eCtx.JSON(httpStatus, respbuilder.Error(respbuilder.ErrGeneral, err))
+	respCode := respbuilder.ErrGeneral
+
+	// The request body is larger than the limit of httpservermw.BodyLimitMiddleware.
+	var errMaxBytes *http.MaxBytesError
+	if errors.As(err, &errMaxBytes) {
+		httpStatus = http.StatusRequestEntityTooLarge
+		respCode = respbuilder.ErrRequestTooLarge
+		err = fmt.Errorf("request body cannot be larger than %d bytes", errMaxBytes.Limit)
+	}
+
+	_err := eCtx.JSON(httpStatus, respbuilder.ErrorCtx(ctx, respCode, err))
 	if _err != nil {
 		slog.ErrorContext(ctx, "echo.HTTPErrorHandler write json error", slog.Any("error", _err))
 	}
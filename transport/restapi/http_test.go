@@ -0,0 +1,72 @@
+package restapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yusufsyaifudin/go-project-structure/transport/restapi"
+)
+
+type mockRouter struct{}
+
+func (m *mockRouter) Router(e *echo.Echo) {
+	e.GET("/users/:id", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.GET("/static/*", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.POST("/echo", func(c echo.Context) error {
+		var body map[string]any
+		if err := c.Bind(&body); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, body)
+	})
+}
+
+func TestHTTP_MatchRoute(t *testing.T) {
+	h, err := restapi.NewHTTP(restapi.AddHandler(&mockRouter{}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		Method string
+		Path   string
+		Route  string
+	}{
+		{Method: http.MethodGet, Path: "/users/123", Route: "/users/:id"},
+		{Method: http.MethodGet, Path: "/static/js/app.js", Route: "/static/*"},
+		{Method: http.MethodPost, Path: "/users/123", Route: "/users/:id"}, // 405 still has route
+		{Method: http.MethodGet, Path: "/unknown/123", Route: ""},
+		{Method: http.MethodGet, Path: "/users", Route: ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Method+" "+testCase.Path, func(t *testing.T) {
+			req := httptest.NewRequest(testCase.Method, testCase.Path, nil)
+			assert.Equal(t, testCase.Route, h.MatchRoute(req))
+		})
+	}
+
+	t.Run("nil request", func(t *testing.T) {
+		assert.Equal(t, "", h.MatchRoute(nil))
+	})
+}
+
+func TestHTTP_ErrorHandler_BodyTooLarge(t *testing.T) {
+	h, err := restapi.NewHTTP(restapi.AddHandler(&mockRouter{}))
+	require.NoError(t, err)
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"name":"too long"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Body = http.MaxBytesReader(resp, req.Body, 5)
+
+	h.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"code":"E2"`)
+	assert.Contains(t, resp.Body.String(), "request body cannot be larger than 5 bytes")
+}